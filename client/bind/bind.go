@@ -0,0 +1,201 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bind holds what the Go bindings generated by util/abigen need to
+// deploy, call and transact with a contract and to watch the logs it emits.
+package bind
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/burrow/client"
+	"github.com/hyperledger/burrow/client/rpc"
+	"github.com/hyperledger/burrow/keys"
+	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
+	"github.com/hyperledger/burrow/txs"
+)
+
+// Who sends the txs of a binding and what they pay for them. The signing key
+// is taken from PublicKey or, if it is empty, from the key client by Address,
+// both in hex, as the burrow-client tx commands take them.
+type TransactOpts struct {
+	ChainID   string
+	KeyClient keys.KeyClient
+	PublicKey string
+	Address   string
+	Amount    int64
+	GasLimit  int64
+	Fee       int64
+}
+
+// A contract deployed at Address whose ABI is known
+type BoundContract struct {
+	Address    []byte
+	ABI        *abi.ABI
+	nodeClient client.NodeClient
+}
+
+// A log a bound contract emitted with the arguments of its event, as
+// abi.Event.Unpack gives them
+type Log struct {
+	Values []interface{}
+	Raw    txs.EventDataLog
+}
+
+func NewBoundContract(address []byte, abiJSON string, nodeClient client.NodeClient) (*BoundContract, error) {
+	contractABI, err := abi.JSON([]byte(abiJSON))
+	if err != nil {
+		return nil, err
+	}
+	return &BoundContract{
+		Address:    address,
+		ABI:        contractABI,
+		nodeClient: nodeClient,
+	}, nil
+}
+
+// Deploys the contract of the ABI whose code is bin, in hex, passing args to
+// its constructor, and waits for the tx to be committed
+func Deploy(nodeClient client.NodeClient, opts *TransactOpts, abiJSON, bin string,
+	args ...interface{}) (*BoundContract, *rpc.TxResult, error) {
+	contract, err := NewBoundContract(nil, abiJSON, nodeClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	code, err := hex.DecodeString(bin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("bin is bad hex: %v", err)
+	}
+	var inputs []abi.Argument
+	if contract.ABI.Constructor != nil {
+		inputs = contract.ABI.Constructor.Inputs
+	}
+	packed, err := abi.Pack(inputs, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not encode arguments of the constructor: %v", err)
+	}
+	result, err := contract.send(opts, append(code, packed...))
+	if err != nil {
+		return nil, nil, err
+	}
+	contract.Address = result.Address
+	return contract, result, nil
+}
+
+// Runs the function against the latest state without sending a tx, as
+// caller, and decodes what it returned
+func (contract *BoundContract) Call(caller []byte, function string, args ...interface{}) ([]interface{}, error) {
+	method, data, err := contract.pack(function, args)
+	if err != nil {
+		return nil, err
+	}
+	ret, _, err := contract.nodeClient.QueryContract(caller, contract.Address, data)
+	if err != nil {
+		return nil, err
+	}
+	return method.Unpack(ret)
+}
+
+// Sends a tx calling the function, waits for it to be committed and decodes
+// what the call returned
+func (contract *BoundContract) Transact(opts *TransactOpts, function string,
+	args ...interface{}) ([]interface{}, *rpc.TxResult, error) {
+	method, data, err := contract.pack(function, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := contract.send(opts, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	values, err := method.Unpack(result.Return)
+	if err != nil {
+		return nil, result, err
+	}
+	return values, result, nil
+}
+
+// Returns a channel that will receive the logs of the event the contract
+// emits whose indexed arguments, in order, are the values given, where nil
+// matches any value, and a function that stops watching
+func (contract *BoundContract) WatchLogs(eventName string, indexed ...interface{}) (chan *Log, func(), error) {
+	var event *abi.Event
+	for _, e := range contract.ABI.Events {
+		if e.Name == eventName {
+			event = e
+		}
+	}
+	if event == nil {
+		return nil, nil, fmt.Errorf("ABI has no event '%s'", eventName)
+	}
+	topics, err := event.TopicFilter(indexed...)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter := &txs.LogFilter{Topics: topics}
+	wsClient, err := contract.nodeClient.DeriveWebsocketClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	logs, err := wsClient.SubscribeLogs(contract.Address)
+	if err != nil {
+		wsClient.Close()
+		return nil, nil, err
+	}
+	logChannel := make(chan *Log)
+	go func() {
+		defer close(logChannel)
+		for raw := range logs {
+			if !filter.Matches(&raw) {
+				continue
+			}
+			values, err := event.Unpack(raw.Topics, raw.Data)
+			if err != nil {
+				logging.InfoMsg(contract.nodeClient.Logger(), "Could not decode log of watched event",
+					"event", eventName,
+					"error", err)
+				continue
+			}
+			logChannel <- &Log{Values: values, Raw: raw}
+		}
+	}()
+	return logChannel, wsClient.Close, nil
+}
+
+func (contract *BoundContract) pack(function string, args []interface{}) (*abi.Method, []byte, error) {
+	method := contract.ABI.Function(function)
+	if method == nil {
+		return nil, nil, fmt.Errorf("ABI has no function '%s'", function)
+	}
+	data, err := method.Pack(args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return method, data, nil
+}
+
+// Signs and broadcasts a CallTx to the contract, or creating one if it has no
+// address, and waits for it to be committed
+func (contract *BoundContract) send(opts *TransactOpts, data []byte) (*rpc.TxResult, error) {
+	tx, err := rpc.Call(contract.nodeClient, opts.KeyClient, opts.PublicKey, opts.Address,
+		hex.EncodeToString(contract.Address), strconv.FormatInt(opts.Amount, 10), "",
+		strconv.FormatInt(opts.GasLimit, 10), strconv.FormatInt(opts.Fee, 10), hex.EncodeToString(data))
+	if err != nil {
+		return nil, err
+	}
+	return rpc.SignAndBroadcast(opts.ChainID, contract.nodeClient, opts.KeyClient, tx, true, true, true)
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bind
+
+import (
+	"testing"
+
+	"github.com/hyperledger/burrow/client/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testABI = `[
+	{"type":"function","name":"touch","inputs":[{"name":"n","type":"uint8"}],"outputs":[]},
+	{"type":"function","name":"get","constant":true,"inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"event","name":"Touched","inputs":[{"name":"n","type":"uint8","indexed":true}]}
+]`
+
+func TestCall(t *testing.T) {
+	_, err := NewBoundContract(nil, `{}`, mock.NewMockNodeClient())
+	assert.Error(t, err)
+	contract, err := NewBoundContract([]byte{1}, testABI, mock.NewMockNodeClient())
+	assert.NoError(t, err)
+
+	values, err := contract.Call(nil, "touch", 7)
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+	_, err = contract.Call(nil, "touch", 256)
+	assert.Error(t, err, "out of range of uint8")
+	_, err = contract.Call(nil, "untouch")
+	assert.Error(t, err)
+	// The mock node returns nothing, which is too short for a uint256
+	_, err = contract.Call(nil, "get")
+	assert.Error(t, err)
+
+	_, _, err = contract.WatchLogs("Untouched")
+	assert.Error(t, err)
+	_, _, err = contract.WatchLogs("Touched", 1, 2)
+	assert.Error(t, err)
+}
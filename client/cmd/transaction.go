@@ -71,8 +71,10 @@ func buildTransactionCommand() *cobra.Command {
 	callCmd := &cobra.Command{
 		Use:   "call",
 		Short: "burrow-client tx call --amt <amt> --fee <fee> --gas <gas> --to <contract addr> --data <data>",
-		Long:  "burrow-client tx call --amt <amt> --fee <fee> --gas <gas> --to <contract addr> --data <data>",
+		Long: "burrow-client tx call --amt <amt> --fee <fee> --gas <gas> --to <contract addr> --data <data>\n" +
+			"burrow-client tx call --amt <amt> --fee <fee> --gas <gas> --to <contract addr> --abi <abi file> --function <name> [<args>...]",
 		Run: func(cmd *cobra.Command, args []string) {
+			clientDo.FunctionArgs = args
 			err := methods.Call(clientDo)
 			if err != nil {
 				util.Fatalf("Could not complete call: %s", err)
@@ -85,6 +87,10 @@ func buildTransactionCommand() *cobra.Command {
 	callCmd.Flags().StringVarP(&clientDo.DataFlag, "data", "", "", "specify some data")
 	callCmd.Flags().StringVarP(&clientDo.FeeFlag, "fee", "f", "", "specify the fee to send")
 	callCmd.Flags().StringVarP(&clientDo.GasFlag, "gas", "g", "", "specify the gas limit for a CallTx")
+	callCmd.Flags().StringVarP(&clientDo.GasPriceFlag, "gas-price", "", "", "specify the price paid per unit of gas used by a CallTx")
+	callCmd.Flags().BoolVarP(&clientDo.EstimateFlag, "estimate", "", false, "set the gas limit for a CallTx to the least with which the node finds it succeeds")
+	callCmd.Flags().StringVarP(&clientDo.AbiFileFlag, "abi", "", "", "specify a file with the ABI JSON of the contract to call")
+	callCmd.Flags().StringVarP(&clientDo.FunctionFlag, "function", "", "", "specify the function to call, whose arguments follow, in place of --data")
 
 	// BondTx
 	bondCmd := &cobra.Command{
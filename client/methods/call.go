@@ -16,11 +16,14 @@ package methods
 
 import (
 	"fmt"
+	"io/ioutil"
+	"strconv"
 
 	"github.com/hyperledger/burrow/client"
 	"github.com/hyperledger/burrow/client/rpc"
 	"github.com/hyperledger/burrow/definitions"
 	"github.com/hyperledger/burrow/keys"
+	"github.com/hyperledger/burrow/logging"
 )
 
 func Call(do *definitions.ClientDo) error {
@@ -32,13 +35,47 @@ func Call(do *definitions.ClientDo) error {
 	}
 	burrowKeyClient := keys.NewBurrowKeyClient(do.SignAddrFlag, logger)
 	burrowNodeClient := client.NewBurrowNodeClient(do.NodeAddrFlag, logger)
+	gas := do.GasFlag
+	if do.EstimateFlag && gas == "" {
+		// the estimate replaces it below
+		gas = "0"
+	}
+	data := do.DataFlag
+	var abiJSON []byte
+	if do.FunctionFlag != "" {
+		abiJSON, err = ioutil.ReadFile(do.AbiFileFlag)
+		if err != nil {
+			return fmt.Errorf("Could not read ABI file: %s", err)
+		}
+		data, err = rpc.CallData(abiJSON, do.FunctionFlag, do.FunctionArgs)
+		if err != nil {
+			return fmt.Errorf("Failed on encoding call to %s: %s", do.FunctionFlag, err)
+		}
+	}
 	// form the call transaction
 	callTransaction, err := rpc.Call(burrowNodeClient, burrowKeyClient,
 		do.PubkeyFlag, do.AddrFlag, do.ToFlag, do.AmtFlag, do.NonceFlag,
-		do.GasFlag, do.FeeFlag, do.DataFlag)
+		gas, do.FeeFlag, data)
 	if err != nil {
 		return fmt.Errorf("Failed on forming Call Transaction: %s", err)
 	}
+	if do.EstimateFlag {
+		gasLimit, err := burrowNodeClient.EstimateGas(callTransaction.Input.Address,
+			callTransaction.Address, callTransaction.Data)
+		if err != nil {
+			return fmt.Errorf("Failed on estimating gas for Call Transaction: %s", err)
+		}
+		logging.InfoMsg(logger, "Estimated gas limit for Call Transaction",
+			"gas_limit", gasLimit)
+		callTransaction.GasLimit = gasLimit
+	}
+	if do.GasPriceFlag != "" {
+		gasPrice, err := strconv.ParseInt(do.GasPriceFlag, 10, 64)
+		if err != nil {
+			return fmt.Errorf("gas price is misformatted: %v", err)
+		}
+		callTransaction.GasPrice = gasPrice
+	}
 	// TODO: [ben] we carry over the sign bool, but always set it to true,
 	// as we move away from and deprecate the api that allows sending unsigned
 	// transactions and relying on (our) receiving node to sign it.
@@ -49,5 +86,15 @@ func Call(do *definitions.ClientDo) error {
 		return fmt.Errorf("Failed on signing (and broadcasting) transaction: %s", err)
 	}
 	unpackSignAndBroadcast(txResult, logger)
+	if abiJSON != nil && txResult != nil && txResult.Return != nil && txResult.Exception == "" {
+		returns, err := rpc.DecodeReturn(abiJSON, do.FunctionFlag, txResult.Return)
+		if err != nil {
+			return fmt.Errorf("Failed on decoding return value: %s", err)
+		}
+		for _, ret := range returns {
+			logging.InfoMsg(logger, "Decoded return value",
+				"name", ret.Name, "type", ret.Type, "value", ret.Value)
+		}
+	}
 	return nil
 }
@@ -103,6 +103,10 @@ func (mock *MockNodeClient) QueryContractCode(address, code, data []byte) (ret [
 	return ret, 0, nil
 }
 
+func (mock *MockNodeClient) EstimateGas(callerAddress, calleeAddress, data []byte) (gasLimit int64, err error) {
+	return 0, nil
+}
+
 func (mock *MockNodeClient) DumpStorage(address []byte) (storage *core_types.Storage, err error) {
 	return nil, nil
 }
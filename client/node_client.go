@@ -40,6 +40,7 @@ type NodeClient interface {
 	GetAccount(address []byte) (*acc.Account, error)
 	QueryContract(callerAddress, calleeAddress, data []byte) (ret []byte, gasUsed int64, err error)
 	QueryContractCode(address, code, data []byte) (ret []byte, gasUsed int64, err error)
+	EstimateGas(callerAddress, calleeAddress, data []byte) (gasLimit int64, err error)
 
 	DumpStorage(address []byte) (storage *core_types.Storage, err error)
 	GetName(name string) (owner []byte, data string, expirationBlock int, err error)
@@ -54,6 +55,7 @@ type NodeWebsocketClient interface {
 	Unsubscribe(eventId string) error
 
 	WaitForConfirmation(tx txs.Tx, chainId string, inputAddr []byte) (chan Confirmation, error)
+	SubscribeLogs(address []byte) (chan txs.EventDataLog, error)
 	Close()
 }
 
@@ -171,7 +173,7 @@ func (burrowNodeClient *burrowNodeClient) ChainId() (ChainName, ChainId string,
 // NOTE: there is no check on the caller;
 func (burrowNodeClient *burrowNodeClient) QueryContract(callerAddress, calleeAddress, data []byte) (ret []byte, gasUsed int64, err error) {
 	client := rpcclient.NewClientJSONRPC(burrowNodeClient.broadcastRPC)
-	callResult, err := tendermint_client.Call(client, callerAddress, calleeAddress, data)
+	callResult, err := tendermint_client.Call(client, callerAddress, calleeAddress, data, 0)
 	if err != nil {
 		err = fmt.Errorf("Error connnecting to node (%s) to query contract at (%X) with data (%X)",
 			burrowNodeClient.broadcastRPC, calleeAddress, data, err.Error())
@@ -194,10 +196,23 @@ func (burrowNodeClient *burrowNodeClient) QueryContractCode(address, code, data
 	return callResult.Return, callResult.GasUsed, nil
 }
 
+// EstimateGas returns the least gas limit with which a CallTx from the caller
+// to the callee (or creating a contract if the callee is empty) succeeds
+func (burrowNodeClient *burrowNodeClient) EstimateGas(callerAddress, calleeAddress, data []byte) (gasLimit int64, err error) {
+	client := rpcclient.NewClientJSONRPC(burrowNodeClient.broadcastRPC)
+	gasLimit, err = tendermint_client.EstimateGas(client, callerAddress, calleeAddress, data)
+	if err != nil {
+		err = fmt.Errorf("Error connecting to node (%s) to estimate gas for call to (%X) with data (%X): %s",
+			burrowNodeClient.broadcastRPC, calleeAddress, data, err.Error())
+		return 0, err
+	}
+	return gasLimit, nil
+}
+
 // GetAccount returns a copy of the account
 func (burrowNodeClient *burrowNodeClient) GetAccount(address []byte) (*acc.Account, error) {
 	client := rpcclient.NewClientJSONRPC(burrowNodeClient.broadcastRPC)
-	account, err := tendermint_client.GetAccount(client, address)
+	account, err := tendermint_client.GetAccount(client, address, 0)
 	if err != nil {
 		err = fmt.Errorf("Error connecting to node (%s) to fetch account (%X): %s",
 			burrowNodeClient.broadcastRPC, address, err.Error())
@@ -214,7 +229,7 @@ func (burrowNodeClient *burrowNodeClient) GetAccount(address []byte) (*acc.Accou
 // DumpStorage returns the full storage for an account.
 func (burrowNodeClient *burrowNodeClient) DumpStorage(address []byte) (storage *core_types.Storage, err error) {
 	client := rpcclient.NewClientJSONRPC(burrowNodeClient.broadcastRPC)
-	resultStorage, err := tendermint_client.DumpStorage(client, address)
+	resultStorage, err := tendermint_client.DumpStorage(client, address, 0)
 	if err != nil {
 		err = fmt.Errorf("Error connecting to node (%s) to get storage for account (%X): %s",
 			burrowNodeClient.broadcastRPC, address, err.Error())
@@ -232,7 +247,7 @@ func (burrowNodeClient *burrowNodeClient) DumpStorage(address []byte) (storage *
 
 func (burrowNodeClient *burrowNodeClient) GetName(name string) (owner []byte, data string, expirationBlock int, err error) {
 	client := rpcclient.NewClientJSONRPC(burrowNodeClient.broadcastRPC)
-	entryResult, err := tendermint_client.GetName(client, name)
+	entryResult, err := tendermint_client.GetName(client, name, 0)
 	if err != nil {
 		err = fmt.Errorf("Error connecting to node (%s) to get name registrar entry for name (%s)",
 			burrowNodeClient.broadcastRPC, name)
@@ -0,0 +1,90 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+
+	acc "github.com/hyperledger/burrow/account"
+	"github.com/hyperledger/burrow/manager/burrow-mint/state"
+	"github.com/tendermint/go-merkle"
+	"github.com/tendermint/go-wire"
+)
+
+// VerifyQuery decodes the data of a result of the ABCI Query of BurrowMint and
+// checks that the value it holds is proved to be in the state with the given
+// app hash. The app hash of the state queried is that in the header of the
+// block after the result's height.
+func VerifyQuery(data, appHash []byte) (*state.QueryResult, error) {
+	result := new(state.QueryResult)
+	err := wire.ReadBinaryBytes(data, result)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode query result: %v", err)
+	}
+	if err := VerifyQueryResult(result, appHash); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// VerifyQueryResult checks the proof of the value of a query result against
+// an app hash
+func VerifyQueryResult(result *state.QueryResult, appHash []byte) error {
+	query, err := state.ParseQueryPath(result.Path)
+	if err != nil {
+		return err
+	}
+	proof := result.Proof
+	if proof == nil {
+		return fmt.Errorf("Query result for '%s' has no proof", result.Path)
+	}
+	if !bytes.Equal(state.AppHash(proof.AccountsRoot, proof.ValidatorInfosRoot, proof.NameRegRoot,
+		proof.ContractCreatorsRoot), appHash) {
+		return fmt.Errorf("Tree roots of the proof for '%s' do not hash to app hash %X",
+			result.Path, appHash)
+	}
+
+	treeRoot, treeValue := proof.AccountsRoot, result.Value
+	switch query.Kind {
+	case state.QueryName:
+		treeRoot = proof.NameRegRoot
+	case state.QueryStorage:
+		treeValue = proof.Account
+	}
+	if err := verifyTreeProof(proof.TreeProof, query.TreeKey, treeValue, treeRoot); err != nil {
+		return fmt.Errorf("Invalid proof for '%s': %v", result.Path, err)
+	}
+	if query.Kind == state.QueryStorage {
+		storageRoot := acc.DecodeAccount(proof.Account).StorageRoot
+		err := verifyTreeProof(proof.StorageProof, query.StorageKey, result.Value, storageRoot)
+		if err != nil {
+			return fmt.Errorf("Invalid storage proof for '%s': %v", result.Path, err)
+		}
+	}
+	return nil
+}
+
+func verifyTreeProof(proofBytes, key, value, root []byte) error {
+	proof, err := merkle.ReadProof(proofBytes)
+	if err != nil {
+		return err
+	}
+	if !proof.Verify(key, value, root) {
+		return fmt.Errorf("key %X with value %X is not proved in tree with root %X",
+			key, value, root)
+	}
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/hyperledger/burrow/client"
 	"github.com/hyperledger/burrow/keys"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
 	"github.com/hyperledger/burrow/txs"
 )
 
@@ -82,6 +83,39 @@ func Call(nodeClient client.NodeClient, keyClient keys.KeyClient, pubkey, addr,
 	return tx, nil
 }
 
+// Encodes the data of a CallTx to the function of the contract described by
+// abiJSON, with args as its arguments written as abi.Pack takes them from the
+// command line
+func CallData(abiJSON []byte, function string, args []string) (string, error) {
+	contractABI, err := abi.JSON(abiJSON)
+	if err != nil {
+		return "", err
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	data, err := contractABI.Pack(function, values...)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// Decodes what a call to the function of the contract described by abiJSON
+// returned
+func DecodeReturn(abiJSON []byte, function string, ret []byte) ([]abi.DecodedArg, error) {
+	contractABI, err := abi.JSON(abiJSON)
+	if err != nil {
+		return nil, err
+	}
+	method := contractABI.Function(function)
+	if method == nil {
+		return nil, fmt.Errorf("ABI has no function '%s'", function)
+	}
+	return abi.DecodeArgs(method.Outputs, ret)
+}
+
 func Name(nodeClient client.NodeClient, keyClient keys.KeyClient, pubkey, addr, amtS, nonceS, feeS, name, data string) (*txs.NameTx, error) {
 	pub, amt, nonce, err := checkCommon(nodeClient, keyClient, pubkey, addr, amtS, nonceS)
 	if err != nil {
@@ -29,6 +29,7 @@ func Test(t *testing.T) {
 	mockNodeClient := mockclient.NewMockNodeClient()
 	testSend(t, mockNodeClient, mockKeyClient)
 	testCall(t, mockNodeClient, mockKeyClient)
+	testCallData(t)
 	testName(t, mockNodeClient, mockKeyClient)
 	testPermissions(t, mockNodeClient, mockKeyClient)
 	// t.Run("BondTransaction", )
@@ -97,6 +98,30 @@ func testCall(t *testing.T,
 	// TODO: test content of Transaction
 }
 
+func testCallData(t *testing.T) {
+	abiJSON := []byte(`[{"type":"function","name":"set","inputs":[{"name":"key","type":"string"},
+		{"name":"values","type":"uint8[]"}],"outputs":[{"name":"count","type":"uint256"}]}]`)
+	data, err := CallData(abiJSON, "set", []string{"a", "[1,2]"})
+	if err != nil {
+		t.Fatalf("Error in CallData: %s", err)
+	}
+	// The selector, the offsets of the string and the array, the string's
+	// length and its word of bytes, and the array's length and elements
+	if len(data) != 2*(4+7*32) {
+		t.Errorf("Expected call data of %v bytes but got %s", 4+7*32, data)
+	}
+	if _, err = CallData(abiJSON, "set", []string{"a", "[256]"}); err == nil {
+		t.Error("Expected an error encoding 256 as a uint8")
+	}
+	returns, err := DecodeReturn(abiJSON, "set", append(make([]byte, 31), 2))
+	if err != nil {
+		t.Fatalf("Error in DecodeReturn: %s", err)
+	}
+	if len(returns) != 1 || returns[0].Value != "2" {
+		t.Errorf("Expected count of 2 but got %v", returns)
+	}
+}
+
 func testName(t *testing.T,
 	nodeClient *mockclient.MockNodeClient, keyClient *mockkeys.MockKeyClient) {
 
@@ -197,6 +197,47 @@ func (burrowNodeWebsocketClient *burrowNodeWebsocketClient) WaitForConfirmation(
 	return confirmationChannel, nil
 }
 
+// Returns a channel that will receive the logs the contract at address emits
+// until the websocket is closed; or an error is returned and the channel is nil.
+func (burrowNodeWebsocketClient *burrowNodeWebsocketClient) SubscribeLogs(address []byte) (chan txs.EventDataLog, error) {
+	if err := burrowNodeWebsocketClient.assertNoErrors(); err != nil {
+		return nil, err
+	}
+	eid := txs.EventStringLogEvent(address)
+	if err := burrowNodeWebsocketClient.tendermintWebsocket.Subscribe(eid); err != nil {
+		return nil, fmt.Errorf("Error subscribing to Log event (%s): %v", eid, err)
+	}
+	logChannel := make(chan txs.EventDataLog)
+	go func() {
+		defer close(logChannel)
+		var err error
+		for {
+			resultBytes, ok := <-burrowNodeWebsocketClient.tendermintWebsocket.ResultsCh
+			if !ok {
+				return
+			}
+			result := new(ctypes.BurrowResult)
+			if wire.ReadJSONPtr(result, resultBytes, &err); err != nil {
+				logging.InfoMsg(burrowNodeWebsocketClient.logger, "Failed to unmarshal json bytes for websocket event",
+					"error", err)
+				continue
+			}
+			event, ok := (*result).(*ctypes.ResultEvent)
+			if !ok || event.Event != eid {
+				continue
+			}
+			data, ok := event.Data.(txs.EventDataLog)
+			if !ok {
+				logging.InfoMsg(burrowNodeWebsocketClient.logger, "Received Log event without a log",
+					"event", event.Event)
+				continue
+			}
+			logChannel <- data
+		}
+	}()
+	return logChannel, nil
+}
+
 func (burrowNodeWebsocketClient *burrowNodeWebsocketClient) Close() {
 	if burrowNodeWebsocketClient.tendermintWebsocket != nil {
 		burrowNodeWebsocketClient.tendermintWebsocket.Stop()
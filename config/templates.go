@@ -150,6 +150,9 @@ const sectionServers = `[servers]
 
   [servers.http]
   json_rpc_endpoint = "/rpc"
+  # serve the subset of the Ethereum JSON-RPC API used by web3 libraries
+  # at this endpoint, leave empty to disable
+  eth_json_rpc_endpoint = ""
 
   [servers.websocket]
   endpoint = "/socketrpc"
@@ -291,6 +294,9 @@ const sectionBurrowMint = `
 # Database backend to use for BurrowMint state database.
 # Supported "leveldb" and "memdb".
 db_backend = "leveldb"
+# Number of the latest block heights whose state is kept for queries at a
+# height. Set to 0 to keep the state of every height.
+state_history_blocks = 0
 # tendermint host address needs to correspond to tendermints configuration
 # of the rpc local address
 tendermint_host = "0.0.0.0:46657"
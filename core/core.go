@@ -27,6 +27,8 @@ import (
 	"github.com/hyperledger/burrow/manager"
 	// rpc_v0 is carried over from burrowv0.11 and before on port 1337
 	rpc_v0 "github.com/hyperledger/burrow/rpc/v0"
+	// rpc_eth serves web3 libraries on the same port as rpc_v0 when enabled
+	rpc_eth "github.com/hyperledger/burrow/rpc/eth"
 	// rpc_tendermint is carried over from burrowv0.11 and before on port 46657
 
 	"github.com/hyperledger/burrow/logging"
@@ -100,8 +102,12 @@ func (core *Core) NewGatewayV0(config *server.ServerConfig) (*server.ServeProces
 	restServer := rpc_v0.NewRestServer(codec, core.pipe, eventSubscriptions)
 	wsServer := server.NewWebSocketServer(config.WebSocket.MaxWebSocketSessions,
 		tmwss)
+	servers := []server.Server{jsonServer, restServer, wsServer}
+	if config.HTTP.EthJsonRpcEndpoint != "" {
+		servers = append(servers, rpc_eth.NewEthJsonRpcServer(core.pipe))
+	}
 	// Create a server process.
-	proc, err := server.NewServeProcess(config, jsonServer, restServer, wsServer)
+	proc, err := server.NewServeProcess(config, servers...)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to load gateway: %v", err)
 	}
@@ -23,6 +23,8 @@ import (
 	"github.com/tendermint/tendermint/types"
 
 	account "github.com/hyperledger/burrow/account"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
+	. "github.com/hyperledger/burrow/word256"
 )
 
 type (
@@ -126,8 +128,53 @@ type (
 	Call struct {
 		Return  string `json:"return"`
 		GasUsed int64  `json:"gas_used"`
+		// Set when the call reverted, in which case Return holds its output
+		Exception    string `json:"exception"`
+		RevertReason string `json:"revert_reason"`
+		// Set when the callee's ABI has been registered
+		Decoded *abi.DecodedCall `json:"decoded"`
 		// TODO ...
 	}
+
+	// The least gas limit with which a CallTx succeeds
+	GasEstimate struct {
+		GasLimit int64 `json:"gas_limit"`
+	}
+
+	// A Call along with a log of the ops the VM ran to make it
+	CallTrace struct {
+		Call       *Call       `json:"call"`
+		StructLogs []StructLog `json:"struct_logs"`
+	}
+
+	// Describes a single op run by the VM
+	StructLog struct {
+		Depth   int    `json:"depth"`
+		PC      int64  `json:"pc"`
+		Op      string `json:"op"`
+		Gas     int64  `json:"gas"`
+		GasCost int64  `json:"gas_cost"`
+		// The stack before the op was run, bottom first
+		Stack        []Word256       `json:"stack"`
+		MemorySize   int64           `json:"memory_size"`
+		MemoryChange *MemoryChange   `json:"memory_change"`
+		Storage      []StorageAccess `json:"storage"`
+		Error        string          `json:"error"`
+	}
+
+	// A region of memory changed by an op
+	MemoryChange struct {
+		Offset int64  `json:"offset"`
+		Data   []byte `json:"data"`
+	}
+
+	// A read or write of storage by an op
+	StorageAccess struct {
+		Address Word256 `json:"address"`
+		Key     Word256 `json:"key"`
+		Value   Word256 `json:"value"`
+		Write   bool    `json:"write"`
+	}
 )
 
 //------------------------------------------------------------------------------
@@ -42,8 +42,18 @@ type ClientDo struct {
 	ToFlag       string
 	FeeFlag      string
 	GasFlag      string
+	GasPriceFlag string
 	UnbondtoFlag string
 	HeightFlag   string
+
+	// Use the node's estimate of the gas a CallTx needs as its gas limit
+	EstimateFlag bool
+
+	// Encode the data of a CallTx as a call to FunctionFlag of the contract
+	// whose ABI JSON is in AbiFileFlag, with FunctionArgs as its arguments
+	AbiFileFlag  string
+	FunctionFlag string
+	FunctionArgs []string
 }
 
 func NewClientDo() *ClientDo {
@@ -69,8 +79,15 @@ func NewClientDo() *ClientDo {
 	clientDo.ToFlag = ""
 	clientDo.FeeFlag = ""
 	clientDo.GasFlag = ""
+	clientDo.GasPriceFlag = ""
 	clientDo.UnbondtoFlag = ""
 	clientDo.HeightFlag = ""
 
+	clientDo.EstimateFlag = false
+
+	clientDo.AbiFileFlag = ""
+	clientDo.FunctionFlag = ""
+	clientDo.FunctionArgs = nil
+
 	return clientDo
 }
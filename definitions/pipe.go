@@ -70,6 +70,12 @@ type NameReg interface {
 type Transactor interface {
 	Call(fromAddress, toAddress, data []byte) (*types.Call, error)
 	CallCode(fromAddress, code, data []byte) (*types.Call, error)
+	TraceCall(fromAddress, toAddress, data []byte) (*types.CallTrace, error)
+	TraceTx(tx txs.Tx) (*types.CallTrace, error)
+	EstimateGas(fromAddress, toAddress, data []byte) (int64, error)
+	TxReceipt(txHash []byte) (*txs.TxReceipt, error)
+	SearchTxs(filter *txs.TxFilter) (*txs.TxSearchResult, error)
+	GetLogs(filter *txs.LogFilter) (*txs.LogSearchResult, error)
 	// Send(privKey, toAddress []byte, amount int64) (*types.Receipt, error)
 	// SendAndHold(privKey, toAddress []byte, amount int64) (*types.Receipt, error)
 	BroadcastTx(tx txs.Tx) (*txs.Receipt, error)
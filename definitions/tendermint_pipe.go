@@ -42,14 +42,23 @@ type TendermintPipe interface {
 	ChainId() (*rpc_tm_types.ResultChainId, error)
 
 	// Accounts
-	GetAccount(address []byte) (*rpc_tm_types.ResultGetAccount, error)
-	ListAccounts() (*rpc_tm_types.ResultListAccounts, error)
-	GetStorage(address, key []byte) (*rpc_tm_types.ResultGetStorage, error)
-	DumpStorage(address []byte) (*rpc_tm_types.ResultDumpStorage, error)
+	// Queries taking a height read the state as it was after committing the
+	// block at that height, or the latest state for height 0
+	GetAccount(address []byte, height int) (*rpc_tm_types.ResultGetAccount, error)
+	ListAccounts(height int) (*rpc_tm_types.ResultListAccounts, error)
+	GetStorage(address, key []byte, height int) (*rpc_tm_types.ResultGetStorage, error)
+	DumpStorage(address []byte, height int) (*rpc_tm_types.ResultDumpStorage, error)
 
 	// Call
-	Call(fromAddress, toAddress, data []byte) (*rpc_tm_types.ResultCall, error)
+	Call(fromAddress, toAddress, data []byte, height int) (*rpc_tm_types.ResultCall, error)
 	CallCode(fromAddress, code, data []byte) (*rpc_tm_types.ResultCall, error)
+	TraceCall(fromAddress, toAddress, data []byte) (*rpc_tm_types.ResultTrace, error)
+	TraceTx(tx txs.Tx) (*rpc_tm_types.ResultTrace, error)
+	ReplayTx(height int, txHash []byte, trace bool) (*rpc_tm_types.ResultReplayTx, error)
+	EstimateGas(fromAddress, toAddress, data []byte) (*rpc_tm_types.ResultEstimateGas, error)
+	GetTxReceipt(txHash []byte) (*rpc_tm_types.ResultGetTxReceipt, error)
+	SearchTxs(filter *txs.TxFilter) (*rpc_tm_types.ResultSearchTxs, error)
+	GetLogs(filter *txs.LogFilter) (*rpc_tm_types.ResultGetLogs, error)
 
 	// TODO: [ben] deprecate as we should not allow unsafe behaviour
 	// where a user is allowed to send a private key over the wire,
@@ -59,8 +68,8 @@ type TendermintPipe interface {
 		error)
 
 	// Name registry
-	GetName(name string) (*rpc_tm_types.ResultGetName, error)
-	ListNames() (*rpc_tm_types.ResultListNames, error)
+	GetName(name string, height int) (*rpc_tm_types.ResultGetName, error)
+	ListNames(height int) (*rpc_tm_types.ResultListNames, error)
 
 	// Memory pool
 	BroadcastTxAsync(transaction txs.Tx) (*rpc_tm_types.ResultBroadcastTx, error)
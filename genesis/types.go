@@ -67,6 +67,30 @@ type GenesisPrivateValidator struct {
 
 type GenesisParams struct {
 	GlobalPermissions *ptypes.AccountPermissions `json:"global_permissions"`
+	// Name of the gas schedule the EVM charges by, "byzantium" or "legacy" for
+	// the flat costs of older chains. Left empty it is "legacy" so that chains
+	// made before the choice keep their rules; new chains opt in to
+	// "byzantium" by naming it.
+	GasSchedule string `json:"gas_schedule"`
+	// Maximum bytes of memory the EVM allows each call frame, 1 MB if zero
+	MemoryLimit int64 `json:"memory_limit"`
+	// Minimum fee of each kind of tx, none if nil
+	MinFees *GenesisFees `json:"min_fees"`
+	// Minimum gas price of a CallTx. The gas it uses times its price is
+	// charged on top of its fee.
+	MinGasPrice int64 `json:"min_gas_price"`
+	// Account credited with the fees of each block. If empty the fees are
+	// shared among the bonded validators in proportion to their voting power.
+	Treasury []byte `json:"treasury"`
+}
+
+// The fee is what a SendTx or BondTx leaves of its inputs after its outputs,
+// and the Fee of a CallTx or NameTx
+type GenesisFees struct {
+	SendTx int64 `json:"send_tx"`
+	CallTx int64 `json:"call_tx"`
+	NameTx int64 `json:"name_tx"`
+	BondTx int64 `json:"bond_tx"`
 }
 
 //------------------------------------------------------------
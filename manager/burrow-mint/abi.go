@@ -0,0 +1,106 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package burrowmint
+
+import (
+	edb_event "github.com/hyperledger/burrow/event"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
+	"github.com/hyperledger/burrow/manager/burrow-mint/state"
+	"github.com/hyperledger/burrow/txs"
+)
+
+// Contract ABIs are registered in the name registry under
+// txs.ContractABIName by the contract or its creator, or by a root account
+// for contracts with no recorded creator. Calls and logs of
+// contracts without one, or that do not match theirs, are left undecoded.
+
+func contractABI(st *state.State, address []byte) *abi.ABI {
+	entry := st.GetNameRegEntry(txs.ContractABIName(address))
+	// Entries registered before only the contract and its creator could
+	// register them are not trusted
+	if entry == nil || !state.IsContractABIOwner(st, st, address, entry.Owner) {
+		return nil
+	}
+	contractABI, err := abi.JSON([]byte(entry.Data))
+	if err != nil {
+		return nil
+	}
+	return contractABI
+}
+
+func decodeCall(st *state.State, address, data, ret []byte) *abi.DecodedCall {
+	contractABI := contractABI(st, address)
+	if contractABI == nil {
+		return nil
+	}
+	decoded, err := contractABI.DecodeCall(data, ret)
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// Sets Decoded on the logs in place
+func decodeLogs(st *state.State, logs []*txs.EventDataLog) {
+	for _, log := range logs {
+		contractABI := contractABI(st, log.Address.Postfix(20))
+		if contractABI == nil {
+			continue
+		}
+		log.Decoded, _ = contractABI.DecodeLog(log.Topics, log.Data)
+	}
+}
+
+// Returns the call and log events with their decoding, and other events as
+// they are
+func decodeEventData(st *state.State, eventData txs.EventData) txs.EventData {
+	switch data := eventData.(type) {
+	case txs.EventDataCall:
+		if data.CallData != nil {
+			ret := data.Return
+			// What a call that threw returns is not its outputs
+			if data.Exception != "" {
+				ret = nil
+			}
+			data.Decoded = decodeCall(st, data.CallData.Callee, data.CallData.Data, ret)
+		}
+		return data
+	case txs.EventDataLog:
+		decodeLogs(st, []*txs.EventDataLog{&data})
+		return data
+	}
+	return eventData
+}
+
+// Decodes the call and log events of the events it wraps against the ABIs
+// registered in the state burrowMint last committed, which is that of the
+// block the events fire for
+type decodingEvents struct {
+	edb_event.EventEmitter
+	burrowMint *BurrowMint
+}
+
+func newDecodingEvents(events edb_event.EventEmitter, burrowMint *BurrowMint) *decodingEvents {
+	return &decodingEvents{events, burrowMint}
+}
+
+func (evts *decodingEvents) Subscribe(subId, event string,
+	callback func(txs.EventData)) error {
+	return evts.EventEmitter.Subscribe(subId, event, func(eventData txs.EventData) {
+		// Events fire while burrowMint commits, holding the lock GetState
+		// takes
+		callback(decodeEventData(evts.burrowMint.GetCommittedState(), eventData))
+	})
+}
@@ -15,7 +15,6 @@
 package burrowmint
 
 import (
-	"bytes"
 	"fmt"
 	"sync"
 	"time"
@@ -47,6 +46,11 @@ type BurrowMint struct {
 	evc  *tendermint_events.EventCache
 	evsw tendermint_events.EventSwitch
 
+	// The state as last committed, which event listeners read rather than
+	// state since Commit holds mtx while it flushes events to them
+	committedMtx   sync.Mutex
+	committedState *sm.State
+
 	nTxs   int // count txs in a block
 	logger loggers.InfoTraceLogger
 }
@@ -54,6 +58,7 @@ type BurrowMint struct {
 // NOTE [ben] Compiler check to ensure BurrowMint successfully implements
 // burrow/manager/types.Application
 var _ manager_types.Application = (*BurrowMint)(nil)
+var _ manager_types.BlockchainAware = (*BurrowMint)(nil)
 
 // NOTE: [ben] also automatically implements abci.Application,
 // undesired but unharmful
@@ -65,6 +70,14 @@ func (app *BurrowMint) GetState() *sm.State {
 	return app.state.Copy()
 }
 
+// Returns a copy of the state as last committed without taking app.mtx, so it
+// may be called by event listeners
+func (app *BurrowMint) GetCommittedState() *sm.State {
+	app.committedMtx.Lock()
+	defer app.committedMtx.Unlock()
+	return app.committedState.Copy()
+}
+
 // TODO: this is used for call/callcode and to get nonces during mempool.
 // the former should work on last committed state only and the later should
 // be handled by the client, or a separate wallet-like nonce tracker thats not part of the app
@@ -80,6 +93,8 @@ func NewBurrowMint(s *sm.State, evsw tendermint_events.EventSwitch, logger logge
 		evc:        tendermint_events.NewEventCache(evsw),
 		evsw:       evsw,
 		logger:     logging.WithScope(logger, "BurrowMint"),
+
+		committedState: s.Copy(),
 	}
 }
 
@@ -98,46 +113,144 @@ func (app *BurrowMint) DeliverTx(txBytes []byte) abci.Result {
 	app.nTxs += 1
 
 	// XXX: if we had tx ids we could cache the decoded txs on CheckTx
-	var n int
-	var err error
-	tx := new(txs.Tx)
-	buf := bytes.NewBuffer(txBytes)
-	wire.ReadBinaryPtr(tx, buf, len(txBytes), &n, &err)
+	tx, err := txs.DecodeTx(txBytes)
 	if err != nil {
 		return abci.NewError(abci.CodeType_EncodingError, fmt.Sprintf("Encoding error: %v", err))
 	}
 
-	err = sm.ExecTx(app.cache, *tx, true, app.evc)
+	logs := &logRecorder{Fireable: app.evc}
+	callResult, err := sm.TraceTx(app.cache, tx, logs, nil)
+	txReceipt := &txs.TxReceipt{
+		TxHash: txs.TxHash(app.state.ChainID, tx),
+		Height: app.state.LastBlockHeight + 1,
+		Index:  app.nTxs - 1,
+	}
+	// Kept whether or not the tx failed, since it is in the block either way
+	defer app.addTxReceipt(tx, txReceipt)
+
 	if err != nil {
-		return abci.NewError(abci.CodeType_InternalError, fmt.Sprintf("Internal error: %v", err))
+		res := execErrorResult(app.state.ChainID, tx, err)
+		txReceipt.Code, txReceipt.Log = res.Code, res.Log
+		return res
 	}
 
-	receipt := txs.GenerateReceipt(app.state.ChainID, *tx)
+	receipt := txs.GenerateReceipt(app.state.ChainID, tx)
+	if callResult != nil {
+		txReceipt.Return = callResult.Return
+		txReceipt.GasUsed = callResult.GasUsed
+		if callResult.Exception != nil {
+			// The tx has taken its fee even so
+			receipt.Code = sm.CodeTypeVMException
+			receipt.Log = callResult.Exception.Error()
+			txReceipt.Code, txReceipt.Log = receipt.Code, receipt.Log
+			return abci.NewResult(receipt.Code, wire.BinaryBytes(receipt), receipt.Log)
+		}
+		txReceipt.ContractAddr = receipt.ContractAddr
+		txReceipt.Logs = logs.logs
+	}
 	receiptBytes := wire.BinaryBytes(receipt)
 	return abci.NewResultOK(receiptBytes, "Success")
 }
 
+// Passes events on while keeping the logs fired by the VM
+type logRecorder struct {
+	tendermint_events.Fireable
+	logs []*txs.EventDataLog
+}
+
+func (recorder *logRecorder) FireEvent(event string, data tendermint_events.EventData) {
+	if log, ok := data.(txs.EventDataLog); ok {
+		recorder.logs = append(recorder.logs, &log)
+	}
+	recorder.Fireable.FireEvent(event, data)
+}
+
+// Keeps the receipt of a tx of the block being run to be indexed with it
+func (app *BurrowMint) addTxReceipt(tx txs.Tx, txReceipt *txs.TxReceipt) {
+	app.mtx.Lock() // the lock protects app.state
+	defer app.mtx.Unlock()
+	app.state.AddTxReceipt(tx, txReceipt)
+}
+
 // Implements manager/types.Application
 func (app *BurrowMint) CheckTx(txBytes []byte) abci.Result {
-	var n int
-	var err error
-	tx := new(txs.Tx)
-	buf := bytes.NewBuffer(txBytes)
-	wire.ReadBinaryPtr(tx, buf, len(txBytes), &n, &err)
+	tx, err := txs.DecodeTx(txBytes)
 	if err != nil {
 		return abci.NewError(abci.CodeType_EncodingError, fmt.Sprintf("Encoding error: %v", err))
 	}
 
-	// TODO: map ExecTx errors to sensible abci error codes
-	err = sm.ExecTx(app.checkCache, *tx, false, nil)
+	err = sm.ExecTx(app.checkCache, tx, false, nil)
 	if err != nil {
-		return abci.NewError(abci.CodeType_InternalError, fmt.Sprintf("Internal error: %v", err))
+		return execErrorResult(app.state.ChainID, tx, err)
 	}
-	receipt := txs.GenerateReceipt(app.state.ChainID, *tx)
+	receipt := txs.GenerateReceipt(app.state.ChainID, tx)
 	receiptBytes := wire.BinaryBytes(receipt)
 	return abci.NewResultOK(receiptBytes, "Success")
 }
 
+// Reports an error of ExecTx with the ABCI code of its kind, and as data the
+// receipt of the tx carrying the same code
+func execErrorResult(chainID string, tx txs.Tx, err error) abci.Result {
+	receipt := txs.GenerateReceipt(chainID, tx)
+	receipt.Code = sm.ErrorCodeOf(err).ABCICode()
+	receipt.Log = err.Error()
+	return abci.NewResult(receipt.Code, wire.BinaryBytes(receipt), receipt.Log)
+}
+
+// Implements manager/types.BlockchainAware
+// The genesis validators are already in the state made from the genesis doc
+func (app *BurrowMint) InitChain(validators []*abci.Validator) {
+}
+
+// Implements manager/types.BlockchainAware
+// Takes the time and hash of the block from its header for the VM
+func (app *BurrowMint) BeginBlock(hash []byte, header *abci.Header) {
+	app.mtx.Lock() // the lock protects app.state
+	defer app.mtx.Unlock()
+
+	if header.Height != uint64(app.state.LastBlockHeight+1) {
+		logging.InfoMsg(app.logger, "Beginning block at unexpected height",
+			"height", header.Height,
+			"last_block_height", app.state.LastBlockHeight)
+	}
+	app.state.BeginBlock(hash, time.Unix(int64(header.Time), 0))
+}
+
+// Implements manager/types.BlockchainAware
+// Releases the validators done unbonding, pays out the fees of the block and
+// returns the validators bonded, unbonded or rebonded in it to Tendermint
+func (app *BurrowMint) EndBlock(height uint64) (res abci.ResponseEndBlock) {
+	app.mtx.Lock() // the lock protects app.state, which takes the block's fees
+	defer app.mtx.Unlock()
+
+	sm.ReleaseValidators(app.cache, int(height))
+	if fees := sm.DistributeFees(app.cache); fees > 0 {
+		logging.InfoMsg(app.logger, "Distributed fees",
+			"height", height,
+			"fees", fees)
+	}
+	for _, valInfo := range app.cache.ValidatorInfoUpdates() {
+		power := valInfo.VotingPower()
+		// Tendermint refuses to remove validators not in its set, so only
+		// changes of power are returned
+		var lastPower int64
+		if lastValInfo := app.state.GetValidatorInfo(valInfo.Address); lastValInfo != nil {
+			lastPower = lastValInfo.VotingPower()
+		}
+		if power == lastPower {
+			continue
+		}
+		logging.InfoMsg(app.logger, "Updating validator",
+			"address", fmt.Sprintf("%X", valInfo.Address),
+			"power", power)
+		res.Diffs = append(res.Diffs, &abci.Validator{
+			PubKey: valInfo.PubKey.Bytes(),
+			Power:  uint64(power),
+		})
+	}
+	return res
+}
+
 // Implements manager/types.Application
 // Commit the state (called at end of block)
 // NOTE: CheckTx/AppendTx must not run concurrently with Commit -
@@ -146,7 +259,7 @@ func (app *BurrowMint) Commit() (res abci.Result) {
 	app.mtx.Lock() // the lock protects app.state
 	defer app.mtx.Unlock()
 
-	app.state.LastBlockHeight += 1
+	app.state.FinishBlock()
 	logging.InfoMsg(app.logger, "Committing block",
 		"last_block_height", app.state.LastBlockHeight)
 
@@ -163,19 +276,31 @@ func (app *BurrowMint) Commit() (res abci.Result) {
 	// save state to disk
 	app.state.Save()
 
+	app.committedMtx.Lock()
+	app.committedState = app.state.Copy()
+	app.committedMtx.Unlock()
+
 	// flush events to listeners (XXX: note issue with blocking)
 	app.evc.Flush()
 
-	// TODO: [ben] over the tendermint 0.6 TMSP interface we have
-	// no access to the block header implemented;
-	// On Tendermint v0.8 load the blockheader into the application
-	// state and remove the fixed 2-"seconds" per block internal clock.
-	// NOTE: set internal time as two seconds per block
-	app.state.LastBlockTime = app.state.LastBlockTime.Add(time.Duration(2) * time.Second)
 	appHash := app.state.Hash()
 	return abci.NewResultOK(appHash, "Success")
 }
 
+// Implements manager/types.Application
+// Answers queries on the last committed state with proofs against its app
+// hash. See state.ParseQueryPath for the paths queried and client.VerifyQuery
+// for checking the proofs.
 func (app *BurrowMint) Query(query []byte) (res abci.Result) {
-	return abci.NewResultOK(nil, "Success")
+	parsedQuery, err := sm.ParseQueryPath(string(query))
+	if err != nil {
+		return abci.NewError(abci.CodeType_UnknownRequest, err.Error())
+	}
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	result, err := app.state.Query(parsedQuery)
+	if err != nil {
+		return abci.NewError(abci.CodeType_BaseUnknownAddress, err.Error())
+	}
+	return abci.NewResultOK(wire.BinaryBytes(result), "Success")
 }
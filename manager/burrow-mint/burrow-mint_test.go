@@ -15,9 +15,28 @@
 package burrowmint
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/hyperledger/burrow/account"
+	"github.com/hyperledger/burrow/client"
+	core_types "github.com/hyperledger/burrow/core/types"
+	edb_event "github.com/hyperledger/burrow/event"
+	genesis "github.com/hyperledger/burrow/genesis"
+	"github.com/hyperledger/burrow/logging/loggers"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm"
+	sm "github.com/hyperledger/burrow/manager/burrow-mint/state"
+	"github.com/hyperledger/burrow/txs"
+	"github.com/hyperledger/burrow/word256"
 	assert "github.com/stretchr/testify/assert"
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/go-crypto"
+	dbm "github.com/tendermint/go-db"
+	tendermint_events "github.com/tendermint/go-events"
+	wire "github.com/tendermint/go-wire"
 )
 
 func TestCompatibleConsensus(t *testing.T) {
@@ -28,3 +47,345 @@ func TestCompatibleConsensus(t *testing.T) {
 		assert.Nil(t, AssertCompatibleConsensus(listedConsensus))
 	}
 }
+
+func TestQuery(t *testing.T) {
+	owner := account.GenPrivAccountFromSecret("owner")
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "query",
+		Accounts:   []genesis.GenesisAccount{{Address: owner.Address, Amount: 1000}},
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	contract := account.GenPrivAccountFromSecret("contract").Address
+	cache := sm.NewBlockCache(st)
+	cache.UpdateAccount(&account.Account{Address: contract, Code: []byte{0x00}})
+	cache.SetStorage(word256.LeftPadWord256(contract), word256.Int64ToWord256(1),
+		word256.Int64ToWord256(42))
+	cache.UpdateNameRegEntry(&core_types.NameRegEntry{Name: "foo", Owner: owner.Address,
+		Data: "bar", Expires: 10})
+	cache.Sync()
+	appHash := st.Hash()
+	app := NewBurrowMint(st, nil, loggers.NewNoopInfoTraceLogger())
+
+	for _, path := range []string{
+		fmt.Sprintf("/account/%X", owner.Address),
+		fmt.Sprintf("/storage/%X/01", contract),
+		"/name/foo",
+	} {
+		res := app.Query([]byte(path))
+		assert.Equal(t, abci.CodeType_OK, res.Code, res.Log)
+		result, err := client.VerifyQuery(res.Data, appHash)
+		assert.NoError(t, err, path)
+		_, err = client.VerifyQuery(res.Data, []byte("not the app hash"))
+		assert.Error(t, err, path)
+		if path == "/name/foo" {
+			assert.Equal(t, "bar", sm.DecodeNameRegEntry(result.Value).Data)
+		}
+	}
+
+	res := app.Query([]byte(fmt.Sprintf("/storage/%X/02", contract)))
+	assert.Equal(t, abci.CodeType_BaseUnknownAddress, res.Code)
+	res = app.Query([]byte("/validator/foo"))
+	assert.Equal(t, abci.CodeType_UnknownRequest, res.Code)
+}
+
+func TestEndBlockValidatorUpdates(t *testing.T) {
+	bonder := account.GenPrivAccountFromSecret("bonder")
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "end_block",
+		Accounts:   []genesis.GenesisAccount{{Address: bonder.Address, Amount: 1000}},
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	evsw := tendermint_events.NewEventSwitch()
+	var bondEvents int
+	evsw.AddListenerForEvent("test", txs.EventStringBond(), func(data tendermint_events.EventData) {
+		bondEvents++
+	})
+	app := NewBurrowMint(st, evsw, loggers.NewNoopInfoTraceLogger())
+
+	bondTx, _ := txs.NewBondTx(bonder.PubKey)
+	bondTx.AddInput(st, bonder.PubKey, 100)
+	bondTx.AddOutput(bonder.Address, 100)
+	bondTx.SignInput(st.ChainID, 0, bonder)
+	bondTx.SignBond(st.ChainID, bonder)
+	res := app.DeliverTx(wire.BinaryBytes(struct{ txs.Tx }{bondTx}))
+	assert.Equal(t, abci.CodeType_OK, res.Code, res.Log)
+	assert.Equal(t, []*abci.Validator{{PubKey: bonder.PubKey.Bytes(), Power: 100}},
+		app.EndBlock(1).Diffs)
+	app.Commit()
+	assert.Equal(t, 1, bondEvents)
+
+	// Nothing changed in the next block
+	assert.Empty(t, app.EndBlock(2).Diffs)
+	app.Commit()
+
+	unbondTx := txs.NewUnbondTx(bonder.Address, 3)
+	unbondTx.Sign(st.ChainID, bonder)
+	res = app.DeliverTx(wire.BinaryBytes(struct{ txs.Tx }{unbondTx}))
+	assert.Equal(t, abci.CodeType_OK, res.Code, res.Log)
+	assert.Equal(t, []*abci.Validator{{PubKey: bonder.PubKey.Bytes(), Power: 0}},
+		app.EndBlock(3).Diffs)
+	app.Commit()
+}
+
+func TestEndBlockSNativeValidatorUpdates(t *testing.T) {
+	owner := account.GenPrivAccountFromSecret("owner")
+	validator := account.GenPrivAccountFromSecret("validator")
+	bonder := account.GenPrivAccountFromSecret("bonder")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "end_block_snative",
+		Accounts:   []genesis.GenesisAccount{{Address: owner.Address, Amount: 1000}},
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	app := NewBurrowMint(st, tendermint_events.NewEventSwitch(), loggers.NewNoopInfoTraceLogger())
+
+	// Bond through the Validators SNative as a contract would
+	txCache := sm.NewTxCache(app.cache)
+	caller := txCache.GetAccount(word256.LeftPadWord256(owner.Address))
+	contract := vm.SNativeContracts()["Validators"]
+	function, err := contract.FunctionByName("bond")
+	assert.NoError(t, err)
+	funcID := function.ID()
+	pubKey := bonder.PubKey.(crypto.PubKeyEd25519)
+	bondTx := txs.NewSNativeBondTx(pubKey, owner.Address, 100)
+	bondTx.SignBond(st.ChainID, bonder)
+	gas := int64(1000)
+	amount := word256.Int64ToWord256(100)
+	// The signature is dynamic so comes after the head as its length and bytes
+	args := append(append(append(funcID[:], pubKey[:]...), amount[:]...),
+		word256.Int64ToWord256(96).Bytes()...)
+	args = append(append(args, word256.Int64ToWord256(64).Bytes()...), bondTx.Signature[:]...)
+	_, err = contract.Dispatch(txCache, caller, args, &gas)
+	assert.NoError(t, err)
+	txCache.Sync()
+	assert.Equal(t, []*abci.Validator{{PubKey: bonder.PubKey.Bytes(), Power: 100}},
+		app.EndBlock(1).Diffs)
+	app.Commit()
+	assert.Equal(t, int64(900), app.state.GetAccount(owner.Address).Balance)
+}
+
+func TestBeginBlock(t *testing.T) {
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "begin_block",
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	app := NewBurrowMint(st, tendermint_events.NewEventSwitch(), loggers.NewNoopInfoTraceLogger())
+
+	// Beginning a block must not race with RPC readers copying the state
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.GetState()
+	}()
+	blockHash := []byte("block one")
+	app.BeginBlock(blockHash, &abci.Header{Height: 1, Time: 1500000000})
+	<-done
+	params := app.GetState().VMParams()
+	assert.Equal(t, int64(1), params.BlockHeight)
+	assert.Equal(t, int64(1500000000), params.BlockTime)
+	app.Commit()
+
+	st = app.GetState()
+	assert.Equal(t, time.Unix(1500000000, 0), st.LastBlockTime)
+	assert.Equal(t, blockHash, st.LastBlockHash)
+	assert.Equal(t, word256.LeftPadWord256(blockHash), st.GetBlockHash(1))
+	// Until the next block begins calls run as if in a block at the same time
+	params = st.VMParams()
+	assert.Equal(t, int64(2), params.BlockHeight)
+	assert.Equal(t, int64(1500000000), params.BlockTime)
+}
+
+func TestHistoricalQueries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "historical_queries")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := dbm.NewDB("state", dbm.GoLevelDBBackendStr, dir)
+	defer db.Close()
+	sender := account.GenPrivAccountFromSecret("sender")
+	receiver := account.GenPrivAccountFromSecret("receiver")
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(db, &genesis.GenesisDoc{
+		ChainID:    "historical_queries",
+		Accounts:   []genesis.GenesisAccount{{Address: sender.Address, Amount: 1000}},
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	st.Save()
+	app := NewBurrowMint(st, tendermint_events.NewEventSwitch(), loggers.NewNoopInfoTraceLogger())
+	pipe := &burrowMintPipe{burrowMint: app}
+
+	// Each block sends the receiver 10 more, changing the same accounts so
+	// later trees orphan the nodes of earlier ones
+	for height := 1; height <= 5; height++ {
+		sendTx := txs.NewSendTx()
+		sendTx.AddInputWithNonce(sender.PubKey, 10, height)
+		sendTx.AddOutput(receiver.Address, 10)
+		sendTx.SignInput(st.ChainID, 0, sender)
+		res := app.DeliverTx(wire.BinaryBytes(struct{ txs.Tx }{sendTx}))
+		assert.Equal(t, abci.CodeType_OK, res.Code, res.Log)
+		app.EndBlock(uint64(height))
+		app.Commit()
+	}
+
+	for _, height := range []int{1, 2, 5} {
+		result, err := pipe.GetAccount(receiver.Address, height)
+		if assert.NoError(t, err, "height %v", height) {
+			assert.Equal(t, int64(10*height), result.Account.Balance, "height %v", height)
+		}
+	}
+	listed, err := pipe.ListAccounts(1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, listed.BlockHeight)
+	}
+	_, err = pipe.GetAccount(receiver.Address, 6)
+	assert.Error(t, err)
+}
+
+func TestTxResultCodes(t *testing.T) {
+	caller := account.GenPrivAccountFromSecret("caller")
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "result_codes",
+		Accounts:   []genesis.GenesisAccount{{Address: caller.Address, Amount: 1000}},
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	// PUSH1 0, PUSH1 0, REVERT
+	reverter := account.GenPrivAccountFromSecret("reverter").Address
+	st.UpdateAccount(&account.Account{Address: reverter, Code: []byte{0x60, 0x00, 0x60, 0x00, 0xfd}})
+	app := NewBurrowMint(st, tendermint_events.NewEventSwitch(), loggers.NewNoopInfoTraceLogger())
+
+	checkReceipt := func(res abci.Result, code abci.CodeType) {
+		assert.Equal(t, code, res.Code, res.Log)
+		receipt := new(txs.Receipt)
+		assert.NoError(t, wire.ReadBinaryBytes(res.Data, receipt))
+		assert.Equal(t, code, receipt.Code)
+		assert.Equal(t, res.Log, receipt.Log)
+	}
+
+	sendTx := txs.NewSendTx()
+	sendTx.AddInputWithNonce(caller.PubKey, 10, 2)
+	sendTx.AddOutput(validator.Address, 10)
+	sendTx.SignInput(st.ChainID, 0, caller)
+	checkReceipt(app.CheckTx(wire.BinaryBytes(struct{ txs.Tx }{sendTx})),
+		abci.CodeType_BaseInvalidSequence)
+
+	sendTx = txs.NewSendTx()
+	sendTx.AddInputWithNonce(caller.PubKey, 5000, 1)
+	sendTx.AddOutput(validator.Address, 5000)
+	sendTx.SignInput(st.ChainID, 0, caller)
+	checkReceipt(app.CheckTx(wire.BinaryBytes(struct{ txs.Tx }{sendTx})),
+		abci.CodeType_BaseInsufficientFunds)
+
+	// A call the VM throws on is still run
+	callTx := txs.NewCallTxWithNonce(caller.PubKey, reverter, nil, 1, 1000, 0, 1)
+	callTx.Sign(st.ChainID, caller)
+	checkReceipt(app.DeliverTx(wire.BinaryBytes(struct{ txs.Tx }{callTx})),
+		sm.CodeTypeVMException)
+	app.Commit()
+	assert.Equal(t, 1, app.GetState().GetAccount(caller.Address).Sequence)
+}
+
+func TestTxReceipts(t *testing.T) {
+	caller := account.GenPrivAccountFromSecret("caller")
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "tx_receipts",
+		Accounts:   []genesis.GenesisAccount{{Address: caller.Address, Amount: 1000}},
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	// PUSH1 0, PUSH1 0, LOG0, STOP
+	logger := account.GenPrivAccountFromSecret("logger").Address
+	st.UpdateAccount(&account.Account{Address: logger, Code: []byte{0x60, 0x00, 0x60, 0x00, 0xa0, 0x00}})
+	// PUSH1 0, PUSH1 0, REVERT
+	reverter := account.GenPrivAccountFromSecret("reverter").Address
+	st.UpdateAccount(&account.Account{Address: reverter, Code: []byte{0x60, 0x00, 0x60, 0x00, 0xfd}})
+	app := NewBurrowMint(st, tendermint_events.NewEventSwitch(), loggers.NewNoopInfoTraceLogger())
+
+	logTx := txs.NewCallTxWithNonce(caller.PubKey, logger, nil, 1, 1000, 0, 1)
+	logTx.Sign(st.ChainID, caller)
+	revertTx := txs.NewCallTxWithNonce(caller.PubKey, reverter, nil, 1, 1000, 0, 2)
+	revertTx.Sign(st.ChainID, caller)
+	badSequenceTx := txs.NewCallTxWithNonce(caller.PubKey, logger, nil, 1, 1000, 0, 5)
+	badSequenceTx.Sign(st.ChainID, caller)
+	// Receipts are kept as txs are run while RPC readers may copy the state
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		app.GetState()
+	}()
+	for _, tx := range []txs.Tx{logTx, revertTx, badSequenceTx} {
+		app.DeliverTx(wire.BinaryBytes(struct{ txs.Tx }{tx}))
+	}
+	<-done
+	// Receipts are only stored once the block is committed
+	assert.Nil(t, app.GetState().GetTxReceipt(txs.TxHash(st.ChainID, logTx)))
+	app.Commit()
+
+	receipt := app.GetState().GetTxReceipt(txs.TxHash(st.ChainID, logTx))
+	if assert.NotNil(t, receipt) {
+		assert.Equal(t, 1, receipt.Height)
+		assert.Equal(t, 0, receipt.Index)
+		assert.Equal(t, abci.CodeType_OK, receipt.Code)
+		assert.True(t, receipt.GasUsed > 0)
+		if assert.Len(t, receipt.Logs, 1) {
+			assert.Equal(t, logger, receipt.Logs[0].Address.Postfix(20))
+		}
+	}
+
+	receipt = app.GetState().GetTxReceipt(txs.TxHash(st.ChainID, revertTx))
+	if assert.NotNil(t, receipt) {
+		assert.Equal(t, 1, receipt.Index)
+		assert.Equal(t, sm.CodeTypeVMException, receipt.Code)
+		assert.NotEmpty(t, receipt.Log)
+		assert.Empty(t, receipt.Logs)
+	}
+
+	receipt = app.GetState().GetTxReceipt(txs.TxHash(st.ChainID, badSequenceTx))
+	if assert.NotNil(t, receipt) {
+		assert.Equal(t, 2, receipt.Index)
+		assert.Equal(t, abci.CodeType_BaseInvalidSequence, receipt.Code)
+	}
+}
+
+func TestDecodingEventsOnCommit(t *testing.T) {
+	caller := account.GenPrivAccountFromSecret("caller")
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "decoding_events",
+		Accounts:   []genesis.GenesisAccount{{Address: caller.Address, Amount: 1000}},
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	// PUSH1 0, PUSH1 0, LOG0, STOP
+	logger := account.GenPrivAccountFromSecret("logger").Address
+	st.UpdateAccount(&account.Account{Address: logger, Code: []byte{0x60, 0x00, 0x60, 0x00, 0xa0, 0x00}})
+	evsw := tendermint_events.NewEventSwitch()
+	app := NewBurrowMint(st, evsw, loggers.NewNoopInfoTraceLogger())
+	events := newDecodingEvents(edb_event.NewEvents(evsw, loggers.NewNoopInfoTraceLogger()), app)
+
+	logs := make(chan txs.EventData, 1)
+	assert.NoError(t, events.Subscribe("logs", txs.EventStringLogEvent(logger),
+		func(eventData txs.EventData) {
+			logs <- eventData
+		}))
+	logTx := txs.NewCallTxWithNonce(caller.PubKey, logger, nil, 1, 1000, 0, 1)
+	logTx.Sign(st.ChainID, caller)
+	app.DeliverTx(wire.BinaryBytes(struct{ txs.Tx }{logTx}))
+	// Listeners are run by Commit, so must not wait on the app
+	committed := make(chan struct{})
+	go func() {
+		defer close(committed)
+		app.Commit()
+	}()
+	select {
+	case <-committed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Commit deadlocked flushing events")
+	}
+	select {
+	case eventData := <-logs:
+		assert.IsType(t, txs.EventDataLog{}, eventData)
+	default:
+		t.Fatal("Expected the log event")
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	. "github.com/hyperledger/burrow/word256"
+)
+
+// Values are encoded in words of 32 bytes
+const wordLength = 32
+
+var twoTo256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// An argument or return value with its value written out: integers in
+// decimal, addresses and bytes in hex and strings as they are
+type DecodedArg struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type DecodedCall struct {
+	Function string       `json:"function"`
+	Args     []DecodedArg `json:"args"`
+	Returns  []DecodedArg `json:"returns"`
+}
+
+type DecodedEvent struct {
+	Event string       `json:"event"`
+	Args  []DecodedArg `json:"args"`
+}
+
+// Decodes the call data of a call to one of the ABI's functions and, if ret
+// is not empty, what the call returned
+func (abi *ABI) DecodeCall(data, ret []byte) (*DecodedCall, error) {
+	if len(data) < FunctionSelectorLength {
+		return nil, fmt.Errorf("Call data is too short to hold a function selector")
+	}
+	var selector FunctionSelector
+	copy(selector[:], data)
+	method := abi.FunctionBySelector(selector)
+	if method == nil {
+		return nil, fmt.Errorf("No function has selector %X", selector)
+	}
+	args, err := DecodeArgs(method.Inputs, data[FunctionSelectorLength:])
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode arguments of '%s': %v", method.Name, err)
+	}
+	call := &DecodedCall{Function: method.Name, Args: args}
+	if len(ret) > 0 {
+		call.Returns, err = DecodeArgs(method.Outputs, ret)
+		if err != nil {
+			return nil, fmt.Errorf("Could not decode return values of '%s': %v", method.Name, err)
+		}
+	}
+	return call, nil
+}
+
+// Decodes a log emitted by one of the ABI's events. Indexed arguments are
+// taken from the topics after the event's ID, except that those of types
+// that do not fit in a word are only there as hashes, which are given in hex.
+func (abi *ABI) DecodeLog(topics []Word256, data []byte) (*DecodedEvent, error) {
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("Log has no topics, so no event ID")
+	}
+	event := abi.EventByID(topics[0])
+	if event == nil {
+		return nil, fmt.Errorf("No event has ID %X", topics[0].Bytes())
+	}
+	values, err := event.Unpack(topics, data)
+	if err != nil {
+		return nil, err
+	}
+	decoded := &DecodedEvent{Event: event.Name, Args: make([]DecodedArg, len(event.Inputs))}
+	for i, arg := range event.Inputs {
+		decoded.Args[i] = DecodedArg{Name: arg.Name, Type: arg.Type.String()}
+		if arg.Indexed && !arg.Type.elementary() {
+			decoded.Args[i].Value = fmt.Sprintf("%X", values[i])
+			continue
+		}
+		decoded.Args[i].Value = FormatValue(arg.Type, values[i])
+	}
+	return decoded, nil
+}
+
+// Decodes the arguments of a log the event emitted, in the order of its
+// inputs and as Unpack gives them, except that indexed arguments of types
+// that do not fit in a word are given as the bytes of their hash
+func (event *Event) Unpack(topics []Word256, data []byte) ([]interface{}, error) {
+	if !event.Anonymous {
+		if len(topics) == 0 || topics[0] != event.ID {
+			return nil, fmt.Errorf("Log is not of event '%s'", event.Name)
+		}
+		topics = topics[1:]
+	}
+	var dataArgs []Argument
+	for _, arg := range event.Inputs {
+		if !arg.Indexed {
+			dataArgs = append(dataArgs, arg)
+		}
+	}
+	dataValues, err := Unpack(dataArgs, data)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode data of event '%s': %v", event.Name, err)
+	}
+	values := make([]interface{}, len(event.Inputs))
+	for i, arg := range event.Inputs {
+		if !arg.Indexed {
+			values[i] = dataValues[0]
+			dataValues = dataValues[1:]
+			continue
+		}
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("Log has too few topics for event '%s'", event.Name)
+		}
+		topic := topics[0].Bytes()
+		topics = topics[1:]
+		if !arg.Type.elementary() {
+			values[i] = topic
+			continue
+		}
+		if values[i], err = decodeWord(arg.Type, topic); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Decodes values encoded one after another, as arguments and return values
+// are, and writes them out
+func DecodeArgs(args []Argument, data []byte) ([]DecodedArg, error) {
+	values, err := Unpack(args, data)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]DecodedArg, len(args))
+	for i, arg := range args {
+		decoded[i] = DecodedArg{Name: arg.Name, Type: arg.Type.String(), Value: FormatValue(arg.Type, values[i])}
+	}
+	return decoded, nil
+}
+
+// Decodes values encoded one after another, as arguments and return values
+// are. Integers of up to 64 bits are given as uint64 or int64 and larger ones
+// as *big.Int, addresses as Address, bytes of either sort as []byte, arrays
+// and tuples as []interface{} and other values as the Go type of their name.
+func Unpack(args []Argument, data []byte) ([]interface{}, error) {
+	return decodeTuple(componentTypes(args), data)
+}
+
+// Decodes values encoded one after another, where the head of each of a
+// dynamic type holds the offset in data of the rest of it
+func decodeTuple(types []Type, data []byte) ([]interface{}, error) {
+	values := make([]interface{}, len(types))
+	offset := 0
+	for i, t := range types {
+		value, err := decodeValue(t, data, offset)
+		if err != nil {
+			return nil, fmt.Errorf("argument %v: %v", i, err)
+		}
+		values[i] = value
+		offset += t.headLength()
+	}
+	return values, nil
+}
+
+func readWord(data []byte, offset int) ([]byte, error) {
+	if offset < 0 || offset+wordLength > len(data) {
+		return nil, fmt.Errorf("data ends before word at %v", offset)
+	}
+	return data[offset : offset+wordLength], nil
+}
+
+// Reads an offset or length, which must lie within data
+func readInt(data []byte, offset int) (int, error) {
+	word, err := readWord(data, offset)
+	if err != nil {
+		return 0, err
+	}
+	n := new(big.Int).SetBytes(word)
+	if n.BitLen() > 31 || int(n.Int64()) > len(data) {
+		return 0, fmt.Errorf("offset or length %v at %v is beyond the end of the data", n, offset)
+	}
+	return int(n.Int64()), nil
+}
+
+// Decodes the value whose head is at offset in data. The head of a value of
+// a dynamic type is the offset in data of the rest of it: for bytes and
+// strings their length and then their bytes, for dynamic arrays their length
+// and then their elements as a tuple, and for other types their elements or
+// components as a tuple.
+func decodeValue(t Type, data []byte, offset int) (interface{}, error) {
+	if !t.Dynamic() {
+		switch t.Kind {
+		case ArrayKind, TupleKind:
+			if offset > len(data) {
+				return nil, fmt.Errorf("data ends before value at %v", offset)
+			}
+			if t.Kind == ArrayKind {
+				if err := checkMembersFit(t.Size, t.Elem, data[offset:]); err != nil {
+					return nil, err
+				}
+			}
+			return decodeTuple(t.memberTypes(0), data[offset:])
+		}
+		word, err := readWord(data, offset)
+		if err != nil {
+			return nil, err
+		}
+		return decodeWord(t, word)
+	}
+	start, err := readInt(data, offset)
+	if err != nil {
+		return nil, err
+	}
+	tail := data[start:]
+	switch t.Kind {
+	case BytesKind, StringKind:
+		length, err := readInt(tail, 0)
+		if err != nil {
+			return nil, err
+		}
+		if wordLength+length > len(tail) {
+			return nil, fmt.Errorf("data ends before the %v bytes at %v", length, start+wordLength)
+		}
+		bs := tail[wordLength : wordLength+length]
+		if t.Kind == StringKind {
+			return string(bs), nil
+		}
+		return append([]byte{}, bs...), nil
+	case SliceKind:
+		length, err := readInt(tail, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkMembersFit(length, t.Elem, tail[wordLength:]); err != nil {
+			return nil, err
+		}
+		return decodeTuple(t.memberTypes(length), tail[wordLength:])
+	case ArrayKind:
+		if err := checkMembersFit(t.Size, t.Elem, tail); err != nil {
+			return nil, err
+		}
+	}
+	return decodeTuple(t.memberTypes(0), tail)
+}
+
+// Errors unless data holds the heads of n members of type elem, before room
+// is made for them
+func checkMembersFit(n int, elem *Type, data []byte) error {
+	if elemLength := elem.headLength(); elemLength > 0 && n > len(data)/elemLength {
+		return fmt.Errorf("data ends before the %v members of type %v", n, elem)
+	}
+	return nil
+}
+
+func decodeWord(t Type, word []byte) (interface{}, error) {
+	switch t.Kind {
+	case UintKind:
+		n := new(big.Int).SetBytes(word)
+		if n.BitLen() > t.Size {
+			return nil, fmt.Errorf("%v does not fit in %v", n, t)
+		}
+		if t.Size <= 64 {
+			return n.Uint64(), nil
+		}
+		return n, nil
+	case IntKind:
+		n := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			n.Sub(n, twoTo256)
+		}
+		if !intFits(n, t.Size) {
+			return nil, fmt.Errorf("%v does not fit in %v", n, t)
+		}
+		if t.Size <= 64 {
+			return n.Int64(), nil
+		}
+		return n, nil
+	case AddressKind:
+		if !allZero(word[:wordLength-AddressLength]) {
+			return nil, fmt.Errorf("invalid address %X", word)
+		}
+		var address Address
+		copy(address[:], word[wordLength-AddressLength:])
+		return address, nil
+	case BoolKind:
+		n := new(big.Int).SetBytes(word)
+		if n.BitLen() > 1 {
+			return nil, fmt.Errorf("invalid bool %X", word)
+		}
+		return n.Sign() == 1, nil
+	case FixedBytesKind:
+		return append([]byte{}, word[:t.Size]...), nil
+	}
+	return nil, fmt.Errorf("cannot decode a %v from a word", t)
+}
+
+// Whether n is in the range of the signed integers of size bits
+func intFits(n *big.Int, size int) bool {
+	if n.Sign() >= 0 {
+		return n.BitLen() < size
+	}
+	return new(big.Int).Not(n).BitLen() < size
+}
+
+func allZero(bs []byte) bool {
+	for _, b := range bs {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Writes out a value of type t as Unpack gives it: integers in decimal,
+// addresses and bytes in hex, strings as they are, and arrays and tuples as
+// their elements or components in brackets or parentheses
+func FormatValue(t Type, value interface{}) string {
+	switch t.Kind {
+	case AddressKind, FixedBytesKind, BytesKind:
+		return fmt.Sprintf("%X", value)
+	case ArrayKind, SliceKind, TupleKind:
+		values, ok := value.([]interface{})
+		if !ok {
+			break
+		}
+		types := t.memberTypes(len(values))
+		formatted := make([]string, len(values))
+		for i, value := range values {
+			formatted[i] = FormatValue(types[i], value)
+		}
+		if t.Kind == TupleKind {
+			return fmt.Sprintf("(%s)", strings.Join(formatted, ","))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(formatted, ","))
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
+	. "github.com/hyperledger/burrow/word256"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tokenABI = `[
+	{"type":"constructor","inputs":[{"name":"supply","type":"uint"}]},
+	{"type":"function","name":"transfer","constant":false,
+	 "inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],
+	 "outputs":[{"name":"ok","type":"bool"}]},
+	{"type":"function","name":"setMemo","stateMutability":"nonpayable",
+	 "inputs":[{"name":"memo","type":"string"},{"name":"tag","type":"bytes4"}],"outputs":[]},
+	{"type":"event","name":"Transfer","inputs":[
+	 {"name":"from","type":"address","indexed":true},
+	 {"name":"memo","type":"string","indexed":true},
+	 {"name":"delta","type":"int64","indexed":false},
+	 {"name":"data","type":"bytes","indexed":false}]},
+	{"type":"fallback","payable":true}
+]`
+
+func word(hexString string) []byte {
+	bs, err := hex.DecodeString(hexString)
+	if err != nil {
+		panic(err)
+	}
+	return LeftPadBytes(bs, 32)
+}
+
+func concat(parts ...[]byte) []byte {
+	var bs []byte
+	for _, part := range parts {
+		bs = append(bs, part...)
+	}
+	return bs
+}
+
+func TestJSON(t *testing.T) {
+	abi, err := JSON([]byte(tokenABI))
+	assert.NoError(t, err)
+	assert.Equal(t, []Argument{{Name: "supply", Type: Type{Kind: UintKind, Size: 256}}},
+		abi.Constructor.Inputs)
+	if assert.Len(t, abi.Functions, 2) {
+		assert.Equal(t, "transfer(address,uint256)", abi.Functions[0].Signature())
+		assert.Equal(t, FunctionSelector{0xa9, 0x05, 0x9c, 0xbb}, abi.Functions[0].Selector)
+		assert.Equal(t, "setMemo(string,bytes4)", abi.Functions[1].Signature())
+	}
+	if assert.Len(t, abi.Events, 1) {
+		assert.Equal(t, "Transfer(address,string,int64,bytes)", abi.Events[0].Signature())
+	}
+	assert.Equal(t, abi.Functions[1], abi.Function("setMemo"))
+	assert.Nil(t, abi.Function("approve"))
+
+	_, err = JSON([]byte(`[{"type":"function","name":"f","inputs":[{"name":"x","type":"uint257"}]}]`))
+	assert.Error(t, err)
+	_, err = JSON([]byte(`[{"type":"receive"}]`))
+	assert.Error(t, err)
+	_, err = JSON([]byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestDecodeCall(t *testing.T) {
+	abi, err := JSON([]byte(tokenABI))
+	assert.NoError(t, err)
+
+	call, err := abi.DecodeCall(concat([]byte{0xa9, 0x05, 0x9c, 0xbb}, word("0102"), word("03e8")), word("01"))
+	assert.NoError(t, err)
+	assert.Equal(t, &DecodedCall{
+		Function: "transfer",
+		Args: []DecodedArg{
+			{Name: "to", Type: "address", Value: "0000000000000000000000000000000000000102"},
+			{Name: "amount", Type: "uint256", Value: "1000"},
+		},
+		Returns: []DecodedArg{{Name: "ok", Type: "bool", Value: "true"}},
+	}, call)
+
+	setMemo := abi.Function("setMemo").Selector
+	data := concat(setMemo[:], word("40"), RightPadBytes([]byte("tag!"), 32),
+		word("05"), RightPadBytes([]byte("hello"), 32))
+	call, err = abi.DecodeCall(data, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []DecodedArg{
+		{Name: "memo", Type: "string", Value: "hello"},
+		{Name: "tag", Type: "bytes4", Value: "74616721"},
+	}, call.Args)
+	assert.Nil(t, call.Returns)
+
+	// The string's length runs past the end of the data
+	_, err = abi.DecodeCall(concat(setMemo[:], word("40"), word("00"), word("ff")), nil)
+	assert.Error(t, err)
+	_, err = abi.DecodeCall([]byte{0xa9, 0x05, 0x9c, 0xbb, 0x01}, nil)
+	assert.Error(t, err)
+	_, err = abi.DecodeCall([]byte{1, 2, 3, 4}, nil)
+	assert.Error(t, err)
+	// Bools are 0 or 1
+	_, err = abi.DecodeCall(concat([]byte{0xa9, 0x05, 0x9c, 0xbb}, word("0102"), word("03e8")), word("02"))
+	assert.Error(t, err)
+}
+
+func TestDecodeArrayLongerThanData(t *testing.T) {
+	for _, name := range []string{"uint256[32768]", "string[32768]", "uint256[]"} {
+		arrayType, err := ParseType(name)
+		assert.NoError(t, err, name)
+		// The offset of a dynamic array and the length of a slice
+		_, err = Unpack([]Argument{{Type: arrayType}}, concat(word("20"), word("40"), word("00")))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestDecodeLog(t *testing.T) {
+	abi, err := JSON([]byte(tokenABI))
+	assert.NoError(t, err)
+	memoHash := sha3.Sha3([]byte("hello"))
+	topics := []Word256{abi.Events[0].ID, LeftPadWord256(word("0102")), LeftPadWord256(memoHash)}
+	data := concat(word("ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff85"),
+		word("40"), word("02"), RightPadBytes([]byte{0xbe, 0xef}, 32))
+
+	event, err := abi.DecodeLog(topics, data)
+	assert.NoError(t, err)
+	assert.Equal(t, &DecodedEvent{
+		Event: "Transfer",
+		Args: []DecodedArg{
+			{Name: "from", Type: "address", Value: "0000000000000000000000000000000000000102"},
+			{Name: "memo", Type: "string", Value: fmt.Sprintf("%X", memoHash)},
+			{Name: "delta", Type: "int64", Value: "-123"},
+			{Name: "data", Type: "bytes", Value: "BEEF"},
+		},
+	}, event)
+
+	values, err := abi.Events[0].Unpack(topics, data)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{Address{18: 0x01, 19: 0x02}, memoHash, int64(-123), []byte{0xbe, 0xef}}, values)
+	_, err = abi.Events[0].Unpack(topics[1:], data)
+	assert.Error(t, err)
+
+	_, err = abi.DecodeLog(topics[:2], data)
+	assert.Error(t, err)
+	_, err = abi.DecodeLog([]Word256{Zero256}, data)
+	assert.Error(t, err)
+	_, err = abi.DecodeLog(nil, data)
+	assert.Error(t, err)
+}
@@ -0,0 +1,300 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
+	. "github.com/hyperledger/burrow/word256"
+)
+
+// Encodes values one after another as arguments of the types of args.
+//
+// Besides the Go values Unpack gives, any Go integer or a string of one in
+// decimal or 0x-prefixed hex is taken for an integer, a string of hex for an
+// address or bytes, a string of true or false for a bool, any slice or array
+// for an array or tuple, and a JSON array for an array or tuple, so that
+// values can be given as they are written on a command line.
+func Pack(args []Argument, values ...interface{}) ([]byte, error) {
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("%v values given for %v arguments", len(values), len(args))
+	}
+	return encodeTuple(componentTypes(args), values)
+}
+
+// Encodes a value of an indexed event argument as the topic it is emitted
+// as. Values of types that do not fit in a word are emitted as the hash of
+// their encoding, or of their bytes for bytes and strings.
+func EncodeTopic(t Type, value interface{}) (Word256, error) {
+	switch {
+	case t.elementary():
+		encoded, err := encodeValue(t, value)
+		if err != nil {
+			return Zero256, err
+		}
+		return LeftPadWord256(encoded), nil
+	case t.Kind == BytesKind:
+		bs, err := toBytes(value)
+		if err != nil {
+			return Zero256, err
+		}
+		return LeftPadWord256(sha3.Sha3(bs)), nil
+	case t.Kind == StringKind:
+		str, err := toString(value)
+		if err != nil {
+			return Zero256, err
+		}
+		return LeftPadWord256(sha3.Sha3([]byte(str))), nil
+	}
+	encoded, err := encodeValue(t, value)
+	if err != nil {
+		return Zero256, err
+	}
+	return LeftPadWord256(sha3.Sha3(encoded)), nil
+}
+
+// Gives the topics of a txs.LogFilter that matches the logs of the event
+// whose indexed arguments, in order, are the values, where nil matches any
+// value
+func (event *Event) TopicFilter(values ...interface{}) ([][]Word256, error) {
+	var topics [][]Word256
+	if !event.Anonymous {
+		topics = append(topics, []Word256{event.ID})
+	}
+	var indexed []Argument
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if len(values) > len(indexed) {
+		return nil, fmt.Errorf("%v values given for the %v indexed arguments of '%s'",
+			len(values), len(indexed), event.Name)
+	}
+	for i, value := range values {
+		if value == nil {
+			topics = append(topics, nil)
+			continue
+		}
+		topic, err := EncodeTopic(indexed[i].Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("Could not encode '%s' of '%s': %v", indexed[i].Name, event.Name, err)
+		}
+		topics = append(topics, []Word256{topic})
+	}
+	return topics, nil
+}
+
+// Encodes values one after another, with the heads of those of dynamic
+// types holding the offset of the rest of them after all the heads
+func encodeTuple(types []Type, values []interface{}) ([]byte, error) {
+	headLength := typesHeadLength(types)
+	head := make([]byte, 0, headLength)
+	var tail []byte
+	for i, t := range types {
+		encoded, err := encodeValue(t, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("argument %v: %v", i, err)
+		}
+		if t.Dynamic() {
+			head = append(head, Int64ToWord256(int64(headLength+len(tail))).Bytes()...)
+			tail = append(tail, encoded...)
+		} else {
+			head = append(head, encoded...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+// Encodes a value, or for a dynamic type the rest of it that its head points
+// to
+func encodeValue(t Type, value interface{}) ([]byte, error) {
+	switch t.Kind {
+	case UintKind, IntKind:
+		n, err := toBigInt(value)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == UintKind && (n.Sign() < 0 || n.BitLen() > t.Size) ||
+			t.Kind == IntKind && !intFits(n, t.Size) {
+			return nil, fmt.Errorf("%v does not fit in %v", n, t)
+		}
+		if n.Sign() < 0 {
+			n = new(big.Int).Add(n, twoTo256)
+		}
+		return LeftPadBytes(n.Bytes(), wordLength), nil
+	case AddressKind:
+		bs, err := toBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		// Words are accepted as they hold addresses in the VM
+		if len(bs) == wordLength && allZero(bs[:wordLength-AddressLength]) {
+			bs = bs[wordLength-AddressLength:]
+		}
+		if len(bs) != AddressLength {
+			return nil, fmt.Errorf("an address must be %v bytes but %X is not", AddressLength, bs)
+		}
+		return LeftPadBytes(bs, wordLength), nil
+	case BoolKind:
+		b, ok := value.(bool)
+		if !ok {
+			str, err := toString(value)
+			if err != nil {
+				return nil, err
+			}
+			if b, err = strconv.ParseBool(str); err != nil {
+				return nil, fmt.Errorf("invalid bool '%s'", str)
+			}
+		}
+		if b {
+			return LeftPadBytes([]byte{1}, wordLength), nil
+		}
+		return make([]byte, wordLength), nil
+	case FixedBytesKind:
+		bs, err := toBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(bs) > t.Size {
+			return nil, fmt.Errorf("%X is longer than a %v", bs, t)
+		}
+		return RightPadBytes(bs, wordLength), nil
+	case BytesKind, StringKind:
+		var bs []byte
+		if t.Kind == BytesKind {
+			var err error
+			if bs, err = toBytes(value); err != nil {
+				return nil, err
+			}
+		} else {
+			str, err := toString(value)
+			if err != nil {
+				return nil, err
+			}
+			bs = []byte(str)
+		}
+		encoded := Int64ToWord256(int64(len(bs))).Bytes()
+		return append(encoded, RightPadBytes(bs, (len(bs)+wordLength-1)/wordLength*wordLength)...), nil
+	case ArrayKind, SliceKind, TupleKind:
+		values, err := toValues(value)
+		if err != nil {
+			return nil, err
+		}
+		types := t.memberTypes(len(values))
+		if t.Kind != SliceKind && len(values) != len(types) {
+			return nil, fmt.Errorf("a %v has %v members but %v values were given", t, len(types), len(values))
+		}
+		encoded, err := encodeTuple(types, values)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == SliceKind {
+			return append(Int64ToWord256(int64(len(values))).Bytes(), encoded...), nil
+		}
+		return encoded, nil
+	}
+	return nil, fmt.Errorf("cannot encode a %v", t)
+}
+
+func toBigInt(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, nil
+	case Word256:
+		return new(big.Int).SetBytes(v.Bytes()), nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(rv.Uint()), nil
+	case reflect.String:
+		n, ok := new(big.Int).SetString(rv.String(), 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer '%s'", rv.String())
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot take %T for an integer", value)
+}
+
+// Takes byte slices and arrays as they are and strings as hex
+func toBytes(value interface{}) ([]byte, error) {
+	if bs, ok := value.([]byte); ok {
+		return bs, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			bs := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(bs), rv)
+			return bs, nil
+		}
+	case reflect.String:
+		bs, err := hex.DecodeString(strings.TrimPrefix(rv.String(), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex '%s'", rv.String())
+		}
+		return bs, nil
+	}
+	return nil, fmt.Errorf("cannot take %T for bytes", value)
+}
+
+func toString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("cannot take %T for a string", value)
+}
+
+// Takes slices and arrays as their elements and strings as JSON arrays
+func toValues(value interface{}) ([]interface{}, error) {
+	if values, ok := value.([]interface{}); ok {
+		return values, nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, rv.Len())
+		for i := range values {
+			values[i] = rv.Index(i).Interface()
+		}
+		return values, nil
+	case reflect.String:
+		var values []interface{}
+		decoder := json.NewDecoder(bytes.NewBufferString(rv.String()))
+		// Keep large integers exact
+		decoder.UseNumber()
+		if err := decoder.Decode(&values); err != nil {
+			return nil, fmt.Errorf("invalid JSON array '%s'", rv.String())
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("cannot take %T for an array or tuple", value)
+}
@@ -0,0 +1,179 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
+	. "github.com/hyperledger/burrow/word256"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The examples of the Solidity ABI specification
+const specABI = `[
+	{"type":"function","name":"f","inputs":[{"name":"","type":"uint"},{"name":"","type":"uint32[]"},
+	 {"name":"","type":"bytes10"},{"name":"","type":"bytes"}]},
+	{"type":"function","name":"g","inputs":[{"name":"","type":"uint[][]"},{"name":"","type":"string[]"}]}
+]`
+
+func words(hexWords string) []byte {
+	bs, err := hex.DecodeString(strings.Join(strings.Fields(hexWords), ""))
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+func TestPackSpecExamples(t *testing.T) {
+	abi, err := JSON([]byte(specABI))
+	assert.NoError(t, err)
+
+	data, err := abi.Pack("f", 0x123, []uint32{0x456, 0x789}, []byte("1234567890"), "0x48656c6c6f2c20776f726c6421")
+	assert.NoError(t, err)
+	assert.Equal(t, words(`8be65246
+		0000000000000000000000000000000000000000000000000000000000000123
+		0000000000000000000000000000000000000000000000000000000000000080
+		3132333435363738393000000000000000000000000000000000000000000000
+		00000000000000000000000000000000000000000000000000000000000000e0
+		0000000000000000000000000000000000000000000000000000000000000002
+		0000000000000000000000000000000000000000000000000000000000000456
+		0000000000000000000000000000000000000000000000000000000000000789
+		000000000000000000000000000000000000000000000000000000000000000d
+		48656c6c6f2c20776f726c642100000000000000000000000000000000000000`), data)
+
+	values, err := Unpack(abi.Function("f").Inputs, data[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{big.NewInt(0x123), []interface{}{uint64(0x456), uint64(0x789)},
+		[]byte("1234567890"), []byte("Hello, world!")}, values)
+
+	data, err = abi.Pack("g", `[[1,2],[3]]`, []string{"one", "two", "three"})
+	assert.NoError(t, err)
+	assert.Equal(t, words(`2289b18c
+		0000000000000000000000000000000000000000000000000000000000000040
+		0000000000000000000000000000000000000000000000000000000000000140
+		0000000000000000000000000000000000000000000000000000000000000002
+		0000000000000000000000000000000000000000000000000000000000000040
+		00000000000000000000000000000000000000000000000000000000000000a0
+		0000000000000000000000000000000000000000000000000000000000000002
+		0000000000000000000000000000000000000000000000000000000000000001
+		0000000000000000000000000000000000000000000000000000000000000002
+		0000000000000000000000000000000000000000000000000000000000000001
+		0000000000000000000000000000000000000000000000000000000000000003
+		0000000000000000000000000000000000000000000000000000000000000003
+		0000000000000000000000000000000000000000000000000000000000000060
+		00000000000000000000000000000000000000000000000000000000000000a0
+		00000000000000000000000000000000000000000000000000000000000000e0
+		0000000000000000000000000000000000000000000000000000000000000003
+		6f6e650000000000000000000000000000000000000000000000000000000000
+		0000000000000000000000000000000000000000000000000000000000000003
+		74776f0000000000000000000000000000000000000000000000000000000000
+		0000000000000000000000000000000000000000000000000000000000000005
+		7468726565000000000000000000000000000000000000000000000000000000`), data)
+
+	call, err := abi.DecodeCall(data, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []DecodedArg{
+		{Type: "uint256[][]", Value: "[[1,2],[3]]"},
+		{Type: "string[]", Value: "[one,two,three]"},
+	}, call.Args)
+}
+
+func TestPackTuples(t *testing.T) {
+	abi, err := JSON([]byte(`[{"type":"function","name":"h","inputs":[
+		{"name":"pairs","type":"tuple[2]","components":[{"name":"n","type":"int8"},{"name":"b","type":"bool"}]},
+		{"name":"named","type":"tuple[]","components":[{"name":"owner","type":"address"},{"name":"name","type":"string"}]},
+		{"name":"last","type":"uint16"}],
+		"outputs":[{"name":"","type":"(uint8,bytes)"}]}]`))
+	assert.NoError(t, err)
+	h := abi.Function("h")
+	assert.Equal(t, "h((int8,bool)[2],(address,string)[],uint16)", h.Signature())
+	assert.False(t, h.Inputs[0].Type.Dynamic())
+	assert.Equal(t, 4*wordLength, h.Inputs[0].Type.headLength())
+	assert.True(t, h.Inputs[1].Type.Dynamic())
+	assert.Equal(t, "owner", h.Inputs[1].Type.Elem.Components[0].Name)
+
+	owner := Address{1, 2, 3}
+	data, err := h.Pack([][]interface{}{{-1, true}, {"2", "false"}},
+		[]interface{}{[]interface{}{owner, "alice"}, []interface{}{owner[:], "bob"}}, uint16(7))
+	assert.NoError(t, err)
+	values, err := Unpack(h.Inputs, data[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		[]interface{}{[]interface{}{int64(-1), true}, []interface{}{int64(2), false}},
+		[]interface{}{[]interface{}{owner, "alice"}, []interface{}{owner, "bob"}},
+		uint64(7),
+	}, values)
+	args, err := DecodeArgs(h.Inputs, data[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, "[(-1,true),(2,false)]", args[0].Value)
+	assert.Equal(t, "[(0102030000000000000000000000000000000000,alice),(0102030000000000000000000000000000000000,bob)]",
+		args[1].Value)
+
+	ret, err := Pack(h.Outputs, []interface{}{255, []byte{0xca, 0xfe}})
+	assert.NoError(t, err)
+	values, err = h.Unpack(ret)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{[]interface{}{uint64(255), []byte{0xca, 0xfe}}}, values)
+
+	_, err = h.Pack([][]interface{}{{-1, true}}, []interface{}{}, 7)
+	assert.Error(t, err, "too few elements of a fixed array")
+	_, err = h.Pack([][]interface{}{{-129, true}, {0, false}}, []interface{}{}, 7)
+	assert.Error(t, err, "out of range of int8")
+	_, err = h.Pack([][]interface{}{{1, true}, {0, false}}, []interface{}{}, -7)
+	assert.Error(t, err, "negative uint")
+	_, err = h.Pack([][]interface{}{{1, true}, {0, false}}, []interface{}{})
+	assert.Error(t, err, "too few arguments")
+}
+
+func TestTopics(t *testing.T) {
+	abi, err := JSON([]byte(tokenABI))
+	assert.NoError(t, err)
+	event := abi.Events[0]
+	from := Address{0xf}
+
+	topics, err := event.TopicFilter(nil, "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, [][]Word256{{event.ID}, nil, {LeftPadWord256(sha3.Sha3([]byte("hello")))}}, topics)
+	topics, err = event.TopicFilter(from)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]Word256{{event.ID}, {LeftPadWord256(from[:])}}, topics)
+	_, err = event.TopicFilter(from, "hello", 1)
+	assert.Error(t, err)
+
+	topic, err := EncodeTopic(Type{Kind: IntKind, Size: 64}, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", fmt.Sprintf("%X", topic.Bytes()))
+}
+
+func TestParseType(t *testing.T) {
+	for _, name := range []string{"uint8", "int256", "bytes32", "address[]", "bool[2][]",
+		"(uint256,(string,bytes1)[])[3]", "()", "uint256[32768]"} {
+		parsed, err := ParseType(name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, name, parsed.String())
+	}
+	for _, name := range []string{"uint7", "bytes33", "int[0]", "uint[", "(uint256", "tuple", "fixed128x18",
+		// Too long or holding members taking up no room
+		"uint256[32769]", "bool[4096][4096]", "(uint256[32768],bool)", "()[]", "()[1]"} {
+		_, err := ParseType(name)
+		assert.Error(t, err, name)
+	}
+}
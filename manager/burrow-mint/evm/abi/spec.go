@@ -0,0 +1,214 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
+	. "github.com/hyperledger/burrow/word256"
+)
+
+// The interface of a contract as described by the ABI JSON solc outputs
+type ABI struct {
+	Constructor *Method
+	// In the order they are described
+	Functions []*Method
+	Events    []*Event
+}
+
+type Argument struct {
+	Name string
+	Type Type
+	// Whether an event argument is a topic rather than in the log's data
+	Indexed bool
+}
+
+type Method struct {
+	Name     string
+	Inputs   []Argument
+	Outputs  []Argument
+	Constant bool
+	Payable  bool
+	Selector FunctionSelector
+}
+
+type Event struct {
+	Name   string
+	Inputs []Argument
+	// Anonymous events do not take their ID as their first topic
+	Anonymous bool
+	ID        Word256
+}
+
+type jsonEntry struct {
+	Type            string         `json:"type"`
+	Name            string         `json:"name"`
+	Inputs          []jsonArgument `json:"inputs"`
+	Outputs         []jsonArgument `json:"outputs"`
+	Constant        bool           `json:"constant"`
+	Payable         bool           `json:"payable"`
+	StateMutability string         `json:"stateMutability"`
+	Anonymous       bool           `json:"anonymous"`
+}
+
+type jsonArgument struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Indexed    bool           `json:"indexed"`
+	Components []jsonArgument `json:"components"`
+}
+
+// Parses ABI JSON, a list of the constructor, functions and events of a
+// contract. The fallback function is skipped since it has no selector.
+func JSON(abiJSON []byte) (*ABI, error) {
+	var entries []jsonEntry
+	if err := json.Unmarshal(abiJSON, &entries); err != nil {
+		return nil, fmt.Errorf("Could not parse ABI JSON: %v", err)
+	}
+	abi := new(ABI)
+	for _, entry := range entries {
+		inputs, err := parseArguments(entry.Inputs)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid inputs of '%s': %v", entry.Name, err)
+		}
+		switch entry.Type {
+		case "function", "":
+			outputs, err := parseArguments(entry.Outputs)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid outputs of '%s': %v", entry.Name, err)
+			}
+			method := &Method{
+				Name:    entry.Name,
+				Inputs:  inputs,
+				Outputs: outputs,
+				Constant: entry.Constant || entry.StateMutability == "view" ||
+					entry.StateMutability == "pure",
+				Payable: entry.Payable || entry.StateMutability == "payable",
+			}
+			copy(method.Selector[:], sha3.Sha3([]byte(method.Signature())))
+			abi.Functions = append(abi.Functions, method)
+		case "constructor":
+			abi.Constructor = &Method{
+				Inputs:  inputs,
+				Payable: entry.Payable || entry.StateMutability == "payable",
+			}
+		case "event":
+			event := &Event{Name: entry.Name, Inputs: inputs, Anonymous: entry.Anonymous}
+			event.ID = LeftPadWord256(sha3.Sha3([]byte(event.Signature())))
+			abi.Events = append(abi.Events, event)
+		case "fallback":
+		default:
+			return nil, fmt.Errorf("Unknown ABI entry type '%s'", entry.Type)
+		}
+	}
+	return abi, nil
+}
+
+func parseArguments(jsonArgs []jsonArgument) ([]Argument, error) {
+	args := make([]Argument, len(jsonArgs))
+	for i, jsonArg := range jsonArgs {
+		var components []Argument
+		if jsonArg.Components != nil {
+			var err error
+			if components, err = parseArguments(jsonArg.Components); err != nil {
+				return nil, err
+			}
+		}
+		argType, err := parseType(jsonArg.Type, components)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = Argument{Name: jsonArg.Name, Type: argType, Indexed: jsonArg.Indexed}
+	}
+	return args, nil
+}
+
+func signature(name string, args []Argument) string {
+	typeNames := make([]string, len(args))
+	for i, arg := range args {
+		typeNames[i] = arg.Type.String()
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(typeNames, ","))
+}
+
+// The canonical signature of the function, whose hash gives its selector
+func (method *Method) Signature() string {
+	return signature(method.Name, method.Inputs)
+}
+
+// The canonical signature of the event, whose hash is its ID
+func (event *Event) Signature() string {
+	return signature(event.Name, event.Inputs)
+}
+
+// Returns the first function with the name, or nil if there is none
+func (abi *ABI) Function(name string) *Method {
+	for _, method := range abi.Functions {
+		if method.Name == name {
+			return method
+		}
+	}
+	return nil
+}
+
+// Encodes a call to the function with the name, with the values as its
+// arguments
+func (abi *ABI) Pack(name string, values ...interface{}) ([]byte, error) {
+	method := abi.Function(name)
+	if method == nil {
+		return nil, fmt.Errorf("ABI has no function '%s'", name)
+	}
+	return method.Pack(values...)
+}
+
+// Encodes a call to the function, which is its selector followed by its
+// arguments
+func (method *Method) Pack(values ...interface{}) ([]byte, error) {
+	args, err := Pack(method.Inputs, values...)
+	if err != nil {
+		return nil, fmt.Errorf("Could not encode arguments of '%s': %v", method.Name, err)
+	}
+	return append(method.Selector[:], args...), nil
+}
+
+// Decodes the values a call to the function returned
+func (method *Method) Unpack(ret []byte) ([]interface{}, error) {
+	values, err := Unpack(method.Outputs, ret)
+	if err != nil {
+		return nil, fmt.Errorf("Could not decode return values of '%s': %v", method.Name, err)
+	}
+	return values, nil
+}
+
+func (abi *ABI) FunctionBySelector(selector FunctionSelector) *Method {
+	for _, method := range abi.Functions {
+		if method.Selector == selector {
+			return method
+		}
+	}
+	return nil
+}
+
+func (abi *ABI) EventByID(id Word256) *Event {
+	for _, event := range abi.Events {
+		if !event.Anonymous && event.ID == id {
+			return event
+		}
+	}
+	return nil
+}
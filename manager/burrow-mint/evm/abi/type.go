@@ -0,0 +1,298 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Kind int
+
+const (
+	UintKind Kind = iota
+	IntKind
+	AddressKind
+	BoolKind
+	// bytes1 to bytes32
+	FixedBytesKind
+	BytesKind
+	StringKind
+	// T[n]
+	ArrayKind
+	// T[]
+	SliceKind
+	// (T1,T2,...), which ABI JSON calls tuple and describes by its components
+	TupleKind
+)
+
+// An ABI type, such as uint256, bytes or (address,string)[2]
+type Type struct {
+	Kind Kind
+	// The bits of an integer type, the length of a fixed bytes type or the
+	// number of elements of a fixed array type
+	Size int
+	// The type of the elements of an array type
+	Elem *Type
+	// The members of a tuple type
+	Components []Argument
+}
+
+// The most bytes a value of a type may take up in the head of the values it
+// is encoded with. Anyone may have a type parsed by registering an ABI, so
+// this bounds the members that decoding and encoding its values make room for.
+const maxHeadLength = 1 << 20
+
+// Parses the name of a type as written in Solidity or in signatures, where
+// tuples are written as their component types in parentheses. The aliases
+// uint and int are taken as uint256 and int256.
+func ParseType(name string) (Type, error) {
+	return parseType(name, nil)
+}
+
+// Parses the name of a type as written in ABI JSON, where tuples are written
+// as tuple and their components are given apart
+func parseType(name string, components []Argument) (Type, error) {
+	if strings.HasSuffix(name, "]") {
+		open := strings.LastIndex(name, "[")
+		if open < 0 {
+			return Type{}, fmt.Errorf("Invalid ABI type '%s'", name)
+		}
+		elem, err := parseType(name[:open], components)
+		if err != nil {
+			return Type{}, err
+		}
+		// Arrays of members taking up no room, which are of empty tuples,
+		// could have any number of them in no data
+		elemLength := elem.headLength()
+		if elemLength == 0 {
+			return Type{}, fmt.Errorf("Invalid ABI type '%s': arrays of empty tuples are not supported", name)
+		}
+		length := name[open+1 : len(name)-1]
+		if length == "" {
+			return Type{Kind: SliceKind, Elem: &elem}, nil
+		}
+		size, err := strconv.Atoi(length)
+		if err != nil || size < 1 {
+			return Type{}, fmt.Errorf("Invalid ABI type '%s'", name)
+		}
+		if size > maxHeadLength/elemLength {
+			return Type{}, fmt.Errorf("Invalid ABI type '%s': longer than %v bytes", name, maxHeadLength)
+		}
+		return Type{Kind: ArrayKind, Size: size, Elem: &elem}, nil
+	}
+	if name == "tuple" {
+		if components == nil {
+			return Type{}, fmt.Errorf("ABI type 'tuple' must have components")
+		}
+		return checkTupleLength(name, Type{Kind: TupleKind, Components: components})
+	}
+	if strings.HasPrefix(name, "(") && strings.HasSuffix(name, ")") {
+		componentNames, err := splitTypeList(name[1 : len(name)-1])
+		if err != nil {
+			return Type{}, fmt.Errorf("Invalid ABI type '%s': %v", name, err)
+		}
+		tuple := Type{Kind: TupleKind, Components: make([]Argument, len(componentNames))}
+		for i, componentName := range componentNames {
+			if tuple.Components[i].Type, err = ParseType(componentName); err != nil {
+				return Type{}, err
+			}
+		}
+		return checkTupleLength(name, tuple)
+	}
+	return parseElementaryType(name)
+}
+
+// Errors if the tuple's components, each of which is within maxHeadLength,
+// take up more than maxHeadLength together
+func checkTupleLength(name string, tuple Type) (Type, error) {
+	length := 0
+	for _, component := range tuple.Components {
+		length += component.Type.headLength()
+		if length > maxHeadLength {
+			return Type{}, fmt.Errorf("Invalid ABI type '%s': longer than %v bytes", name, maxHeadLength)
+		}
+	}
+	return tuple, nil
+}
+
+func parseElementaryType(name string) (Type, error) {
+	switch name {
+	case "address":
+		return Type{Kind: AddressKind, Size: AddressLength}, nil
+	case "bool":
+		return Type{Kind: BoolKind}, nil
+	case "bytes":
+		return Type{Kind: BytesKind}, nil
+	case "string":
+		return Type{Kind: StringKind}, nil
+	case "uint", "int":
+		name += "256"
+	}
+	for _, prefix := range []struct {
+		prefix   string
+		kind     Kind
+		min, max int
+		step     int
+	}{
+		{"uint", UintKind, 8, 256, 8},
+		{"int", IntKind, 8, 256, 8},
+		{"bytes", FixedBytesKind, 1, 32, 1},
+	} {
+		if !strings.HasPrefix(name, prefix.prefix) {
+			continue
+		}
+		size, err := strconv.Atoi(name[len(prefix.prefix):])
+		if err != nil || size < prefix.min || size > prefix.max || size%prefix.step != 0 {
+			return Type{}, fmt.Errorf("Invalid ABI type '%s'", name)
+		}
+		return Type{Kind: prefix.kind, Size: size}, nil
+	}
+	return Type{}, fmt.Errorf("Unsupported ABI type '%s'", name)
+}
+
+// Splits a comma separated list of type names, some of which may be tuples
+// with commas of their own
+func splitTypeList(list string) ([]string, error) {
+	if list == "" {
+		return []string{}, nil
+	}
+	var names []string
+	depth, start := 0, 0
+	for i, c := range list {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				names = append(names, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	return append(names, list[start:]), nil
+}
+
+// The canonical name of the type, as used in signatures
+func (t Type) String() string {
+	switch t.Kind {
+	case UintKind:
+		return fmt.Sprintf("uint%d", t.Size)
+	case IntKind:
+		return fmt.Sprintf("int%d", t.Size)
+	case AddressKind:
+		return string(AddressTypeName)
+	case BoolKind:
+		return string(BoolTypeName)
+	case FixedBytesKind:
+		return fmt.Sprintf("bytes%d", t.Size)
+	case BytesKind:
+		return "bytes"
+	case StringKind:
+		return string(StringTypeName)
+	case ArrayKind:
+		return fmt.Sprintf("%v[%d]", t.Elem, t.Size)
+	case SliceKind:
+		return fmt.Sprintf("%v[]", t.Elem)
+	case TupleKind:
+		names := make([]string, len(t.Components))
+		for i, component := range t.Components {
+			names[i] = component.Type.String()
+		}
+		return fmt.Sprintf("(%s)", strings.Join(names, ","))
+	}
+	return "unknown"
+}
+
+// Whether values of the type are encoded after the heads of the values they
+// are encoded with, rather than in place
+func (t Type) Dynamic() bool {
+	switch t.Kind {
+	case BytesKind, StringKind, SliceKind:
+		return true
+	case ArrayKind:
+		return t.Elem.Dynamic()
+	case TupleKind:
+		for _, component := range t.Components {
+			if component.Type.Dynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// The number of bytes a value of the type takes up in the head of the values
+// it is encoded with
+func (t Type) headLength() int {
+	if t.Dynamic() {
+		return wordLength
+	}
+	switch t.Kind {
+	case ArrayKind:
+		return t.Size * t.Elem.headLength()
+	case TupleKind:
+		return typesHeadLength(componentTypes(t.Components))
+	}
+	return wordLength
+}
+
+// Whether values of the type are encoded in a single word, which is what
+// they are as topics when indexed rather than hashed
+func (t Type) elementary() bool {
+	return t.Kind <= FixedBytesKind
+}
+
+func typesHeadLength(types []Type) int {
+	length := 0
+	for _, t := range types {
+		length += t.headLength()
+	}
+	return length
+}
+
+func componentTypes(args []Argument) []Type {
+	types := make([]Type, len(args))
+	for i, arg := range args {
+		types[i] = arg.Type
+	}
+	return types
+}
+
+// The types of the members of a value of an array or tuple type: the
+// components of a tuple, the elements of a fixed array, or n elements of a
+// dynamic array
+func (t Type) memberTypes(n int) []Type {
+	switch t.Kind {
+	case TupleKind:
+		return componentTypes(t.Components)
+	case ArrayKind:
+		n = t.Size
+	}
+	types := make([]Type, n)
+	for i := range types {
+		types[i] = *t.Elem
+	}
+	return types
+}
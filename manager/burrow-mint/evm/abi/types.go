@@ -16,10 +16,10 @@ package abi
 
 // Ethereum defines types and calling conventions for the ABI
 // (application binary interface) here: https://github.com/ethereum/wiki/wiki/Ethereum-Contract-ABI
-// We make a start of representing them here
+// Type and the codec built on it represent them, and contracts are described
+// by parsing their ABI JSON
 
-// We use TypeName rather than Type to reserve 'Type' for a possible future
-// ABI type the can hold an ABI-native type mapping
+// The name of a type as written in Solidity, which ParseType parses into a Type
 type TypeName string
 
 type Arg struct {
@@ -38,6 +38,7 @@ const (
 	IntTypeName     TypeName = "int"
 	Uint64TypeName  TypeName = "uint64"
 	Bytes32TypeName TypeName = "bytes32"
+	BytesTypeName   TypeName = "bytes"
 	StringTypeName  TypeName = "string"
 	BoolTypeName    TypeName = "bool"
 )
@@ -0,0 +1,154 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	. "github.com/hyperledger/burrow/manager/burrow-mint/evm/opcodes"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
+	"github.com/hyperledger/burrow/txs"
+	. "github.com/hyperledger/burrow/word256"
+	"github.com/stretchr/testify/assert"
+)
+
+// Conformance tests for opcodes introduced after Homestead. Each test case runs
+// code that leaves a single word on the stack which we compare with expected.
+type conformanceTestCase struct {
+	name     string
+	code     []byte
+	expected Word256
+}
+
+// Shift test vectors from EIP-145
+func TestShiftConformance(t *testing.T) {
+	var testCases []conformanceTestCase
+	for _, v := range []struct {
+		op                     OpCode
+		value, shift, expected string
+	}{
+		{SHL, "0000000000000000000000000000000000000000000000000000000000000001", "00", "0000000000000000000000000000000000000000000000000000000000000001"},
+		{SHL, "0000000000000000000000000000000000000000000000000000000000000001", "01", "0000000000000000000000000000000000000000000000000000000000000002"},
+		{SHL, "0000000000000000000000000000000000000000000000000000000000000001", "ff", "8000000000000000000000000000000000000000000000000000000000000000"},
+		{SHL, "0000000000000000000000000000000000000000000000000000000000000001", "0100", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SHL, "0000000000000000000000000000000000000000000000000000000000000001", "0101", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SHL, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "00", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SHL, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "01", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe"},
+		{SHL, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "ff", "8000000000000000000000000000000000000000000000000000000000000000"},
+		{SHL, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "0100", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SHL, "0000000000000000000000000000000000000000000000000000000000000000", "01", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SHL, "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "01", "fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe"},
+
+		{SHR, "0000000000000000000000000000000000000000000000000000000000000001", "00", "0000000000000000000000000000000000000000000000000000000000000001"},
+		{SHR, "0000000000000000000000000000000000000000000000000000000000000001", "01", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SHR, "8000000000000000000000000000000000000000000000000000000000000000", "01", "4000000000000000000000000000000000000000000000000000000000000000"},
+		{SHR, "8000000000000000000000000000000000000000000000000000000000000000", "ff", "0000000000000000000000000000000000000000000000000000000000000001"},
+		{SHR, "8000000000000000000000000000000000000000000000000000000000000000", "0100", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SHR, "8000000000000000000000000000000000000000000000000000000000000000", "0101", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SHR, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "00", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SHR, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "01", "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SHR, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "ff", "0000000000000000000000000000000000000000000000000000000000000001"},
+		{SHR, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "0100", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SHR, "0000000000000000000000000000000000000000000000000000000000000000", "01", "0000000000000000000000000000000000000000000000000000000000000000"},
+
+		{SAR, "0000000000000000000000000000000000000000000000000000000000000001", "00", "0000000000000000000000000000000000000000000000000000000000000001"},
+		{SAR, "0000000000000000000000000000000000000000000000000000000000000001", "01", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SAR, "8000000000000000000000000000000000000000000000000000000000000000", "01", "c000000000000000000000000000000000000000000000000000000000000000"},
+		{SAR, "8000000000000000000000000000000000000000000000000000000000000000", "ff", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SAR, "8000000000000000000000000000000000000000000000000000000000000000", "0100", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SAR, "8000000000000000000000000000000000000000000000000000000000000000", "0101", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SAR, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "00", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SAR, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "01", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SAR, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "ff", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SAR, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "0100", "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{SAR, "0000000000000000000000000000000000000000000000000000000000000000", "01", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SAR, "4000000000000000000000000000000000000000000000000000000000000000", "fe", "0000000000000000000000000000000000000000000000000000000000000001"},
+		{SAR, "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "f8", "000000000000000000000000000000000000000000000000000000000000007f"},
+		{SAR, "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "fe", "0000000000000000000000000000000000000000000000000000000000000001"},
+		{SAR, "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "ff", "0000000000000000000000000000000000000000000000000000000000000000"},
+		{SAR, "7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "0100", "0000000000000000000000000000000000000000000000000000000000000000"},
+	} {
+		testCases = append(testCases, conformanceTestCase{
+			name: fmt.Sprintf("%v(0x%s, 0x%s)", v.op, v.value, v.shift),
+			code: Bytecode(PUSH32, mustHexWord(t, v.value), PUSH32, mustHexWord(t, v.shift),
+				v.op),
+			expected: mustHexWord(t, v.expected),
+		})
+	}
+	runConformanceTests(t, newAppState(), testCases)
+}
+
+func TestExtCodeHashConformance(t *testing.T) {
+	appState := newAppState()
+	code := Bytecode(PUSH1, 1, PUSH1, 2, ADD)
+	_, address := makeAccountWithCode(appState, "hashme", code)
+	_, emptyAddress := makeAccountWithCode(appState, "empty", nil)
+	runConformanceTests(t, appState, []conformanceTestCase{
+		{"EXTCODEHASH of contract", Bytecode(PUSH20, address, EXTCODEHASH),
+			LeftPadWord256(sha3.Sha3(code))},
+		{"EXTCODEHASH of account without code", Bytecode(PUSH20, emptyAddress, EXTCODEHASH),
+			LeftPadWord256(sha3.Sha3(nil))},
+		{"EXTCODEHASH of non-existent account", Bytecode(PUSH20, makeBytes(20), EXTCODEHASH),
+			Zero256},
+	})
+}
+
+func TestCreate2Conformance(t *testing.T) {
+	appState := newAppState()
+	creator, _ := makeAccountWithCode(appState, "creator", nil)
+	salt := Int64ToWord256(0xcafebabe)
+	// Init code that deploys code consisting of the single byte 0x01
+	initCode := Bytecode(PUSH1, 1, PUSH1, 0, MSTORE8, PUSH1, 1, PUSH1, 0, RETURN)
+	address := LeftPadWord256(txs.NewContractAddress2(creator.Address.Postfix(20),
+		salt.Bytes(), initCode))
+	// CREATE2 with init code placed at the start of memory
+	create2 := Bytecode(PUSH32, RightPadWord256(initCode), PUSH1, 0, MSTORE,
+		PUSH32, salt, PUSH1, len(initCode), PUSH1, 0, PUSH1, 0, CREATE2)
+	runConformanceTests(t, appState, []conformanceTestCase{
+		{"CREATE2 returns derived address", create2, address},
+		{"CREATE2 deploys code", Bytecode(PUSH32, address, EXTCODEHASH),
+			LeftPadWord256(sha3.Sha3([]byte{1}))},
+		{"CREATE2 fails when address taken", create2, Zero256},
+	}, creator)
+}
+
+// Runs each test case in a fresh VM (as callee if given) and checks the word
+// it leaves on the stack
+func runConformanceTests(t *testing.T, appState AppState, testCases []conformanceTestCase,
+	callee ...*Account) {
+	for _, tc := range testCases {
+		ourVm := NewVM(appState, newParams(), Zero256, nil)
+		account := &Account{Address: LeftPadWord256([]byte("conformance"))}
+		if len(callee) > 0 {
+			account = callee[0]
+		}
+		var gas int64 = 100000
+		output, err := ourVm.Call(account, account, Bytecode(tc.code, return1()),
+			[]byte{}, 0, &gas)
+		if assert.NoError(t, err, tc.name) {
+			assert.Equal(t, tc.expected.Bytes(), output, tc.name)
+		}
+	}
+}
+
+func mustHexWord(t *testing.T, hexString string) Word256 {
+	bs, err := hex.DecodeString(hexString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return LeftPadWord256(bs)
+}
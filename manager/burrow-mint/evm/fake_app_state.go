@@ -18,14 +18,23 @@ import (
 	"fmt"
 
 	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
+	"github.com/hyperledger/burrow/txs"
 	. "github.com/hyperledger/burrow/word256"
 )
 
 type FakeAppState struct {
-	accounts map[string]*Account
+	accounts  map[string]*Account
+	storage   map[string]Word256
+	snapshots []fakeAppStateSnapshot
+}
+
+type fakeAppStateSnapshot struct {
+	accounts map[string]Account
 	storage  map[string]Word256
 }
 
+var _ SnapshotAppState = &FakeAppState{}
+
 func (fas *FakeAppState) GetAccount(addr Word256) *Account {
 	account := fas.accounts[addr.String()]
 	return account
@@ -60,6 +69,19 @@ func (fas *FakeAppState) CreateAccount(creator *Account) *Account {
 	}
 }
 
+func (fas *FakeAppState) CreateAccount2(creator *Account, salt Word256, initCode []byte) *Account {
+	creator.Nonce += 1
+	addr := LeftPadWord256(txs.NewContractAddress2(creator.Address.Postfix(20), salt.Bytes(), initCode))
+	if fas.accounts[addr.String()] != nil {
+		return nil
+	}
+	account := &Account{
+		Address: addr,
+	}
+	fas.accounts[addr.String()] = account
+	return account
+}
+
 func (fas *FakeAppState) GetStorage(addr Word256, key Word256) Word256 {
 	_, ok := fas.accounts[addr.String()]
 	if !ok {
@@ -83,6 +105,38 @@ func (fas *FakeAppState) SetStorage(addr Word256, key Word256, value Word256) {
 	fas.storage[addr.String()+key.String()] = value
 }
 
+func (fas *FakeAppState) Snapshot() int {
+	snapshot := fakeAppStateSnapshot{
+		accounts: make(map[string]Account, len(fas.accounts)),
+		storage:  make(map[string]Word256, len(fas.storage)),
+	}
+	for k, acc := range fas.accounts {
+		snapshot.accounts[k] = *acc
+	}
+	for k, v := range fas.storage {
+		snapshot.storage[k] = v
+	}
+	fas.snapshots = append(fas.snapshots, snapshot)
+	return len(fas.snapshots) - 1
+}
+
+func (fas *FakeAppState) RevertToSnapshot(snapshot int) {
+	snap := fas.snapshots[snapshot]
+	accounts := make(map[string]*Account, len(snap.accounts))
+	for k, accCopy := range snap.accounts {
+		// Restore in place since the VM holds on to account pointers
+		acc := fas.accounts[k]
+		if acc == nil {
+			acc = new(Account)
+		}
+		*acc = accCopy
+		accounts[k] = acc
+	}
+	fas.accounts = accounts
+	fas.storage = snap.storage
+	fas.snapshots = fas.snapshots[:snapshot]
+}
+
 // Creates a 20 byte address and bumps the nonce.
 func createAddress(creator *Account) Word256 {
 	nonce := creator.Nonce
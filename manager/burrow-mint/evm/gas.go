@@ -14,6 +14,17 @@
 
 package vm
 
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	. "github.com/hyperledger/burrow/manager/burrow-mint/evm/opcodes"
+	. "github.com/hyperledger/burrow/word256"
+)
+
+// Flat costs of the legacy gas schedule
+
 const (
 	GasSha3          int64 = 1
 	GasGetAccount    int64 = 1
@@ -30,3 +41,289 @@ const (
 	GasIdentityWord  int64 = 1
 	GasIdentityBase  int64 = 1
 )
+
+// Names by which a gas schedule can be chosen in the genesis params
+const (
+	ByzantiumGasScheduleName = "byzantium"
+	LegacyGasScheduleName    = "legacy"
+)
+
+// A GasSchedule prices the work done by the VM. Every op costs BaseOp plus its
+// entry in Ops, to which the op-specific dynamic costs below are added.
+type GasSchedule struct {
+	Name string
+
+	BaseOp int64
+	// Charged for each push to and pop from the data stack
+	StackOp int64
+	// Static cost of each op
+	Ops [256]int64
+
+	// Charged per 32-byte word hashed by SHA3 and CREATE2, on top of Sha3
+	Sha3     int64
+	Sha3Word int64
+	// Charged per 32-byte word copied by the *COPY ops
+	CopyWord int64
+	// Memory costs MemoryWord per word plus words^2/MemoryQuadCoeffDiv (if it
+	// is non-zero), charged on the high-water mark of a frame's memory
+	MemoryWord         int64
+	MemoryQuadCoeffDiv int64
+	// Charged for looking up an account
+	GetAccount int64
+	ExpByte    int64
+
+	// Storing a non-zero value into an empty slot costs SStoreSet, any other
+	// store costs SStoreReset. Clearing a slot earns SStoreClearRefund.
+	SStoreSet         int64
+	SStoreReset       int64
+	SStoreClearRefund int64
+	// The refund of a transaction is capped at this fraction of its used gas
+	MaxRefundQuotient int64
+
+	LogTopic    int64
+	LogDataByte int64
+
+	CallValueTransfer int64
+	CallNewAccount    int64
+	// Gas given for free to the callee of a CALL that transfers value
+	CallStipend int64
+	// Charged per byte of code deployed by CREATE
+	CreateDataByte int64
+
+	// Native contracts
+	EcRecover     int64
+	Sha256Base    int64
+	Sha256Word    int64
+	Ripemd160Base int64
+	Ripemd160Word int64
+	IdentityBase  int64
+	IdentityWord  int64
+	// MODEXP costs the complexity of multiplying numbers as long as the base
+	// or modulus, times the bit length of the exponent, over ModExpQuadCoeffDiv
+	ModExpQuadCoeffDiv int64
+	Bn256Add           int64
+	Bn256ScalarMul     int64
+	// A pairing check costs Bn256PairingBase plus Bn256PairingPoint per pair
+	// of points
+	Bn256PairingBase  int64
+	Bn256PairingPoint int64
+	SNativeCall       int64
+	// Whether a call to a native contract that cannot pay for itself uses up
+	// all the gas it was given, rather than handing it back
+	NativeOutOfGasBurnsGas bool
+}
+
+// A schedule priced along the lines of Ethereum as of Byzantium
+func DefaultGasSchedule() *GasSchedule {
+	gs := &GasSchedule{
+		Name: ByzantiumGasScheduleName,
+
+		Sha3Word:           6,
+		CopyWord:           3,
+		MemoryWord:         3,
+		MemoryQuadCoeffDiv: 512,
+		ExpByte:            50,
+
+		SStoreSet:         20000,
+		SStoreReset:       5000,
+		SStoreClearRefund: 15000,
+		MaxRefundQuotient: 2,
+
+		LogTopic:    375,
+		LogDataByte: 8,
+
+		CallValueTransfer: 9000,
+		CallNewAccount:    25000,
+		CallStipend:       2300,
+		CreateDataByte:    200,
+
+		EcRecover:     3000,
+		Sha256Base:    60,
+		Sha256Word:    12,
+		Ripemd160Base: 600,
+		Ripemd160Word: 120,
+		IdentityBase:  15,
+		IdentityWord:  3,
+		// Priced as in Byzantium, though Istanbul repriced bn256 cheaper
+		ModExpQuadCoeffDiv: 20,
+		Bn256Add:           500,
+		Bn256ScalarMul:     40000,
+		Bn256PairingBase:   100000,
+		Bn256PairingPoint:  80000,
+		SNativeCall:        700,
+
+		NativeOutOfGasBurnsGas: true,
+	}
+	setOpCosts(gs, 2, ADDRESS, ORIGIN, CALLER, CALLVALUE, CALLDATASIZE, CODESIZE,
+		GASPRICE_DEPRECATED, COINBASE, TIMESTAMP, BLOCKHEIGHT, DIFFICULTY_DEPRECATED,
+		GASLIMIT, RETURNDATASIZE, POP, PC, MSIZE, GAS)
+	setOpCosts(gs, 3, ADD, SUB, NOT, LT, GT, SLT, SGT, EQ, ISZERO, AND, OR, XOR,
+		BYTE, SHL, SHR, SAR, CALLDATALOAD, MLOAD, MSTORE, MSTORE8, CALLDATACOPY,
+		CODECOPY, RETURNDATACOPY)
+	setOpCosts(gs, 5, MUL, DIV, SDIV, MOD, SMOD, SIGNEXTEND)
+	setOpCosts(gs, 8, ADDMOD, MULMOD, JUMP)
+	setOpCosts(gs, 10, JUMPI, EXP)
+	setOpCosts(gs, 1, JUMPDEST)
+	setOpCosts(gs, 20, BLOCKHASH)
+	setOpCosts(gs, 30, SHA3)
+	setOpCosts(gs, 200, SLOAD)
+	setOpCosts(gs, 400, BALANCE, EXTCODEHASH)
+	setOpCosts(gs, 700, EXTCODESIZE, EXTCODECOPY, CALL, CALLCODE, DELEGATECALL,
+		STATICCALL)
+	setOpCosts(gs, 375, LOG0, LOG1, LOG2, LOG3, LOG4)
+	setOpCosts(gs, 5000, SUICIDE)
+	setOpCosts(gs, 32000, CREATE, CREATE2)
+	for op := PUSH1; op <= PUSH32; op++ {
+		gs.Ops[op] = 3
+	}
+	for op := DUP1; op <= DUP16; op++ {
+		gs.Ops[op] = 3
+	}
+	for op := SWAP1; op <= SWAP16; op++ {
+		gs.Ops[op] = 3
+	}
+	return gs
+}
+
+// The flat schedule burrow has always used, which charges for stack use and
+// a handful of ops but very little else
+func LegacyGasSchedule() *GasSchedule {
+	// The native contracts added since cost as much as under the default
+	// schedule, since they can do a great deal of work on little input
+	byzantium := DefaultGasSchedule()
+	return &GasSchedule{
+		Name:        LegacyGasScheduleName,
+		BaseOp:      GasBaseOp,
+		StackOp:     GasStackOp,
+		Sha3:        GasSha3,
+		GetAccount:  GasGetAccount,
+		SStoreSet:   GasStorageUpdate,
+		SStoreReset: GasStorageUpdate,
+
+		EcRecover:     GasEcRecover,
+		Sha256Base:    GasSha256Base,
+		Sha256Word:    GasSha256Word,
+		Ripemd160Base: GasRipemd160Base,
+		Ripemd160Word: GasRipemd160Word,
+		IdentityBase:  GasIdentityBase,
+		IdentityWord:  GasIdentityWord,
+
+		ModExpQuadCoeffDiv: byzantium.ModExpQuadCoeffDiv,
+		Bn256Add:           byzantium.Bn256Add,
+		Bn256ScalarMul:     byzantium.Bn256ScalarMul,
+		Bn256PairingBase:   byzantium.Bn256PairingBase,
+		Bn256PairingPoint:  byzantium.Bn256PairingPoint,
+	}
+}
+
+// Returns the named gas schedule. The empty name gives the legacy schedule
+// since chains made before there was a choice have no name in their genesis
+// params, so a chain must opt in to the default schedule by name.
+func GasScheduleByName(name string) (*GasSchedule, error) {
+	switch name {
+	case ByzantiumGasScheduleName:
+		return DefaultGasSchedule(), nil
+	case "", LegacyGasScheduleName:
+		return LegacyGasSchedule(), nil
+	}
+	return nil, fmt.Errorf("Unknown gas schedule '%s'", name)
+}
+
+func setOpCosts(gs *GasSchedule, cost int64, ops ...OpCode) {
+	for _, op := range ops {
+		gs.Ops[op] = cost
+	}
+}
+
+// Total cost of a memory of the given number of words
+func (gs *GasSchedule) MemoryGas(words int64) int64 {
+	gas := words * gs.MemoryWord
+	if gs.MemoryQuadCoeffDiv > 0 {
+		gas += words * words / gs.MemoryQuadCoeffDiv
+	}
+	return gas
+}
+
+// Cost of calling the native contract at address with input
+func (gs *GasSchedule) NativeContractGas(address Word256, input []byte) int64 {
+	words := wordsFor(int64(len(input)))
+	switch address {
+	case Int64ToWord256(1):
+		return gs.EcRecover
+	case Int64ToWord256(2):
+		return gs.Sha256Base + words*gs.Sha256Word
+	case Int64ToWord256(3):
+		return gs.Ripemd160Base + words*gs.Ripemd160Word
+	case Int64ToWord256(4):
+		return gs.IdentityBase + words*gs.IdentityWord
+	case Int64ToWord256(5):
+		return gs.modExpGas(input)
+	case Int64ToWord256(6):
+		return gs.Bn256Add
+	case Int64ToWord256(7):
+		return gs.Bn256ScalarMul
+	case Int64ToWord256(8):
+		return gs.Bn256PairingBase + int64(len(input)/bn256PairLength)*gs.Bn256PairingPoint
+	}
+	return gs.SNativeCall
+}
+
+// Cost of MODEXP as EIP-198 prices it, or math.MaxInt64 if that is more
+func (gs *GasSchedule) modExpGas(input []byte) int64 {
+	baseLen, expLen, modLen := modExpLengths(input)
+	// The bit length of the exponent less one, taken from at most its first
+	// word, plus 8 bits for every byte after that
+	expHeadLen := expLen
+	if expHeadLen.Cmp(big.NewInt(32)) > 0 {
+		expHeadLen = big.NewInt(32)
+	}
+	expHead := new(big.Int).SetBytes(rightPaddedSlice(input, new(big.Int).Add(big.NewInt(96), baseLen),
+		expHeadLen))
+	adjExpLen := new(big.Int)
+	if expLen.Cmp(big.NewInt(32)) > 0 {
+		adjExpLen.Sub(expLen, big.NewInt(32))
+		adjExpLen.Mul(adjExpLen, big.NewInt(8))
+	}
+	if bitLen := expHead.BitLen(); bitLen > 0 {
+		adjExpLen.Add(adjExpLen, big.NewInt(int64(bitLen-1)))
+	}
+	if adjExpLen.Sign() == 0 {
+		adjExpLen.SetInt64(1)
+	}
+
+	length := baseLen
+	if modLen.Cmp(length) > 0 {
+		length = modLen
+	}
+	gas := new(big.Int).Mul(multComplexity(length), adjExpLen)
+	if gs.ModExpQuadCoeffDiv > 0 {
+		gas.Div(gas, big.NewInt(gs.ModExpQuadCoeffDiv))
+	}
+	if gas.BitLen() > 63 {
+		return math.MaxInt64
+	}
+	return gas.Int64()
+}
+
+// The cost EIP-198 gives to multiplying numbers of length bytes
+func multComplexity(length *big.Int) *big.Int {
+	x := new(big.Int).Mul(length, length)
+	switch {
+	case length.Cmp(big.NewInt(64)) <= 0:
+		return x
+	case length.Cmp(big.NewInt(1024)) <= 0:
+		// x^2/4 + 96x - 3072
+		x.Div(x, big.NewInt(4))
+		x.Add(x, new(big.Int).Mul(length, big.NewInt(96)))
+		return x.Sub(x, big.NewInt(3072))
+	}
+	// x^2/16 + 480x - 199680
+	x.Div(x, big.NewInt(16))
+	x.Add(x, new(big.Int).Mul(length, big.NewInt(480)))
+	return x.Sub(x, big.NewInt(199680))
+}
+
+// Number of 32-byte words needed to hold size bytes
+func wordsFor(size int64) int64 {
+	return (size + 31) / 32
+}
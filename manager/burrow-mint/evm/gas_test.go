@@ -0,0 +1,130 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"testing"
+
+	. "github.com/hyperledger/burrow/manager/burrow-mint/evm/opcodes"
+	. "github.com/hyperledger/burrow/word256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGasScheduleByName(t *testing.T) {
+	gs, err := GasScheduleByName(ByzantiumGasScheduleName)
+	assert.NoError(t, err)
+	assert.Equal(t, ByzantiumGasScheduleName, gs.Name)
+
+	gs, err = GasScheduleByName(LegacyGasScheduleName)
+	assert.NoError(t, err)
+	assert.Equal(t, LegacyGasScheduleName, gs.Name)
+	// Chains made before there was a choice keep the legacy schedule
+	gs, err = GasScheduleByName("")
+	assert.NoError(t, err)
+	assert.Equal(t, LegacyGasScheduleName, gs.Name)
+
+	_, err = GasScheduleByName("homestead")
+	assert.Error(t, err)
+}
+
+func TestGasUsed(t *testing.T) {
+	add := Bytecode(PUSH1, 1, PUSH1, 2, ADD, STOP)
+	// 3 gas for each op
+	assert.Equal(t, int64(9), gasUsed(t, DefaultGasSchedule(), add))
+	// 1 gas for each push and pop
+	assert.Equal(t, int64(5), gasUsed(t, LegacyGasSchedule(), add))
+
+	// Storing into an empty slot then clearing it again earns a refund of half
+	// the gas used
+	setAndClear := Bytecode(PUSH1, 1, PUSH1, 0, SSTORE, PUSH1, 0, PUSH1, 0, SSTORE,
+		STOP)
+	assert.Equal(t, int64((4*3+20000+5000)/2), gasUsed(t, DefaultGasSchedule(),
+		setAndClear))
+
+	// Storing a word at 64 expands memory to 3 words, after which reading below
+	// that is free of memory costs
+	memory := Bytecode(PUSH1, 1, PUSH1, 64, MSTORE, PUSH1, 0, MLOAD, STOP)
+	assert.Equal(t, int64(5*3+3*3), gasUsed(t, DefaultGasSchedule(), memory))
+}
+
+func TestNativeContractGas(t *testing.T) {
+	gs := DefaultGasSchedule()
+	assert.Equal(t, int64(60+2*12), gs.NativeContractGas(Int64ToWord256(2), makeBytes(33)))
+	assert.Equal(t, int64(600+120), gs.NativeContractGas(Int64ToWord256(3), makeBytes(32)))
+	assert.Equal(t, int64(15), gs.NativeContractGas(Int64ToWord256(4), nil))
+	assert.Equal(t, gs.SNativeCall, gs.NativeContractGas(LeftPadWord256([]byte("snative")), nil))
+	assert.Equal(t, int64(500), gs.NativeContractGas(Int64ToWord256(6), nil))
+	assert.Equal(t, int64(40000), gs.NativeContractGas(Int64ToWord256(7), nil))
+	assert.Equal(t, int64(100000), gs.NativeContractGas(Int64ToWord256(8), nil))
+
+	// Legacy costs are unchanged
+	gs = LegacyGasSchedule()
+	assert.Equal(t, GasSha256Base+2*GasSha256Word, gs.NativeContractGas(Int64ToWord256(2),
+		makeBytes(33)))
+	// But the later native contracts are not cheap
+	assert.Equal(t, int64(100000+80000), gs.NativeContractGas(Int64ToWord256(8), makeBytes(192)))
+	assert.Equal(t, int64(0), gs.NativeContractGas(LeftPadWord256([]byte("snative")), nil))
+}
+
+func TestNativeContractOutOfGas(t *testing.T) {
+	// Calls SHA256 with 10 gas, too little for it to run
+	code := Bytecode(PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 2,
+		PUSH1, 10, CALL, STOP)
+	gs := DefaultGasSchedule()
+	burnt := gasUsed(t, gs, code)
+	gs.NativeOutOfGasBurnsGas = false
+	assert.Equal(t, int64(10), burnt-gasUsed(t, gs, code))
+
+	// Legacy chains always had the gas handed back
+	assert.False(t, LegacyGasSchedule().NativeOutOfGasBurnsGas)
+}
+
+func TestNegativeGasCosts(t *testing.T) {
+	minus1000000 := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xf0, 0xbd, 0xc0}
+	for name, code := range map[string][]byte{
+		// Calls SHA256 with a gas limit of -1000000, so runs out of gas
+		"call": Bytecode(PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 2,
+			PUSH8, minus1000000, CALL, STOP),
+		"sha3":         Bytecode(PUSH8, minus1000000, PUSH1, 0, SHA3, STOP),
+		"calldatacopy": Bytecode(PUSH8, minus1000000, PUSH1, 0, PUSH1, 0, CALLDATACOPY, STOP),
+		"log":          Bytecode(PUSH8, minus1000000, PUSH1, 0, LOG0, STOP),
+		// The cost of the words of the largest size overflows
+		"sha3 overflow": Bytecode(PUSH8, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			PUSH1, 0, SHA3, STOP),
+	} {
+		appState := newAppState()
+		params := newParams()
+		params.GasSchedule = DefaultGasSchedule()
+		ourVm := NewVM(appState, params, Zero256, nil)
+		account, _ := makeAccountWithCode(appState, "gas", code)
+		var gas int64 = 100000
+		_, err := ourVm.Call(account, account, code, []byte{}, 0, &gas)
+		assert.Error(t, err, name)
+		assert.True(t, gas <= 100000, "%s gave gas: %v", name, gas)
+	}
+}
+
+// Runs code under the gas schedule and returns the gas it used
+func gasUsed(t *testing.T, gasSchedule *GasSchedule, code []byte) int64 {
+	params := newParams()
+	params.GasSchedule = gasSchedule
+	appState := newAppState()
+	ourVm := NewVM(appState, params, Zero256, nil)
+	account, _ := makeAccountWithCode(appState, "gas", code)
+	var gas, startGas int64 = 100000, 100000
+	_, err := ourVm.Call(account, account, code, []byte{}, 0, &gas)
+	assert.NoError(t, err)
+	return startGas - gas
+}
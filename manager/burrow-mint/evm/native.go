@@ -16,12 +16,27 @@ package vm
 
 import (
 	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
 
 	. "github.com/hyperledger/burrow/word256"
 
+	bn256 "github.com/ethereum/go-ethereum/crypto/bn256/cloudflare"
 	"golang.org/x/crypto/ripemd160"
 )
 
+// The input of a pairing check is a G1 point of two 32-byte coordinates
+// followed by a G2 point of two coordinates of two 32-byte parts each, for
+// each pair of points
+const bn256PairLength = 64 + 128
+
+var (
+	ErrModExpTooLong        = errors.New("MODEXP operands are too long")
+	ErrBn256PairingInputLen = fmt.Errorf("Pairing check input is not a whole number of %v-byte pairs",
+		bn256PairLength)
+)
+
 var registeredNativeContracts = make(map[Word256]NativeContract)
 
 func RegisteredNativeContract(addr Word256) bool {
@@ -48,21 +63,20 @@ func registerNativeContracts() {
 	registeredNativeContracts[Int64ToWord256(2)] = sha256Func
 	registeredNativeContracts[Int64ToWord256(3)] = ripemd160Func
 	registeredNativeContracts[Int64ToWord256(4)] = identityFunc
+	RegisterNativeContract(Int64ToWord256(5), modExpFunc)
+	RegisterNativeContract(Int64ToWord256(6), bn256AddFunc)
+	RegisterNativeContract(Int64ToWord256(7), bn256ScalarMulFunc)
+	RegisterNativeContract(Int64ToWord256(8), bn256PairingFunc)
 }
 
 //-----------------------------------------------------------------------------
 
+// Native contracts are charged by the VM according to its GasSchedule before
+// they are run, though they may use more gas themselves
 type NativeContract func(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error)
 
 /* Removed due to C dependency
 func ecrecoverFunc(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error) {
-	// Deduct gas
-	gasRequired := GasEcRecover
-	if *gas < gasRequired {
-		return nil, ErrInsufficientGas
-	} else {
-		*gas -= gasRequired
-	}
 	// Recover
 	hash := input[:32]
 	v := byte(input[32] - 27) // ignore input[33:64], v is small.
@@ -78,13 +92,6 @@ func ecrecoverFunc(appState AppState, caller *Account, input []byte, gas *int64)
 */
 
 func sha256Func(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error) {
-	// Deduct gas
-	gasRequired := int64((len(input)+31)/32)*GasSha256Word + GasSha256Base
-	if *gas < gasRequired {
-		return nil, ErrInsufficientGas
-	} else {
-		*gas -= gasRequired
-	}
 	// Hash
 	hasher := sha256.New()
 	// CONTRACT: this does not err
@@ -93,13 +100,6 @@ func sha256Func(appState AppState, caller *Account, input []byte, gas *int64) (o
 }
 
 func ripemd160Func(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error) {
-	// Deduct gas
-	gasRequired := int64((len(input)+31)/32)*GasRipemd160Word + GasRipemd160Base
-	if *gas < gasRequired {
-		return nil, ErrInsufficientGas
-	} else {
-		*gas -= gasRequired
-	}
 	// Hash
 	hasher := ripemd160.New()
 	// CONTRACT: this does not err
@@ -108,13 +108,103 @@ func ripemd160Func(appState AppState, caller *Account, input []byte, gas *int64)
 }
 
 func identityFunc(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error) {
-	// Deduct gas
-	gasRequired := int64((len(input)+31)/32)*GasIdentityWord + GasIdentityBase
-	if *gas < gasRequired {
-		return nil, ErrInsufficientGas
-	} else {
-		*gas -= gasRequired
-	}
 	// Return identity
 	return input, nil
 }
+
+// Computes base**exp % mod as EIP-198 specifies, where the input is the
+// lengths of the three numbers, each in a word, and then the numbers. Input
+// missing from the end is taken to be zeros.
+func modExpFunc(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error) {
+	baseLen, expLen, modLen := modExpLengths(input)
+	// The output is as long as the modulus, and with no modulus the other
+	// lengths are not paid for
+	if modLen.Sign() == 0 {
+		return []byte{}, nil
+	}
+	// The gas charged keeps the lengths far below this
+	maxLen := big.NewInt(1 << 30)
+	if baseLen.Cmp(maxLen) > 0 || expLen.Cmp(maxLen) > 0 || modLen.Cmp(maxLen) > 0 {
+		return nil, ErrModExpTooLong
+	}
+	offset := big.NewInt(96)
+	base := new(big.Int).SetBytes(rightPaddedSlice(input, offset, baseLen))
+	offset.Add(offset, baseLen)
+	exp := new(big.Int).SetBytes(rightPaddedSlice(input, offset, expLen))
+	offset.Add(offset, expLen)
+	mod := new(big.Int).SetBytes(rightPaddedSlice(input, offset, modLen))
+	if mod.Sign() == 0 {
+		return make([]byte, modLen.Int64()), nil
+	}
+	return LeftPadBytes(new(big.Int).Exp(base, exp, mod).Bytes(), int(modLen.Int64())), nil
+}
+
+// Adds two points on the alt_bn128 curve as EIP-196 specifies
+func bn256AddFunc(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error) {
+	input = RightPadBytes(input, 128)
+	x, y := new(bn256.G1), new(bn256.G1)
+	if _, err = x.Unmarshal(input[:64]); err != nil {
+		return nil, err
+	}
+	if _, err = y.Unmarshal(input[64:128]); err != nil {
+		return nil, err
+	}
+	return new(bn256.G1).Add(x, y).Marshal(), nil
+}
+
+// Multiplies a point on the alt_bn128 curve by a scalar as EIP-196 specifies
+func bn256ScalarMulFunc(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error) {
+	input = RightPadBytes(input, 96)
+	point := new(bn256.G1)
+	if _, err = point.Unmarshal(input[:64]); err != nil {
+		return nil, err
+	}
+	scalar := new(big.Int).SetBytes(input[64:96])
+	return new(bn256.G1).ScalarMult(point, scalar).Marshal(), nil
+}
+
+// Checks that the product of the pairings of the pairs of points given is one
+// as EIP-197 specifies, returning a word holding 1 if so and 0 if not
+func bn256PairingFunc(appState AppState, caller *Account, input []byte, gas *int64) (output []byte, err error) {
+	if len(input)%bn256PairLength != 0 {
+		return nil, ErrBn256PairingInputLen
+	}
+	var g1s []*bn256.G1
+	var g2s []*bn256.G2
+	for i := 0; i < len(input); i += bn256PairLength {
+		g1 := new(bn256.G1)
+		if _, err = g1.Unmarshal(input[i : i+64]); err != nil {
+			return nil, err
+		}
+		g2 := new(bn256.G2)
+		if _, err = g2.Unmarshal(input[i+64 : i+bn256PairLength]); err != nil {
+			return nil, err
+		}
+		g1s = append(g1s, g1)
+		g2s = append(g2s, g2)
+	}
+	if bn256.PairingCheck(g1s, g2s) {
+		return Int64ToWord256(1).Bytes(), nil
+	}
+	return Zero256.Bytes(), nil
+}
+
+// The lengths of the base, exponent and modulus at the start of the input of
+// MODEXP
+func modExpLengths(input []byte) (baseLen, expLen, modLen *big.Int) {
+	baseLen = new(big.Int).SetBytes(rightPaddedSlice(input, big.NewInt(0), big.NewInt(32)))
+	expLen = new(big.Int).SetBytes(rightPaddedSlice(input, big.NewInt(32), big.NewInt(32)))
+	modLen = new(big.Int).SetBytes(rightPaddedSlice(input, big.NewInt(64), big.NewInt(32)))
+	return
+}
+
+// The length bytes of data from offset, padded with zeros past its end. The
+// offset may lie far beyond the end but length must be small enough to
+// allocate.
+func rightPaddedSlice(data []byte, offset, length *big.Int) []byte {
+	padded := make([]byte, length.Int64())
+	if offset.Cmp(big.NewInt(int64(len(data)))) < 0 {
+		copy(padded, data[offset.Int64():])
+	}
+	return padded
+}
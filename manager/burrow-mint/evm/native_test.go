@@ -0,0 +1,184 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+
+	. "github.com/hyperledger/burrow/manager/burrow-mint/evm/opcodes"
+	. "github.com/hyperledger/burrow/word256"
+	"github.com/stretchr/testify/assert"
+)
+
+// The generators of G1 and G2 of alt_bn128 and the negation of the generator
+// of G1
+const (
+	bn256G1 = `0000000000000000000000000000000000000000000000000000000000000001
+		0000000000000000000000000000000000000000000000000000000000000002`
+	bn256NegG1 = `0000000000000000000000000000000000000000000000000000000000000001
+		30644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd45`
+	bn256G2 = `198e9393920d483a7260bfb731fb5d25f1aa493335a9e71297e485b7aef312c2
+		1800deef121f1e76426a00665e5c4479674322d4f75edadd46debd5cd992f6ed
+		090689d0585ff075ec9e99ad690c3395bc4b313370b38ef355acdadcd122975b
+		12c85ea5db8c6deb4aab71808dcb408fe3d1e7690c43d37b4ce6cc0166fa7daa`
+)
+
+func hexInput(hexWords string) []byte {
+	bs, err := hex.DecodeString(strings.Join(strings.Fields(hexWords), ""))
+	if err != nil {
+		panic(err)
+	}
+	return bs
+}
+
+// From the examples of EIP-198
+func TestModExp(t *testing.T) {
+	// Fermat's little theorem
+	input := hexInput(`0000000000000000000000000000000000000000000000000000000000000001
+		0000000000000000000000000000000000000000000000000000000000000020
+		0000000000000000000000000000000000000000000000000000000000000020
+		03
+		fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2e
+		fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f`)
+	output, err := modExpFunc(nil, nil, input, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(1).Bytes(), output)
+	assert.Equal(t, int64(13056), DefaultGasSchedule().NativeContractGas(Int64ToWord256(5), input))
+
+	// A base of no length is zero, as is input missing from the end
+	input = hexInput(`0000000000000000000000000000000000000000000000000000000000000000
+		0000000000000000000000000000000000000000000000000000000000000020
+		0000000000000000000000000000000000000000000000000000000000000020
+		fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2e`)
+	output, err = modExpFunc(nil, nil, input, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Zero256.Bytes(), output)
+
+	// Lengths too great to pay for
+	input = hexInput(`0000000000000000000000000000000000000000000000000000000000000000
+		0000000000000000000000000000000000000000000000000000000000000020
+		ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff
+		fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffffe
+		fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffffd`)
+	assert.Equal(t, int64(math.MaxInt64), DefaultGasSchedule().NativeContractGas(Int64ToWord256(5), input))
+
+	// No modulus means no output, however long the exponent
+	output, err = modExpFunc(nil, nil, hexInput(`00
+		ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{}, output)
+}
+
+// From the chfast and cdetrio vectors of the Ethereum tests
+func TestBn256Add(t *testing.T) {
+	output, err := bn256AddFunc(nil, nil, hexInput(`
+		18b18acfb4c2c30276db5411368e7185b311dd124691610c5d3b74034e093dc9
+		063c909c4720840cb5134cb9f59fa749755796819658d32efc0d288198f37266
+		07c2b7f58a84bd6145f00c9c2bc0bb1a187f20ff2c92963a88019e7c6a014eed
+		06614e20c147e940f2d70da3f74c9a17df361706a4485c742bd6788478fa17d7`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, hexInput(`
+		2243525c5efd4b9c3d3c45ac0ca3fe4dd85e830a4ce6b65fa1eeaee202839703
+		301d1d33be6da8e509df21cc35964723180eed7532537db9ae5e7d48f195c915`), output)
+
+	output, err = bn256AddFunc(nil, nil, hexInput(bn256G1+bn256G1), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, hexInput(`
+		030644e72e131a029b85045b68181585d97816a916871ca8d3c208c16d87cfd3
+		15ed738c0e0a7c92e7845f96b2ae9c0a68a6a449e3538fc7ff3ebf7a5a18a2c4`), output)
+
+	// Missing input is the point at infinity
+	output, err = bn256AddFunc(nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, make([]byte, 64), output)
+	output, err = bn256AddFunc(nil, nil, hexInput(bn256G1), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, hexInput(bn256G1), output)
+
+	// (1, 3) is not on the curve
+	_, err = bn256AddFunc(nil, nil, hexInput(`
+		0000000000000000000000000000000000000000000000000000000000000001
+		0000000000000000000000000000000000000000000000000000000000000003`), nil)
+	assert.Error(t, err)
+}
+
+func TestBn256ScalarMul(t *testing.T) {
+	output, err := bn256ScalarMulFunc(nil, nil, hexInput(`
+		2bd3e6d0f3b142924f5ca7b49ce5b9d54c4703d7ae5648e61d02268b1a0a9fb7
+		21611ce0a6af85915e2f1d70300909ce2e49dfad4a4619c8390cae66cefdb204
+		00000000000000000000000000000000000000000000000011138ce750fa15c2`), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, hexInput(`
+		070a8d6a982153cae4be29d434e8faef8a47b274a053f5a4ee2a6c9c13c31e5c
+		031b8ce914eba3a9ffb989f9cdd5b0f01943074bf4f0f315690ec3cec6981afc`), output)
+
+	output, err = bn256ScalarMulFunc(nil, nil, hexInput(bn256G1+
+		`0000000000000000000000000000000000000000000000000000000000000002`), nil)
+	assert.NoError(t, err)
+	doubled, err := bn256AddFunc(nil, nil, hexInput(bn256G1+bn256G1), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, doubled, output)
+}
+
+func TestBn256Pairing(t *testing.T) {
+	// e(G1, G2) * e(-G1, G2) = 1
+	output, err := bn256PairingFunc(nil, nil, hexInput(bn256G1+bn256G2+bn256NegG1+bn256G2), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(1).Bytes(), output)
+
+	// e(2 G1, G2) * e(-G1, G2) * e(-G1, G2) = 1
+	doubled, err := bn256AddFunc(nil, nil, hexInput(bn256G1+bn256G1), nil)
+	assert.NoError(t, err)
+	input := append(append(doubled, hexInput(bn256G2+bn256NegG1+bn256G2)...),
+		hexInput(bn256NegG1+bn256G2)...)
+	output, err = bn256PairingFunc(nil, nil, input, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(1).Bytes(), output)
+
+	// e(G1, G2) is not 1
+	output, err = bn256PairingFunc(nil, nil, hexInput(bn256G1+bn256G2), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Zero256.Bytes(), output)
+
+	// The empty product is 1
+	output, err = bn256PairingFunc(nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(1).Bytes(), output)
+
+	_, err = bn256PairingFunc(nil, nil, hexInput(bn256G1 + bn256G2)[1:], nil)
+	assert.Equal(t, ErrBn256PairingInputLen, err)
+	// G1 for G2
+	_, err = bn256PairingFunc(nil, nil, hexInput(bn256G1+bn256G1+bn256G1), nil)
+	assert.Error(t, err)
+
+	gs := DefaultGasSchedule()
+	assert.Equal(t, int64(100000+3*80000), gs.NativeContractGas(Int64ToWord256(8), input))
+}
+
+func TestCallPrecompile(t *testing.T) {
+	ourVm := NewVM(newAppState(), newParams(), Zero256, nil)
+	callerAccount, _ := makeAccountWithCode(ourVm.appState, "caller", nil)
+	// 2**10 % 1000
+	input := Bytecode(Int64ToWord256(1), Int64ToWord256(1), Int64ToWord256(32), 2, 10, Int64ToWord256(1000))
+	var gas int64 = 100000
+	output, err := ourVm.Call(callerAccount, callerAccount,
+		Bytecode(callCode(STATICCALL, Int64ToWord256(5).Postfix(20), input), PUSH1, 32, PUSH1, 0, RETURN),
+		[]byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(24).Bytes(), output)
+}
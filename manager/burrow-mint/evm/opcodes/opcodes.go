@@ -52,6 +52,9 @@ const (
 	XOR
 	NOT
 	BYTE
+	SHL
+	SHR
+	SAR
 
 	SHA3 = 0x20
 )
@@ -71,6 +74,9 @@ const (
 	GASPRICE_DEPRECATED
 	EXTCODESIZE
 	EXTCODECOPY
+	RETURNDATASIZE
+	RETURNDATACOPY
+	EXTCODEHASH
 )
 
 const (
@@ -183,9 +189,12 @@ const (
 	CALLCODE
 	RETURN
 	DELEGATECALL
+	CREATE2
 
 	// 0x70 range - other
-	SUICIDE = 0xff
+	STATICCALL = 0xfa
+	REVERT     = 0xfd
+	SUICIDE    = 0xff
 )
 
 // Since the opcodes aren't all in order we can't use a regular slice
@@ -214,6 +223,9 @@ var opCodeToString = map[OpCode]string{
 	OR:     "OR",
 	XOR:    "XOR",
 	BYTE:   "BYTE",
+	SHL:    "SHL",
+	SHR:    "SHR",
+	SAR:    "SAR",
 	ADDMOD: "ADDMOD",
 	MULMOD: "MULMOD",
 
@@ -232,6 +244,8 @@ var opCodeToString = map[OpCode]string{
 	CODESIZE:            "CODESIZE",
 	CODECOPY:            "CODECOPY",
 	GASPRICE_DEPRECATED: "TXGASPRICE_DEPRECATED",
+	RETURNDATASIZE:      "RETURNDATASIZE",
+	RETURNDATACOPY:      "RETURNDATACOPY",
 
 	// 0x40 range - block operations
 	BLOCKHASH:             "BLOCKHASH",
@@ -242,6 +256,7 @@ var opCodeToString = map[OpCode]string{
 	GASLIMIT:              "GASLIMIT",
 	EXTCODESIZE:           "EXTCODESIZE",
 	EXTCODECOPY:           "EXTCODECOPY",
+	EXTCODEHASH:           "EXTCODEHASH",
 
 	// 0x50 range - 'storage' and execution
 	POP: "POP",
@@ -338,9 +353,12 @@ var opCodeToString = map[OpCode]string{
 	RETURN:       "RETURN",
 	CALLCODE:     "CALLCODE",
 	DELEGATECALL: "DELEGATECALL",
+	CREATE2:      "CREATE2",
 
 	// 0x70 range - other
-	SUICIDE: "SUICIDE",
+	STATICCALL: "STATICCALL",
+	REVERT:     "REVERT",
+	SUICIDE:    "SUICIDE",
 }
 
 func (o OpCode) String() string {
@@ -0,0 +1,121 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	core_types "github.com/hyperledger/burrow/core/types"
+	. "github.com/hyperledger/burrow/word256"
+)
+
+// Wraps an AppState for the duration of a STATICCALL into a native contract.
+// Writes are dropped rather than passed to the underlying AppState and are
+// recorded so the VM can raise ErrWriteProtection. Accounts are handed out as
+// copies so that in-place changes to them cannot leak either.
+type readOnlyAppState struct {
+	backend AppState
+	written bool
+}
+
+var _ NameRegAppState = &readOnlyAppState{}
+var _ ValidatorAppState = &readOnlyAppState{}
+
+func newReadOnlyAppState(backend AppState) *readOnlyAppState {
+	return &readOnlyAppState{backend: backend}
+}
+
+func (ros *readOnlyAppState) GetAccount(addr Word256) *Account {
+	acc := ros.backend.GetAccount(addr)
+	if acc == nil {
+		return nil
+	}
+	accCopy := *acc
+	accCopy.Permissions.Roles = append([]string(nil), acc.Permissions.Roles...)
+	return &accCopy
+}
+
+func (ros *readOnlyAppState) UpdateAccount(*Account) {
+	ros.written = true
+}
+
+func (ros *readOnlyAppState) RemoveAccount(*Account) {
+	ros.written = true
+}
+
+func (ros *readOnlyAppState) CreateAccount(creator *Account) *Account {
+	ros.written = true
+	return &Account{}
+}
+
+func (ros *readOnlyAppState) CreateAccount2(creator *Account, salt Word256, initCode []byte) *Account {
+	ros.written = true
+	return &Account{}
+}
+
+func (ros *readOnlyAppState) GetStorage(addr Word256, key Word256) Word256 {
+	return ros.backend.GetStorage(addr, key)
+}
+
+func (ros *readOnlyAppState) SetStorage(addr Word256, key Word256, value Word256) {
+	ros.written = true
+}
+
+// Names can be read through a readOnlyAppState when they can be read through
+// its backend
+func (ros *readOnlyAppState) GetNameRegEntry(name string) *core_types.NameRegEntry {
+	nameRegAppState, ok := ros.backend.(NameRegAppState)
+	if !ok {
+		return nil
+	}
+	entry := nameRegAppState.GetNameRegEntry(name)
+	if entry == nil {
+		return nil
+	}
+	entryCopy := *entry
+	return &entryCopy
+}
+
+func (ros *readOnlyAppState) UpdateNameRegEntry(owner Word256, name, data string,
+	value int64) (*core_types.NameRegEntry, error) {
+	ros.written = true
+	return nil, nil
+}
+
+// As are validators
+func (ros *readOnlyAppState) GetValidators() []Word256 {
+	validatorAppState, ok := ros.backend.(ValidatorAppState)
+	if !ok {
+		return nil
+	}
+	return validatorAppState.GetValidators()
+}
+
+func (ros *readOnlyAppState) GetVotingPower(address Word256) int64 {
+	validatorAppState, ok := ros.backend.(ValidatorAppState)
+	if !ok {
+		return 0
+	}
+	return validatorAppState.GetVotingPower(address)
+}
+
+func (ros *readOnlyAppState) BondValidator(owner Word256, pubKey Word256, amount int64,
+	signature []byte) (Word256, error) {
+	ros.written = true
+	return Zero256, nil
+}
+
+func (ros *readOnlyAppState) UnbondValidator(owner Word256, address Word256, anyOwner bool) error {
+	ros.written = true
+	return nil
+}
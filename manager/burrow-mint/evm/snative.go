@@ -15,9 +15,11 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/hyperledger/burrow/common/sanity"
+	core_types "github.com/hyperledger/burrow/core/types"
 	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
 	ptypes "github.com/hyperledger/burrow/permission/types"
 	. "github.com/hyperledger/burrow/word256"
@@ -42,8 +44,16 @@ type SNativeContractDescription struct {
 	Name          string
 	functionsByID map[abi.FunctionSelector]*SNativeFunctionDescription
 	functions     []*SNativeFunctionDescription
+	// The functions' arguments and return values as ABI types
+	methodsByID map[abi.FunctionSelector]*abi.Method
 }
 
+// An SNative function is called with its arguments decoded according to the
+// Args of its description, as abi.Unpack gives them, and returns its result
+// to be encoded according to its Return
+type SNativeFunction func(appState AppState, caller *Account, args []interface{},
+	gas *int64) (result interface{}, err error)
+
 // Metadata for SNative functions. Act as call targets for the EVM when
 // collected into an SNativeContractDescription. Can be used to generate
 // bindings in a smart contract languages.
@@ -59,8 +69,8 @@ type SNativeFunctionDescription struct {
 	// Permissions required to call function
 	PermFlag ptypes.PermFlag
 	// Native function to which calls will be dispatched when a containing
-	// contract is called with a FunctionSelector matching this NativeContract
-	F NativeContract
+	// contract is called with a FunctionSelector matching this function
+	F SNativeFunction
 }
 
 func registerSNativeContracts() {
@@ -183,6 +193,122 @@ func SNativeContracts() map[string]*SNativeContractDescription {
 				ptypes.SetGlobal,
 				setGlobal},
 		),
+
+		NewSNativeContract(`
+		* Interface for the name registry.
+		* @dev This interface describes the functions exposed by the SNative name registry in burrow.
+		* @dev Names are registered with the same costs and rules as a NameTx, paid for out of the calling contract's balance.
+		`,
+			"NameReg",
+			&SNativeFunctionDescription{`
+			* @notice Gets the data registered under a name
+			* @param _name the name
+			* @return data the data registered or empty if the name is not registered or has expired
+			`,
+				"getName",
+				[]abi.Arg{
+					arg("_name", abi.StringTypeName)},
+				ret("data", abi.StringTypeName),
+				ptypes.Name,
+				getName},
+
+			&SNativeFunctionDescription{`
+			* @notice Registers data under a name, or updates it if the caller owns the name. Registering empty data for no amount removes the name.
+			* @param _name the name
+			* @param _data the data to register
+			* @param _amount the amount taken from the caller's balance to pay for the registration
+			* @return expires the block height at which the registration expires or zero if it was removed
+			`,
+				"setName",
+				[]abi.Arg{
+					arg("_name", abi.StringTypeName),
+					arg("_data", abi.StringTypeName),
+					arg("_amount", abi.Uint64TypeName)},
+				ret("expires", abi.Uint64TypeName),
+				ptypes.Name,
+				setName},
+
+			&SNativeFunctionDescription{`
+			* @notice Gets the owner of a name
+			* @param _name the name
+			* @return owner the address of the owner or zero if the name is not registered or has expired
+			`,
+				"owner",
+				[]abi.Arg{
+					arg("_name", abi.StringTypeName)},
+				ret("owner", abi.AddressTypeName),
+				ptypes.Name,
+				nameOwner},
+
+			&SNativeFunctionDescription{`
+			* @notice Gets the block height at which the registration of a name expires
+			* @param _name the name
+			* @return expires the block height or zero if the name is not registered or has expired
+			`,
+				"expires",
+				[]abi.Arg{
+					arg("_name", abi.StringTypeName)},
+				ret("expires", abi.Uint64TypeName),
+				ptypes.Name,
+				nameExpires},
+		),
+
+		NewSNativeContract(`
+		* Interface for managing the validator set.
+		* @dev This interface describes the functions exposed by the SNative validators layer in burrow.
+		* @dev Bonds are paid for out of the calling contract's balance and released back to it after unbonding, as for a BondTx. Changes to the validator set take effect at the end of the block.
+		`,
+			"Validators",
+			&SNativeFunctionDescription{`
+			* @notice Bonds a new validator
+			* @param _pubKey the validator's Ed25519 public key
+			* @param _amount the amount taken from the caller's balance to bond, which becomes the validator's voting power
+			* @param _signature the validator's signature of the BondTx with the caller as its only input and output for _amount
+			* @return validator the address of the validator
+			`,
+				"bond",
+				[]abi.Arg{
+					arg("_pubKey", abi.Bytes32TypeName),
+					arg("_amount", abi.Uint64TypeName),
+					arg("_signature", abi.BytesTypeName)},
+				ret("validator", abi.AddressTypeName),
+				ptypes.Bond,
+				bondValidator},
+
+			&SNativeFunctionDescription{`
+			* @notice Unbonds a validator, which must have been bonded by the caller unless the caller has the Root permission
+			* @param _validator the address of the validator
+			* @return result whether the validator was unbonded
+			`,
+				"unbond",
+				[]abi.Arg{
+					arg("_validator", abi.AddressTypeName)},
+				ret("result", abi.BoolTypeName),
+				ptypes.Bond,
+				unbondValidator},
+
+			&SNativeFunctionDescription{`
+			* @notice Lists the bonded validators
+			* @return validators the addresses of the bonded validators
+			`,
+				"listValidators",
+				[]abi.Arg{},
+				ret("validators", abi.AddressTypeName+"[]"),
+				ptypes.HasBase,
+				listValidators},
+
+			&SNativeFunctionDescription{`
+			* @notice Gets the voting power of a validator
+			* @param _validator the address of the validator
+			* @return power the voting power or zero if the validator is not bonded
+			`,
+				"votingPower",
+				[]abi.Arg{
+					arg("_validator", abi.AddressTypeName)},
+				ret("power", abi.Uint64TypeName),
+				ptypes.HasBase,
+				votingPower},
+		),
 	}
 
 	contractMap := make(map[string]*SNativeContractDescription, len(contracts))
@@ -204,8 +330,14 @@ func NewSNativeContract(comment, name string,
 	functions ...*SNativeFunctionDescription) *SNativeContractDescription {
 
 	functionsByID := make(map[abi.FunctionSelector]*SNativeFunctionDescription, len(functions))
+	methodsByID := make(map[abi.FunctionSelector]*abi.Method, len(functions))
 	for _, f := range functions {
+		method, err := f.abiMethod()
+		if err != nil {
+			panic(fmt.Errorf("Function %s has an invalid ABI: %v", f.Name, err))
+		}
 		fid := f.ID()
+		methodsByID[fid] = method
 		otherF, ok := functionsByID[fid]
 		if ok {
 			panic(fmt.Errorf("Function with ID %x already defined: %s", fid,
@@ -218,6 +350,7 @@ func NewSNativeContract(comment, name string,
 		Name:          name,
 		functionsByID: functionsByID,
 		functions:     functions,
+		methodsByID:   methodsByID,
 	}
 }
 
@@ -243,14 +376,20 @@ func (contract *SNativeContractDescription) Dispatch(appState AppState,
 		return nil, ErrInvalidPermission{caller.Address, function.Name}
 	}
 
-	// ensure there are enough arguments
-	if len(remainingArgs) != function.NArgs()*Word256Length {
-		return nil, fmt.Errorf("%s() takes %d arguments", function.Name,
-			function.NArgs())
+	method := contract.methodsByID[function.ID()]
+	// decode the arguments, which ensures there are enough and they are well formed
+	argValues, err := abi.Unpack(method.Inputs, remainingArgs)
+	if err != nil {
+		return nil, fmt.Errorf("%s() takes %d arguments: %v", function.Name,
+			function.NArgs(), err)
 	}
 
 	// call the function
-	return function.F(appState, caller, remainingArgs, gas)
+	result, err := function.F(appState, caller, argValues, gas)
+	if err != nil {
+		return nil, err
+	}
+	return abi.Pack(method.Outputs, result)
 }
 
 // We define the address of an SNative contact as the last 20 bytes of the sha3
@@ -324,6 +463,28 @@ func (function *SNativeFunctionDescription) NArgs() int {
 	return len(function.Args)
 }
 
+// Get the function's arguments and return value as ABI types
+func (function *SNativeFunctionDescription) abiMethod() (*abi.Method, error) {
+	method := &abi.Method{
+		Name:     function.Name,
+		Inputs:   make([]abi.Argument, len(function.Args)),
+		Selector: function.ID(),
+	}
+	for i, arg := range function.Args {
+		argType, err := abi.ParseType(string(arg.TypeName))
+		if err != nil {
+			return nil, err
+		}
+		method.Inputs[i] = abi.Argument{Name: arg.Name, Type: argType}
+	}
+	returnType, err := abi.ParseType(string(function.Return.TypeName))
+	if err != nil {
+		return nil, err
+	}
+	method.Outputs = []abi.Argument{{Name: function.Return.Name, Type: returnType}}
+	return method, nil
+}
+
 func arg(name string, abiTypeName abi.TypeName) abi.Arg {
 	return abi.Arg{
 		Name:     name,
@@ -341,47 +502,43 @@ func ret(name string, abiTypeName abi.TypeName) abi.Return {
 // Permission function defintions
 
 // TODO: catch errors, log em, return 0s to the vm (should some errors cause exceptions though?)
-func hasBase(appState AppState, caller *Account, args []byte, gas *int64) (output []byte, err error) {
-	addr, permNum := returnTwoArgs(args)
-	vmAcc := appState.GetAccount(addr)
-	if vmAcc == nil {
-		return nil, fmt.Errorf("Unknown account %X", addr)
+func hasBase(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	address, permN := args[0].(abi.Address), ptypes.PermFlag(args[1].(uint64)) // already shifted
+	vmAcc, err := accountArg(appState, address)
+	if err != nil {
+		return nil, err
 	}
-	permN := ptypes.PermFlag(Uint64FromWord256(permNum)) // already shifted
 	if !ValidPermN(permN) {
 		return nil, ptypes.ErrInvalidPermission(permN)
 	}
 	permInt := byteFromBool(HasPermission(appState, vmAcc, permN))
-	dbg.Printf("snative.hasBasePerm(0x%X, %b) = %v\n", addr.Postfix(20), permN, permInt)
-	return LeftPadWord256([]byte{permInt}).Bytes(), nil
+	dbg.Printf("snative.hasBasePerm(0x%X, %b) = %v\n", address, permN, permInt)
+	return uint64(permInt), nil
 }
 
-func setBase(appState AppState, caller *Account, args []byte, gas *int64) (output []byte, err error) {
-	addr, permNum, permVal := returnThreeArgs(args)
-	vmAcc := appState.GetAccount(addr)
-	if vmAcc == nil {
-		return nil, fmt.Errorf("Unknown account %X", addr)
+func setBase(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	address, permN, permV := args[0].(abi.Address), ptypes.PermFlag(args[1].(uint64)), args[2].(bool)
+	vmAcc, err := accountArg(appState, address)
+	if err != nil {
+		return nil, err
 	}
-	permN := ptypes.PermFlag(Uint64FromWord256(permNum))
 	if !ValidPermN(permN) {
 		return nil, ptypes.ErrInvalidPermission(permN)
 	}
-	permV := !permVal.IsZero()
 	if err = vmAcc.Permissions.Base.Set(permN, permV); err != nil {
 		return nil, err
 	}
 	appState.UpdateAccount(vmAcc)
-	dbg.Printf("snative.setBasePerm(0x%X, %b, %v)\n", addr.Postfix(20), permN, permV)
-	return effectivePermBytes(vmAcc.Permissions.Base, globalPerms(appState)), nil
+	dbg.Printf("snative.setBasePerm(0x%X, %b, %v)\n", address, permN, permV)
+	return effectivePerms(vmAcc.Permissions.Base, globalPerms(appState)), nil
 }
 
-func unsetBase(appState AppState, caller *Account, args []byte, gas *int64) (output []byte, err error) {
-	addr, permNum := returnTwoArgs(args)
-	vmAcc := appState.GetAccount(addr)
-	if vmAcc == nil {
-		return nil, fmt.Errorf("Unknown account %X", addr)
+func unsetBase(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	address, permN := args[0].(abi.Address), ptypes.PermFlag(args[1].(uint64))
+	vmAcc, err := accountArg(appState, address)
+	if err != nil {
+		return nil, err
 	}
-	permN := ptypes.PermFlag(Uint64FromWord256(permNum))
 	if !ValidPermN(permN) {
 		return nil, ptypes.ErrInvalidPermission(permN)
 	}
@@ -389,70 +546,196 @@ func unsetBase(appState AppState, caller *Account, args []byte, gas *int64) (out
 		return nil, err
 	}
 	appState.UpdateAccount(vmAcc)
-	dbg.Printf("snative.unsetBasePerm(0x%X, %b)\n", addr.Postfix(20), permN)
-	return effectivePermBytes(vmAcc.Permissions.Base, globalPerms(appState)), nil
+	dbg.Printf("snative.unsetBasePerm(0x%X, %b)\n", address, permN)
+	return effectivePerms(vmAcc.Permissions.Base, globalPerms(appState)), nil
 }
 
-func setGlobal(appState AppState, caller *Account, args []byte, gas *int64) (output []byte, err error) {
-	permNum, permVal := returnTwoArgs(args)
+func setGlobal(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	permN, permV := ptypes.PermFlag(args[0].(uint64)), args[1].(bool)
 	vmAcc := appState.GetAccount(ptypes.GlobalPermissionsAddress256)
 	if vmAcc == nil {
 		sanity.PanicSanity("cant find the global permissions account")
 	}
-	permN := ptypes.PermFlag(Uint64FromWord256(permNum))
 	if !ValidPermN(permN) {
 		return nil, ptypes.ErrInvalidPermission(permN)
 	}
-	permV := !permVal.IsZero()
 	if err = vmAcc.Permissions.Base.Set(permN, permV); err != nil {
 		return nil, err
 	}
 	appState.UpdateAccount(vmAcc)
 	dbg.Printf("snative.setGlobalPerm(%b, %v)\n", permN, permV)
-	return permBytes(vmAcc.Permissions.Base.ResultantPerms()), nil
+	return uint64(vmAcc.Permissions.Base.ResultantPerms()), nil
 }
 
-func hasRole(appState AppState, caller *Account, args []byte, gas *int64) (output []byte, err error) {
-	addr, role := returnTwoArgs(args)
-	vmAcc := appState.GetAccount(addr)
-	if vmAcc == nil {
-		return nil, fmt.Errorf("Unknown account %X", addr)
+func hasRole(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	address, roleS := args[0].(abi.Address), string(args[1].([]byte))
+	vmAcc, err := accountArg(appState, address)
+	if err != nil {
+		return nil, err
 	}
-	roleS := string(role.Bytes())
-	permInt := byteFromBool(vmAcc.Permissions.HasRole(roleS))
-	dbg.Printf("snative.hasRole(0x%X, %s) = %v\n", addr.Postfix(20), roleS, permInt > 0)
-	return LeftPadWord256([]byte{permInt}).Bytes(), nil
+	hasRole := vmAcc.Permissions.HasRole(roleS)
+	dbg.Printf("snative.hasRole(0x%X, %s) = %v\n", address, roleS, hasRole)
+	return hasRole, nil
 }
 
-func addRole(appState AppState, caller *Account, args []byte, gas *int64) (output []byte, err error) {
-	addr, role := returnTwoArgs(args)
-	vmAcc := appState.GetAccount(addr)
-	if vmAcc == nil {
-		return nil, fmt.Errorf("Unknown account %X", addr)
+func addRole(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	address, roleS := args[0].(abi.Address), string(args[1].([]byte))
+	vmAcc, err := accountArg(appState, address)
+	if err != nil {
+		return nil, err
 	}
-	roleS := string(role.Bytes())
-	permInt := byteFromBool(vmAcc.Permissions.AddRole(roleS))
+	added := vmAcc.Permissions.AddRole(roleS)
 	appState.UpdateAccount(vmAcc)
-	dbg.Printf("snative.addRole(0x%X, %s) = %v\n", addr.Postfix(20), roleS, permInt > 0)
-	return LeftPadWord256([]byte{permInt}).Bytes(), nil
+	dbg.Printf("snative.addRole(0x%X, %s) = %v\n", address, roleS, added)
+	return added, nil
 }
 
-func removeRole(appState AppState, caller *Account, args []byte, gas *int64) (output []byte, err error) {
-	addr, role := returnTwoArgs(args)
-	vmAcc := appState.GetAccount(addr)
-	if vmAcc == nil {
-		return nil, fmt.Errorf("Unknown account %X", addr)
+func removeRole(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	address, roleS := args[0].(abi.Address), string(args[1].([]byte))
+	vmAcc, err := accountArg(appState, address)
+	if err != nil {
+		return nil, err
 	}
-	roleS := string(role.Bytes())
-	permInt := byteFromBool(vmAcc.Permissions.RmRole(roleS))
+	removed := vmAcc.Permissions.RmRole(roleS)
 	appState.UpdateAccount(vmAcc)
-	dbg.Printf("snative.rmRole(0x%X, %s) = %v\n", addr.Postfix(20), roleS, permInt > 0)
-	return LeftPadWord256([]byte{permInt}).Bytes(), nil
+	dbg.Printf("snative.rmRole(0x%X, %s) = %v\n", address, roleS, removed)
+	return removed, nil
+}
+
+// Name registry function definitions
+
+func getName(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	name := args[0].(string)
+	entry, err := nameRegEntryArg(appState, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return "", nil
+	}
+	dbg.Printf("snative.getName(%s) = %s\n", name, entry.Data)
+	return entry.Data, nil
+}
+
+func setName(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	name, data, amount := args[0].(string), args[1].(string), args[2].(uint64)
+	nameRegAppState, ok := appState.(NameRegAppState)
+	if !ok {
+		return nil, ErrNameRegUnavailable
+	}
+	if int64(amount) < 0 || int64(amount) > caller.Balance {
+		return nil, ErrInsufficientBalance
+	}
+	entry, err := nameRegAppState.UpdateNameRegEntry(caller.Address, name, data, int64(amount))
+	if err != nil {
+		return nil, err
+	}
+	caller.Balance -= int64(amount)
+	appState.UpdateAccount(caller)
+	if entry == nil {
+		dbg.Printf("snative.setName(%s, %s, %v) removed\n", name, data, amount)
+		return uint64(0), nil
+	}
+	dbg.Printf("snative.setName(%s, %s, %v) = %v\n", name, data, amount, entry.Expires)
+	return uint64(entry.Expires), nil
+}
+
+func nameOwner(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	name := args[0].(string)
+	entry, err := nameRegEntryArg(appState, name)
+	if err != nil {
+		return nil, err
+	}
+	var owner abi.Address
+	if entry != nil {
+		copy(owner[:], entry.Owner)
+	}
+	dbg.Printf("snative.owner(%s) = %X\n", name, owner)
+	return owner, nil
+}
+
+func nameExpires(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	name := args[0].(string)
+	entry, err := nameRegEntryArg(appState, name)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return uint64(0), nil
+	}
+	dbg.Printf("snative.expires(%s) = %v\n", name, entry.Expires)
+	return uint64(entry.Expires), nil
+}
+
+// Validator function definitions
+
+func bondValidator(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	pubKey, amount, signature := RightPadWord256(args[0].([]byte)), args[1].(uint64), args[2].([]byte)
+	validatorAppState, ok := appState.(ValidatorAppState)
+	if !ok {
+		return nil, ErrValidatorsUnavailable
+	}
+	if int64(amount) < 0 || int64(amount) > caller.Balance {
+		return nil, ErrInsufficientBalance
+	}
+	address, err := validatorAppState.BondValidator(caller.Address, pubKey, int64(amount), signature)
+	if err != nil {
+		return nil, err
+	}
+	caller.Balance -= int64(amount)
+	appState.UpdateAccount(caller)
+	dbg.Printf("snative.bond(%X, %v) = %X\n", pubKey, amount, address.Postfix(abi.AddressLength))
+	return address.Postfix(abi.AddressLength), nil
+}
+
+func unbondValidator(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	address := args[0].(abi.Address)
+	validatorAppState, ok := appState.(ValidatorAppState)
+	if !ok {
+		return nil, ErrValidatorsUnavailable
+	}
+	err = validatorAppState.UnbondValidator(caller.Address, LeftPadWord256(address[:]),
+		HasPermission(appState, caller, ptypes.Root))
+	if err != nil {
+		return nil, err
+	}
+	dbg.Printf("snative.unbond(0x%X)\n", address)
+	return true, nil
+}
+
+func listValidators(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	validatorAppState, ok := appState.(ValidatorAppState)
+	if !ok {
+		return nil, ErrValidatorsUnavailable
+	}
+	validators := validatorAppState.GetValidators()
+	addresses := make([]abi.Address, len(validators))
+	for i, validator := range validators {
+		copy(addresses[i][:], validator.Postfix(abi.AddressLength))
+	}
+	dbg.Printf("snative.listValidators() = %X\n", addresses)
+	return addresses, nil
+}
+
+func votingPower(appState AppState, caller *Account, args []interface{}, gas *int64) (result interface{}, err error) {
+	address := args[0].(abi.Address)
+	validatorAppState, ok := appState.(ValidatorAppState)
+	if !ok {
+		return nil, ErrValidatorsUnavailable
+	}
+	power := validatorAppState.GetVotingPower(LeftPadWord256(address[:]))
+	dbg.Printf("snative.votingPower(0x%X) = %v\n", address, power)
+	return uint64(power), nil
 }
 
 //------------------------------------------------------------------------------------------------
 // Errors and utility funcs
 
+var (
+	ErrNameRegUnavailable    = errors.New("The name registry is not available to SNatives in this AppState")
+	ErrValidatorsUnavailable = errors.New("The validator set is not available to SNatives in this AppState")
+)
+
 type ErrInvalidPermission struct {
 	Address Word256
 	SNative string
@@ -481,28 +764,28 @@ func globalPerms(appState AppState) ptypes.BasePermissions {
 
 // Compute the effective permissions from an Account's BasePermissions by
 // taking the bitwise or with the global BasePermissions resultant permissions
-func effectivePermBytes(basePerms ptypes.BasePermissions,
-	globalPerms ptypes.BasePermissions) []byte {
-	return permBytes(basePerms.ResultantPerms() | globalPerms.ResultantPerms())
-}
-
-func permBytes(basePerms ptypes.PermFlag) []byte {
-	return Uint64ToWord256(uint64(basePerms)).Bytes()
+func effectivePerms(basePerms ptypes.BasePermissions,
+	globalPerms ptypes.BasePermissions) uint64 {
+	return uint64(basePerms.ResultantPerms() | globalPerms.ResultantPerms())
 }
 
-// CONTRACT: length has already been checked
-func returnTwoArgs(args []byte) (a Word256, b Word256) {
-	copy(a[:], args[:32])
-	copy(b[:], args[32:64])
-	return
+// Get the live name registry entry for a name an SNative function was called
+// with, which is nil if there is none
+func nameRegEntryArg(appState AppState, name string) (*core_types.NameRegEntry, error) {
+	nameRegAppState, ok := appState.(NameRegAppState)
+	if !ok {
+		return nil, ErrNameRegUnavailable
+	}
+	return nameRegAppState.GetNameRegEntry(name), nil
 }
 
-// CONTRACT: length has already been checked
-func returnThreeArgs(args []byte) (a Word256, b Word256, c Word256) {
-	copy(a[:], args[:32])
-	copy(b[:], args[32:64])
-	copy(c[:], args[64:96])
-	return
+// Get the account at an address an SNative function was called with
+func accountArg(appState AppState, address abi.Address) (*Account, error) {
+	vmAcc := appState.GetAccount(LeftPadWord256(address[:]))
+	if vmAcc == nil {
+		return nil, fmt.Errorf("Unknown account %X", address)
+	}
+	return vmAcc, nil
 }
 
 func byteFromBool(b bool) byte {
@@ -92,6 +92,22 @@ func TestSNativeContractDescription_Dispatch(t *testing.T) {
 		grantee.Address, permFlagToWord256(ptypes.CreateAccount)), &gas)
 	assert.NoError(t, err)
 	assert.Equal(t, retValue, LeftPadBytes([]byte{1}, 32))
+
+	// Arguments are decoded by their ABI types, so must be well formed
+	_, err = contract.Dispatch(state, caller, Bytecode(funcID[:], grantee.Address), &gas)
+	assert.Error(t, err)
+	function, err = contract.FunctionByName("setBase")
+	assert.NoError(t, err)
+	funcID = function.ID()
+	_, err = contract.Dispatch(state, caller, Bytecode(funcID[:], grantee.Address,
+		permFlagToWord256(ptypes.Call), Int64ToWord256(2)), &gas)
+	assert.Error(t, err)
+	retValue, err = contract.Dispatch(state, caller, Bytecode(funcID[:], grantee.Address,
+		permFlagToWord256(ptypes.Call), Int64ToWord256(1)), &gas)
+	assert.NoError(t, err)
+	// The effective permissions, which include those set globally
+	effectivePerms := ptypes.PermFlag(Uint64FromWord256(LeftPadWord256(retValue)))
+	assert.Equal(t, ptypes.Call, effectivePerms&ptypes.Call)
 }
 
 func TestSNativeContractDescription_Address(t *testing.T) {
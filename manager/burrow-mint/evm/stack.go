@@ -27,20 +27,26 @@ type Stack struct {
 	data []Word256
 	ptr  int
 
-	gas *int64
-	err *error
+	// Gas charged for each push and pop
+	opGas int64
+	gas   *int64
+	err   *error
 }
 
-func NewStack(capacity int, gas *int64, err *error) *Stack {
+func NewStack(capacity int, opGas int64, gas *int64, err *error) *Stack {
 	return &Stack{
-		data: make([]Word256, capacity),
-		ptr:  0,
-		gas:  gas,
-		err:  err,
+		data:  make([]Word256, capacity),
+		ptr:   0,
+		opGas: opGas,
+		gas:   gas,
+		err:   err,
 	}
 }
 
 func (st *Stack) useGas(gasToUse int64) {
+	if gasToUse == 0 {
+		return
+	}
 	if *st.gas > gasToUse {
 		*st.gas -= gasToUse
 	} else {
@@ -55,7 +61,7 @@ func (st *Stack) setErr(err error) {
 }
 
 func (st *Stack) Push(d Word256) {
-	st.useGas(GasStackOp)
+	st.useGas(st.opGas)
 	if st.ptr == cap(st.data) {
 		st.setErr(ErrDataStackOverflow)
 		return
@@ -77,7 +83,7 @@ func (st *Stack) Push64(i int64) {
 }
 
 func (st *Stack) Pop() Word256 {
-	st.useGas(GasStackOp)
+	st.useGas(st.opGas)
 	if st.ptr == 0 {
 		st.setErr(ErrDataStackUnderflow)
 		return Zero256
@@ -100,7 +106,7 @@ func (st *Stack) Len() int {
 }
 
 func (st *Stack) Swap(n int) {
-	st.useGas(GasStackOp)
+	st.useGas(st.opGas)
 	if st.ptr < n {
 		st.setErr(ErrDataStackUnderflow)
 		return
@@ -110,7 +116,7 @@ func (st *Stack) Swap(n int) {
 }
 
 func (st *Stack) Dup(n int) {
-	st.useGas(GasStackOp)
+	st.useGas(st.opGas)
 	if st.ptr < n {
 		st.setErr(ErrDataStackUnderflow)
 		return
@@ -128,6 +134,13 @@ func (st *Stack) Peek() Word256 {
 	return st.data[st.ptr-1]
 }
 
+// Not an opcode, costs no gas. Returns a copy of the stack, bottom first.
+func (st *Stack) Words() []Word256 {
+	words := make([]Word256, st.ptr)
+	copy(words, st.data[:st.ptr])
+	return words
+}
+
 func (st *Stack) Print(n int) {
 	fmt.Println("### stack ###")
 	if st.ptr > 0 {
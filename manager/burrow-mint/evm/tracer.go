@@ -0,0 +1,160 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	core_types "github.com/hyperledger/burrow/core/types"
+	. "github.com/hyperledger/burrow/manager/burrow-mint/evm/opcodes"
+	. "github.com/hyperledger/burrow/word256"
+)
+
+// A Tracer follows the execution of the VM op by op, including the ops of any
+// contracts called. Attach one to a VM with SetTracer.
+type Tracer interface {
+	// Whether the tracer wants to follow the next op. Ops it does not want are
+	// run without the cost of copying out their step and neither Capture
+	// method is called for them.
+	WantsStep() bool
+	// Called before each op is run that the tracer wants
+	CaptureOpStart(step *TraceStep)
+	// Called with the same step once the op has run (or failed), by which time
+	// the fields describing its effects are filled in. Ops that call into
+	// another contract end after all the ops of the call.
+	CaptureOpEnd(step *TraceStep)
+}
+
+type TraceStep struct {
+	// The call depth, starting at 1 for the code run by VM.Call
+	Depth int
+	PC    int64
+	Op    OpCode
+	// Gas left before the op was run
+	Gas int64
+	// The stack before the op was run, bottom first
+	Stack []Word256
+
+	// Gas used by the op, including any used by a call it made
+	GasCost int64
+	// Size of memory after the op was run
+	MemorySize int64
+	// The region of memory the op changed, nil if it changed none
+	MemoryChange *core_types.MemoryChange
+	// Storage read or written by the op
+	Storage []core_types.StorageAccess
+	// The error that stopped execution at this op, if any
+	Err error
+}
+
+func (vm *VM) SetTracer(tracer Tracer) {
+	vm.tracer = tracer
+}
+
+// Ops that may change memory other than by growing it
+func changesMemory(op OpCode) bool {
+	switch op {
+	case MSTORE, MSTORE8, CALLDATACOPY, CODECOPY, EXTCODECOPY, RETURNDATACOPY,
+		CALL, CALLCODE, DELEGATECALL, STATICCALL:
+		return true
+	}
+	return false
+}
+
+// Returns the smallest region of memory that differs between before and after,
+// or nil if they do not differ. Memory beyond the end of before counts as
+// zeroed as it would be when grown.
+func memoryChange(before, after []byte) *core_types.MemoryChange {
+	first, last := -1, -1
+	for i, b := range after {
+		var old byte
+		if i < len(before) {
+			old = before[i]
+		}
+		if b != old {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	return &core_types.MemoryChange{
+		Offset: int64(first),
+		Data:   copyslice(after[first : last+1]),
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// A Tracer that keeps a core_types.StructLog of every op run
+type StructLogger struct {
+	logs []core_types.StructLog
+	// Ops beyond this many are not logged, unless it is zero
+	limit int
+	// Indices into logs of the ops that have started but not ended, innermost
+	// last, or -1 for ops that were not logged
+	started []int
+}
+
+var _ Tracer = &StructLogger{}
+
+func NewStructLogger(limit int) *StructLogger {
+	return &StructLogger{limit: limit}
+}
+
+// Steps are wanted until the limit is reached
+func (sl *StructLogger) WantsStep() bool {
+	return sl.limit <= 0 || len(sl.logs) < sl.limit
+}
+
+func (sl *StructLogger) CaptureOpStart(step *TraceStep) {
+	if !sl.WantsStep() {
+		sl.started = append(sl.started, -1)
+		return
+	}
+	sl.started = append(sl.started, len(sl.logs))
+	sl.logs = append(sl.logs, core_types.StructLog{
+		Depth: step.Depth,
+		PC:    step.PC,
+		Op:    step.Op.String(),
+		Gas:   step.Gas,
+		Stack: step.Stack,
+	})
+}
+
+func (sl *StructLogger) CaptureOpEnd(step *TraceStep) {
+	if len(sl.started) == 0 {
+		return
+	}
+	i := sl.started[len(sl.started)-1]
+	sl.started = sl.started[:len(sl.started)-1]
+	if i < 0 {
+		return
+	}
+	log := &sl.logs[i]
+	log.GasCost = step.GasCost
+	log.MemorySize = step.MemorySize
+	log.MemoryChange = step.MemoryChange
+	log.Storage = step.Storage
+	if step.Err != nil {
+		log.Error = step.Err.Error()
+	}
+}
+
+// The ops logged so far
+func (sl *StructLogger) StructLogs() []core_types.StructLog {
+	return sl.logs
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vm
+
+import (
+	"testing"
+
+	core_types "github.com/hyperledger/burrow/core/types"
+	. "github.com/hyperledger/burrow/manager/burrow-mint/evm/opcodes"
+	. "github.com/hyperledger/burrow/word256"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructLogger(t *testing.T) {
+	appState := newAppState()
+	ourVm := NewVM(appState, newParams(), Zero256, nil)
+	structLogger := NewStructLogger(0)
+	ourVm.SetTracer(structLogger)
+
+	account, _ := makeAccountWithCode(appState, "traced",
+		Bytecode(PUSH1, 0x2a, PUSH1, 0, MSTORE, PUSH1, 1, PUSH1, 0, SSTORE, returnWord()))
+	var gas int64 = 1000
+	_, err := ourVm.Call(account, account, account.Code, []byte{}, 0, &gas)
+	assert.NoError(t, err)
+
+	logs := structLogger.StructLogs()
+	var ops []string
+	for _, log := range logs {
+		ops = append(ops, log.Op)
+		assert.Equal(t, 1, log.Depth)
+	}
+	assert.Equal(t, []string{"PUSH1", "PUSH1", "MSTORE", "PUSH1", "PUSH1", "SSTORE",
+		"PUSH1", "PUSH1", "RETURN"}, ops)
+
+	assert.Equal(t, int64(1000), logs[0].Gas)
+	assert.Equal(t, GasStackOp, logs[0].GasCost)
+	assert.Empty(t, logs[0].Stack)
+
+	mstore := logs[2]
+	assert.Equal(t, []Word256{Int64ToWord256(0x2a), Zero256}, mstore.Stack)
+	assert.Equal(t, int64(32), mstore.MemorySize)
+	assert.Equal(t, &core_types.MemoryChange{Offset: 31, Data: []byte{0x2a}}, mstore.MemoryChange)
+
+	sstore := logs[5]
+	assert.Nil(t, sstore.MemoryChange)
+	assert.Equal(t, []core_types.StorageAccess{{
+		Address: account.Address,
+		Key:     Zero256,
+		Value:   Int64ToWord256(1),
+		Write:   true,
+	}}, sstore.Storage)
+}
+
+func TestStructLoggerError(t *testing.T) {
+	appState := newAppState()
+	ourVm := NewVM(appState, newParams(), Zero256, nil)
+	// Only the first op is logged
+	structLogger := NewStructLogger(1)
+	ourVm.SetTracer(structLogger)
+
+	account, _ := makeAccountWithCode(appState, "traced", Bytecode(PUSH1, 3, JUMP))
+	var gas int64 = 1000
+	_, err := ourVm.Call(account, account, account.Code, []byte{}, 0, &gas)
+	assert.Equal(t, ErrInvalidJumpDest, err)
+
+	logs := structLogger.StructLogs()
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "", logs[0].Error)
+
+	// Without a limit the failed jump is logged with its error
+	structLogger = NewStructLogger(0)
+	ourVm.SetTracer(structLogger)
+	_, err = ourVm.Call(account, account, account.Code, []byte{}, 0, &gas)
+	logs = structLogger.StructLogs()
+	assert.Len(t, logs, 2)
+	assert.Equal(t, ErrInvalidJumpDest.Error(), logs[1].Error)
+}
+
+// Counts the steps it is given, wanting only the first few
+type countingTracer struct {
+	want   int
+	starts int
+	ends   int
+}
+
+func (ct *countingTracer) WantsStep() bool                { return ct.starts < ct.want }
+func (ct *countingTracer) CaptureOpStart(step *TraceStep) { ct.starts++ }
+func (ct *countingTracer) CaptureOpEnd(step *TraceStep)   { ct.ends++ }
+
+func TestTracerWantsStep(t *testing.T) {
+	appState := newAppState()
+	ourVm := NewVM(appState, newParams(), Zero256, nil)
+	tracer := &countingTracer{want: 2}
+	ourVm.SetTracer(tracer)
+
+	account, _ := makeAccountWithCode(appState, "traced",
+		Bytecode(PUSH1, 0x2a, PUSH1, 0, MSTORE, PUSH1, 1, PUSH1, 0, SSTORE, returnWord()))
+	var gas int64 = 1000
+	_, err := ourVm.Call(account, account, account.Code, []byte{}, 0, &gas)
+	assert.NoError(t, err)
+	// The ops after those wanted are not handed over at all
+	assert.Equal(t, 2, tracer.starts)
+	assert.Equal(t, 2, tracer.ends)
+}
+
+func TestMemoryChange(t *testing.T) {
+	assert.Nil(t, memoryChange([]byte{1, 2}, []byte{1, 2}))
+	assert.Nil(t, memoryChange(nil, make([]byte, 64)))
+	assert.Equal(t, &core_types.MemoryChange{Offset: 1, Data: []byte{3, 2, 4}},
+		memoryChange([]byte{1, 2, 2, 2}, []byte{1, 3, 2, 4}))
+	assert.Equal(t, &core_types.MemoryChange{Offset: 33, Data: []byte{5}},
+		memoryChange(make([]byte, 32), append(make([]byte, 33), 5, 0)))
+}
@@ -17,6 +17,7 @@ package vm
 import (
 	"fmt"
 
+	core_types "github.com/hyperledger/burrow/core/types"
 	ptypes "github.com/hyperledger/burrow/permission/types"
 	. "github.com/hyperledger/burrow/word256"
 )
@@ -50,6 +51,9 @@ type AppState interface {
 	UpdateAccount(*Account)
 	RemoveAccount(*Account)
 	CreateAccount(*Account) *Account
+	// Creates an account at the address CREATE2 derives from the creator, salt
+	// and init code. Returns nil if there is already an account there.
+	CreateAccount2(creator *Account, salt Word256, initCode []byte) *Account
 
 	// Storage
 	GetStorage(Word256, Word256) Word256
@@ -57,9 +61,69 @@ type AppState interface {
 
 }
 
+// An AppState that can also take snapshots of its pending changes. When the
+// AppState given to the VM implements this the changes made by a call frame
+// that reverts or fails are rolled back without touching those of its callers.
+type SnapshotAppState interface {
+	AppState
+
+	// Returns an identifier for the current state of pending changes
+	Snapshot() int
+	// Discards all changes made since the snapshot was taken
+	RevertToSnapshot(snapshot int)
+}
+
+// An AppState that can also read and write the name registry, as needed by the
+// NameReg SNative. Updates follow the same rules as a NameTx.
+type NameRegAppState interface {
+	AppState
+
+	// Returns the entry registered under name, or nil if there is none or it
+	// has expired
+	GetNameRegEntry(name string) *core_types.NameRegEntry
+	// Registers data under name on behalf of owner paying value for it, which
+	// removes the entry when value is zero and data empty. Returns the updated
+	// entry or nil if it was removed.
+	UpdateNameRegEntry(owner Word256, name, data string, value int64) (*core_types.NameRegEntry, error)
+}
+
+// An AppState that can also change the validator set, as needed by the
+// Validators SNative. Validators bonded or unbonded through it are passed on to
+// the consensus engine at the end of the block.
+type ValidatorAppState interface {
+	AppState
+
+	// Returns the addresses of the bonded validators in order
+	GetValidators() []Word256
+	// Returns the voting power of the validator at address, which is 0 unless
+	// it is bonded
+	GetVotingPower(address Word256) int64
+	// Bonds a validator with the Ed25519 public key pubKey for amount, which is
+	// held until the validator is released to owner. The validator must have
+	// signed the BondTx made by txs.NewSNativeBondTx with signature. Returns the
+	// validator's address.
+	BondValidator(owner Word256, pubKey Word256, amount int64, signature []byte) (Word256, error)
+	// Unbonds the validator at address, which must have been bonded by owner
+	// unless anyOwner is set
+	UnbondValidator(owner Word256, address Word256, anyOwner bool) error
+}
+
+// Gives the hashes of past blocks to the BLOCKHASH op
+type BlockHashGetter interface {
+	// Returns the hash of the block at height, or Zero256 if it is not known
+	GetBlockHash(height int64) Word256
+}
+
 type Params struct {
 	BlockHeight int64
 	BlockHash   Word256
 	BlockTime   int64
 	GasLimit    int64
+	// Hashes of the blocks before BlockHeight, BLOCKHASH gives zero if nil
+	BlockHashes BlockHashGetter
+	// The LegacyGasSchedule is used if this is nil, as for a chain whose
+	// genesis params name none
+	GasSchedule *GasSchedule
+	// Bytes of memory each call frame may use, DefaultMemoryLimit if zero
+	MemoryLimit int64
 }
@@ -18,10 +18,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 
 	"github.com/hyperledger/burrow/common/math/integral"
 	"github.com/hyperledger/burrow/common/sanity"
+	core_types "github.com/hyperledger/burrow/core/types"
 	. "github.com/hyperledger/burrow/manager/burrow-mint/evm/opcodes"
 	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
 	ptypes "github.com/hyperledger/burrow/permission/types"
@@ -45,6 +47,9 @@ var (
 	ErrDataStackUnderflow     = errors.New("Data stack underflow")
 	ErrInvalidContract        = errors.New("Invalid contract")
 	ErrNativeContractCodeCopy = errors.New("Tried to copy native contract code")
+	ErrReturnDataOutOfBounds  = errors.New("Return data out of bounds")
+	ErrExecutionReverted      = errors.New("Execution reverted")
+	ErrWriteProtection        = errors.New("Attempt to modify state in a read-only (static) call")
 )
 
 type ErrPermission struct {
@@ -55,12 +60,25 @@ func (err ErrPermission) Error() string {
 	return fmt.Sprintf("Contract does not have permission to %s", err.typ)
 }
 
+// Returned when a call frame tries to use more memory than it is allowed
+type ErrMemoryLimitExceeded struct {
+	Limit     int64
+	Requested int64
+}
+
+func (err ErrMemoryLimitExceeded) Error() string {
+	return fmt.Sprintf("Memory out of bounds: access up to byte %v exceeds the limit "+
+		"of %v bytes per call", err.Requested, err.Limit)
+}
+
 const (
 	dataStackCapacity = 1024
-	callStackCapacity = 100         // TODO ensure usage.
-	memoryCapacity    = 1024 * 1024 // 1 MB
+	callStackCapacity = 100 // TODO ensure usage.
 )
 
+// Memory available to each call frame unless Params gives a MemoryLimit
+const DefaultMemoryLimit int64 = 1024 * 1024 // 1 MB
+
 type Debug bool
 
 var dbg Debug
@@ -82,17 +100,35 @@ type VM struct {
 	txid     []byte
 
 	callDepth int
+	// Set while executing within a STATICCALL, when no state may be modified
+	readOnly bool
+
+	gasSchedule *GasSchedule
+	memoryLimit int64
+	// Gas to be given back at the end of the top-level call for clearing storage
+	refund int64
 
-	evc events.Fireable
+	evc    events.Fireable
+	tracer Tracer
 }
 
 func NewVM(appState AppState, params Params, origin Word256, txid []byte) *VM {
+	gasSchedule := params.GasSchedule
+	if gasSchedule == nil {
+		gasSchedule = LegacyGasSchedule()
+	}
+	memoryLimit := params.MemoryLimit
+	if memoryLimit == 0 {
+		memoryLimit = DefaultMemoryLimit
+	}
 	return &VM{
-		appState:  appState,
-		params:    params,
-		origin:    origin,
-		callDepth: 0,
-		txid:      txid,
+		appState:    appState,
+		params:      params,
+		origin:      origin,
+		callDepth:   0,
+		txid:        txid,
+		gasSchedule: gasSchedule,
+		memoryLimit: memoryLimit,
 	}
 }
 
@@ -122,16 +158,35 @@ func HasPermission(appState AppState, acc *Account, perm ptypes.PermFlag) bool {
 func (vm *VM) fireCallEvent(exception *string, output *[]byte, caller, callee *Account, input []byte, value int64, gas *int64) {
 	// fire the post call event (including exception if applicable)
 	if vm.evc != nil {
+		var revertReason string
+		if *exception != "" {
+			revertReason, _ = RevertReason(*output)
+		}
 		vm.evc.FireEvent(txs.EventStringAccCall(callee.Address.Postfix(20)), txs.EventDataCall{
 			&txs.CallData{caller.Address.Postfix(20), callee.Address.Postfix(20), input, value, *gas},
 			vm.origin.Postfix(20),
 			vm.txid,
 			*output,
 			*exception,
+			revertReason,
+			nil,
 		})
 	}
 }
 
+// Takes a snapshot of the pending changes to appState (if it supports it) and
+// returns a function that will roll them back to the snapshot.
+func (vm *VM) snapshot() (revert func()) {
+	snapshotAppState, ok := vm.appState.(SnapshotAppState)
+	if !ok {
+		return func() {}
+	}
+	snapshot := snapshotAppState.Snapshot()
+	return func() {
+		snapshotAppState.RevertToSnapshot(snapshot)
+	}
+}
+
 // CONTRACT appState is aware of caller and callee, so we can just mutate them.
 // CONTRACT code and input are not mutated.
 // CONTRACT returned 'ret' is a new compact slice.
@@ -143,6 +198,9 @@ func (vm *VM) Call(caller, callee *Account, code, input []byte, value int64, gas
 	exception := new(string)
 	// fire the post call event (including exception if applicable)
 	defer vm.fireCallEvent(exception, &output, caller, callee, input, value, gas)
+	if vm.callDepth == 0 {
+		defer vm.applyRefund(*gas, gas)
+	}
 
 	if err = transfer(caller, callee, value); err != nil {
 		*exception = err.Error()
@@ -150,11 +208,15 @@ func (vm *VM) Call(caller, callee *Account, code, input []byte, value int64, gas
 	}
 
 	if len(code) > 0 {
+		revert := vm.snapshot()
+		refund := vm.refund
 		vm.callDepth += 1
 		output, err = vm.call(caller, callee, code, input, value, gas)
 		vm.callDepth -= 1
 		if err != nil {
 			*exception = err.Error()
+			revert()
+			vm.refund = refund
 			err := transfer(callee, caller, value)
 			if err != nil {
 				// data has been corrupted in ram
@@ -181,21 +243,109 @@ func (vm *VM) DelegateCall(caller, callee *Account, code, input []byte, value in
 	// DelegateCall does not transfer the value to the callee.
 
 	if len(code) > 0 {
+		revert := vm.snapshot()
+		refund := vm.refund
 		vm.callDepth += 1
 		output, err = vm.call(caller, callee, code, input, value, gas)
 		vm.callDepth -= 1
 		if err != nil {
 			*exception = err.Error()
+			revert()
+			vm.refund = refund
 		}
 	}
 
 	return
 }
 
+// Credits the refund earned for clearing storage, up to the fraction of the gas
+// used since startGas allowed by the gas schedule
+func (vm *VM) applyRefund(startGas int64, gas *int64) {
+	refund := vm.refund
+	vm.refund = 0
+	if vm.gasSchedule.MaxRefundQuotient > 0 {
+		maxRefund := (startGas - *gas) / vm.gasSchedule.MaxRefundQuotient
+		if refund > maxRefund {
+			refund = maxRefund
+		}
+	}
+	*gas += refund
+}
+
+// Grows memory a word at a time to cover size bytes from offset, charging for
+// the expansion. If the memory limit would be exceeded or there is not enough
+// gas set err and return true.
+func (vm *VM) expandMemory(memory *[]byte, gas *int64, offset, size int64, err *error) bool {
+	if size == 0 {
+		return false
+	}
+	if offset < 0 || size < 0 {
+		*err = firstErr(*err, ErrMemoryOutOfBounds)
+		return true
+	}
+	if offset > vm.memoryLimit || size > vm.memoryLimit ||
+		wordsFor(offset+size)*32 > vm.memoryLimit {
+		*err = firstErr(*err, ErrMemoryLimitExceeded{
+			Limit:     vm.memoryLimit,
+			Requested: offset + size,
+		})
+		return true
+	}
+	words := wordsFor(offset + size)
+	memoryWords := int64(len(*memory)) / 32
+	if words <= memoryWords {
+		return false
+	}
+	gasToUse := vm.gasSchedule.MemoryGas(words) - vm.gasSchedule.MemoryGas(memoryWords)
+	if useGasNegative(gas, gasToUse, err) {
+		return true
+	}
+	*memory = append(*memory, make([]byte, (words-memoryWords)*32)...)
+	return false
+}
+
+// Tells the tracer an op is about to run, copying memory if the op may change it
+// so that we can find what it changed. Returns a nil step, having copied
+// nothing, if the tracer does not want the op.
+func (vm *VM) startTraceStep(op OpCode, pc, gas int64, stack *Stack,
+	memory []byte) (step *TraceStep, memoryBefore []byte) {
+	if !vm.tracer.WantsStep() {
+		return nil, nil
+	}
+	step = &TraceStep{
+		Depth: vm.callDepth,
+		PC:    pc,
+		Op:    op,
+		Gas:   gas,
+		Stack: stack.Words(),
+	}
+	vm.tracer.CaptureOpStart(step)
+	if changesMemory(op) {
+		memoryBefore = copyslice(memory)
+	}
+	return step, memoryBefore
+}
+
+// Tells the tracer what the op of step did, if there is a step
+func (vm *VM) endTraceStep(step *TraceStep, memoryBefore, memory []byte, gas int64,
+	err error) {
+	if step == nil {
+		return
+	}
+	step.GasCost = step.Gas - gas
+	step.MemorySize = int64(len(memory))
+	if changesMemory(step.Op) {
+		step.MemoryChange = memoryChange(memoryBefore, memory)
+	}
+	step.Err = err
+	vm.tracer.CaptureOpEnd(step)
+}
+
 // Try to deduct gasToUse from gasLeft.  If ok return false, otherwise
-// set err and return true.
+// set err and return true. A negative gasToUse, as a cost that overflowed
+// gives, is more than there is.
 func useGasNegative(gasLeft *int64, gasToUse int64, err *error) bool {
-	if *gasLeft >= gasToUse {
+	if gasToUse >= 0 && *gasLeft >= gasToUse {
 		*gasLeft -= gasToUse
 		return false
 	} else if *err == nil {
@@ -204,23 +354,74 @@ func useGasNegative(gasLeft *int64, gasToUse int64, err *error) bool {
 	return true
 }
 
+// Try to deduct base plus perUnit for each of units, which are taken from
+// the stack so may be negative or make the cost overflow. Negative units set
+// err to outOfBounds; other errors are as for useGasNegative.
+func useGasPerUnit(gasLeft *int64, base, perUnit, units int64, outOfBounds error, err *error) bool {
+	if units < 0 {
+		*err = firstErr(*err, outOfBounds)
+		return true
+	}
+	if perUnit > 0 && units > (math.MaxInt64-base)/perUnit {
+		return useGasNegative(gasLeft, -1, err)
+	}
+	return useGasNegative(gasLeft, base+units*perUnit, err)
+}
+
+// Like useGasPerUnit for perWord for each word of size bytes
+func useGasPerWord(gasLeft *int64, base, perWord, size int64, outOfBounds error, err *error) bool {
+	if size < 0 {
+		*err = firstErr(*err, outOfBounds)
+		return true
+	}
+	// wordsFor would overflow for the largest sizes
+	words := size / 32
+	if size%32 != 0 {
+		words++
+	}
+	return useGasPerUnit(gasLeft, base, perWord, words, outOfBounds, err)
+}
+
 // Just like Call() but does not transfer 'value' or modify the callDepth.
 func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas *int64) (output []byte, err error) {
 	dbg.Printf("(%d) (%X) %X (code=%d) gas: %v (d) %X\n", vm.callDepth, caller.Address[:4], callee.Address, len(callee.Code), *gas, input)
 
 	var (
-		pc     int64 = 0
-		stack        = NewStack(dataStackCapacity, gas, &err)
-		memory       = make([]byte, memoryCapacity)
+		pc    int64 = 0
+		gs          = vm.gasSchedule
+		stack       = NewStack(dataStackCapacity, gs.StackOp, gas, &err)
+		// Grown by expandMemory as it is used
+		memory []byte
+		// The op being traced, if there is a tracer
+		step         *TraceStep
+		memoryBefore []byte
+		// The output of the most recent call made from this frame
+		returnData []byte
 	)
+	// Leave read-only mode as we found it, even if we bail out in the middle
+	// of a STATICCALL
+	defer func(readOnly bool) {
+		vm.readOnly = readOnly
+	}(vm.readOnly)
+	if vm.tracer != nil {
+		// End the trace of the last op however we leave
+		defer func() {
+			vm.endTraceStep(step, memoryBefore, memory, *gas, err)
+		}()
+	}
 
 	for {
-		// Use BaseOp gas.
-		if useGasNegative(gas, GasBaseOp, &err) {
-			return nil, err
+		var op = codeGetOp(code, pc)
+
+		if vm.tracer != nil {
+			vm.endTraceStep(step, memoryBefore, memory, *gas, nil)
+			step, memoryBefore = vm.startTraceStep(op, pc, *gas, stack, memory)
 		}
 
-		var op = codeGetOp(code, pc)
+		// Use BaseOp gas and the static cost of the op.
+		if useGasNegative(gas, gs.BaseOp+gs.Ops[op], &err) {
+			return nil, err
+		}
 		dbg.Printf("(pc) %-3d (op) %-14s (st) %-4d ", pc, op.String(), stack.Len())
 
 		switch op {
@@ -346,6 +547,9 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			x, y := stack.Pop(), stack.Pop()
 			xb := new(big.Int).SetBytes(x[:])
 			yb := new(big.Int).SetBytes(y[:])
+			if useGasNegative(gas, int64(len(yb.Bytes()))*gs.ExpByte, &err) {
+				return nil, err
+			}
 			pow := new(big.Int).Exp(xb, yb, big.NewInt(0))
 			res := LeftPadWord256(U256(pow).Bytes())
 			stack.Push(res)
@@ -483,11 +687,54 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			stack.Push64(int64(res))
 			dbg.Printf(" => 0x%X\n", res)
 
+		case SHL: // 0x1B
+			shift, x := stack.Pop(), stack.Pop()
+			if shiftOutOfRange(shift) {
+				stack.Push(Zero256)
+				dbg.Printf(" %X << %X = %v\n", x, shift, 0)
+			} else {
+				shiftb := uint(Uint64FromWord256(shift))
+				xb := new(big.Int).SetBytes(x[:])
+				shifted := new(big.Int).Lsh(xb, shiftb)
+				res := LeftPadWord256(U256(shifted).Bytes())
+				stack.Push(res)
+				dbg.Printf(" %v << %v = %v (%X)\n", xb, shiftb, shifted, res)
+			}
+
+		case SHR: // 0x1C
+			shift, x := stack.Pop(), stack.Pop()
+			if shiftOutOfRange(shift) {
+				stack.Push(Zero256)
+				dbg.Printf(" %X >> %X = %v\n", x, shift, 0)
+			} else {
+				shiftb := uint(Uint64FromWord256(shift))
+				xb := new(big.Int).SetBytes(x[:])
+				shifted := new(big.Int).Rsh(xb, shiftb)
+				res := LeftPadWord256(shifted.Bytes())
+				stack.Push(res)
+				dbg.Printf(" %v >> %v = %v (%X)\n", xb, shiftb, shifted, res)
+			}
+
+		case SAR: // 0x1D
+			shift, x := stack.Pop(), stack.Pop()
+			xb := S256(new(big.Int).SetBytes(x[:]))
+			// Shifting a negative number all the way leaves -1
+			shiftb := uint(255)
+			if !shiftOutOfRange(shift) {
+				shiftb = uint(Uint64FromWord256(shift))
+			}
+			// Rsh on big.Int rounds towards negative infinity so is arithmetic
+			shifted := new(big.Int).Rsh(xb, shiftb)
+			res := LeftPadWord256(U256(shifted).Bytes())
+			stack.Push(res)
+			dbg.Printf(" %v >> %v = %v (%X)\n", xb, shiftb, shifted, res)
+
 		case SHA3: // 0x20
-			if useGasNegative(gas, GasSha3, &err) {
+			offset, size := stack.Pop64(), stack.Pop64()
+			if useGasPerWord(gas, gs.Sha3, gs.Sha3Word, size, ErrMemoryOutOfBounds, &err) ||
+				vm.expandMemory(&memory, gas, offset, size, &err) {
 				return nil, err
 			}
-			offset, size := stack.Pop64(), stack.Pop64()
 			data, ok := subslice(memory, offset, size)
 			if !ok {
 				return nil, firstErr(err, ErrMemoryOutOfBounds)
@@ -502,7 +749,7 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 
 		case BALANCE: // 0x31
 			addr := stack.Pop()
-			if useGasNegative(gas, GasGetAccount, &err) {
+			if useGasNegative(gas, gs.GetAccount, &err) {
 				return nil, err
 			}
 			acc := vm.appState.GetAccount(addr)
@@ -543,6 +790,10 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			memOff := stack.Pop64()
 			inputOff := stack.Pop64()
 			length := stack.Pop64()
+			if useGasPerWord(gas, 0, gs.CopyWord, length, ErrMemoryOutOfBounds, &err) ||
+				vm.expandMemory(&memory, gas, memOff, length, &err) {
+				return nil, err
+			}
 			data, ok := subslice(input, inputOff, length)
 			if !ok {
 				return nil, firstErr(err, ErrInputOutOfBounds)
@@ -563,6 +814,10 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			memOff := stack.Pop64()
 			codeOff := stack.Pop64()
 			length := stack.Pop64()
+			if useGasPerWord(gas, 0, gs.CopyWord, length, ErrMemoryOutOfBounds, &err) ||
+				vm.expandMemory(&memory, gas, memOff, length, &err) {
+				return nil, err
+			}
 			data, ok := subslice(code, codeOff, length)
 			if !ok {
 				return nil, firstErr(err, ErrCodeOutOfBounds)
@@ -580,7 +835,7 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 
 		case EXTCODESIZE: // 0x3B
 			addr := stack.Pop()
-			if useGasNegative(gas, GasGetAccount, &err) {
+			if useGasNegative(gas, gs.GetAccount, &err) {
 				return nil, err
 			}
 			acc := vm.appState.GetAccount(addr)
@@ -598,7 +853,7 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			}
 		case EXTCODECOPY: // 0x3C
 			addr := stack.Pop()
-			if useGasNegative(gas, GasGetAccount, &err) {
+			if useGasNegative(gas, gs.GetAccount, &err) {
 				return nil, err
 			}
 			acc := vm.appState.GetAccount(addr)
@@ -613,6 +868,10 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			memOff := stack.Pop64()
 			codeOff := stack.Pop64()
 			length := stack.Pop64()
+			if useGasPerWord(gas, 0, gs.CopyWord, length, ErrMemoryOutOfBounds, &err) ||
+				vm.expandMemory(&memory, gas, memOff, length, &err) {
+				return nil, err
+			}
 			data, ok := subslice(code, codeOff, length)
 			if !ok {
 				return nil, firstErr(err, ErrCodeOutOfBounds)
@@ -624,9 +883,62 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			copy(dest, data)
 			dbg.Printf(" => [%v, %v, %v] %X\n", memOff, codeOff, length, data)
 
+		case RETURNDATASIZE: // 0x3D
+			stack.Push64(int64(len(returnData)))
+			dbg.Printf(" => %d\n", len(returnData))
+
+		case RETURNDATACOPY: // 0x3E
+			memOff := stack.Pop64()
+			outputOff := stack.Pop64()
+			length := stack.Pop64()
+			// Unlike the other copy operations reading beyond the end of the
+			// return data is an error rather than being zero-padded
+			if outputOff < 0 || length < 0 || length > int64(len(returnData))-outputOff {
+				return nil, firstErr(err, ErrReturnDataOutOfBounds)
+			}
+			if useGasPerWord(gas, 0, gs.CopyWord, length, ErrMemoryOutOfBounds, &err) ||
+				vm.expandMemory(&memory, gas, memOff, length, &err) {
+				return nil, err
+			}
+			data := returnData[outputOff : outputOff+length]
+			dest, ok := subslice(memory, memOff, length)
+			if !ok {
+				return nil, firstErr(err, ErrMemoryOutOfBounds)
+			}
+			copy(dest, data)
+			dbg.Printf(" => [%v, %v, %v] %X\n", memOff, outputOff, length, data)
+
+		case EXTCODEHASH: // 0x3F
+			addr := stack.Pop()
+			if useGasNegative(gas, gs.GetAccount, &err) {
+				return nil, err
+			}
+			acc := vm.appState.GetAccount(addr)
+			if acc == nil {
+				// Non-existent accounts (including native contracts, which have no
+				// code to hash) have a zero hash
+				stack.Push(Zero256)
+				dbg.Printf(" => 0x%X (no account)\n", Zero256)
+			} else {
+				if useGasNegative(gas, gs.Sha3+wordsFor(int64(len(acc.Code)))*gs.Sha3Word, &err) {
+					return nil, err
+				}
+				hash := LeftPadWord256(sha3.Sha3(acc.Code))
+				stack.Push(hash)
+				dbg.Printf(" => 0x%X\n", hash)
+			}
+
 		case BLOCKHASH: // 0x40
-			stack.Push(Zero256)
-			dbg.Printf(" => 0x%X (NOT SUPPORTED)\n", stack.Peek().Bytes())
+			number := stack.Pop()
+			hash := Zero256
+			// Only the hashes of the 256 blocks before this one are available
+			height := vm.params.BlockHeight
+			if vm.params.BlockHashes != nil && number.Compare(Int64ToWord256(height)) < 0 &&
+				Int64FromWord256(number) >= height-256 {
+				hash = vm.params.BlockHashes.GetBlockHash(Int64FromWord256(number))
+			}
+			stack.Push(hash)
+			dbg.Printf(" => 0x%X\n", hash)
 
 		case COINBASE: // 0x41
 			stack.Push(Zero256)
@@ -652,6 +964,9 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 
 		case MLOAD: // 0x51
 			offset := stack.Pop64()
+			if vm.expandMemory(&memory, gas, offset, 32, &err) {
+				return nil, err
+			}
 			data, ok := subslice(memory, offset, 32)
 			if !ok {
 				return nil, firstErr(err, ErrMemoryOutOfBounds)
@@ -661,6 +976,9 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 
 		case MSTORE: // 0x52
 			offset, data := stack.Pop64(), stack.Pop()
+			if vm.expandMemory(&memory, gas, offset, 32, &err) {
+				return nil, err
+			}
 			dest, ok := subslice(memory, offset, 32)
 			if !ok {
 				return nil, firstErr(err, ErrMemoryOutOfBounds)
@@ -670,6 +988,9 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 
 		case MSTORE8: // 0x53
 			offset, val := stack.Pop64(), byte(stack.Pop64()&0xFF)
+			if vm.expandMemory(&memory, gas, offset, 1, &err) {
+				return nil, err
+			}
 			if len(memory) <= int(offset) {
 				return nil, firstErr(err, ErrMemoryOutOfBounds)
 			}
@@ -679,15 +1000,41 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 		case SLOAD: // 0x54
 			loc := stack.Pop()
 			data := vm.appState.GetStorage(callee.Address, loc)
+			if step != nil {
+				step.Storage = append(step.Storage, core_types.StorageAccess{
+					Address: callee.Address,
+					Key:     loc,
+					Value:   data,
+				})
+			}
 			stack.Push(data)
 			dbg.Printf(" {0x%X : 0x%X}\n", loc, data)
 
 		case SSTORE: // 0x55
+			if vm.readOnly {
+				return nil, firstErr(err, ErrWriteProtection)
+			}
 			loc, data := stack.Pop(), stack.Pop()
-			if useGasNegative(gas, GasStorageUpdate, &err) {
+			current := vm.appState.GetStorage(callee.Address, loc)
+			storeGas := gs.SStoreReset
+			if current.IsZero() && !data.IsZero() {
+				storeGas = gs.SStoreSet
+			}
+			if useGasNegative(gas, storeGas, &err) {
 				return nil, err
 			}
+			if !current.IsZero() && data.IsZero() {
+				vm.refund += gs.SStoreClearRefund
+			}
 			vm.appState.SetStorage(callee.Address, loc, data)
+			if step != nil {
+				step.Storage = append(step.Storage, core_types.StorageAccess{
+					Address: callee.Address,
+					Key:     loc,
+					Value:   data,
+					Write:   true,
+				})
+			}
 			dbg.Printf(" {0x%X : 0x%X}\n", loc, data)
 
 		case JUMP: // 0x56
@@ -744,12 +1091,19 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			//stack.Print(10)
 
 		case LOG0, LOG1, LOG2, LOG3, LOG4:
+			if vm.readOnly {
+				return nil, firstErr(err, ErrWriteProtection)
+			}
 			n := int(op - LOG0)
 			topics := make([]Word256, n)
 			offset, size := stack.Pop64(), stack.Pop64()
 			for i := 0; i < n; i++ {
 				topics[i] = stack.Pop()
 			}
+			if useGasPerUnit(gas, int64(n)*gs.LogTopic, gs.LogDataByte, size, ErrMemoryOutOfBounds, &err) ||
+				vm.expandMemory(&memory, gas, offset, size, &err) {
+				return nil, err
+			}
 			data, ok := subslice(memory, offset, size)
 			if !ok {
 				return nil, firstErr(err, ErrMemoryOutOfBounds)
@@ -763,17 +1117,24 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 					topics,
 					data,
 					vm.params.BlockHeight,
+					nil,
 				}
 				vm.evc.FireEvent(eventID, log)
 			}
 			dbg.Printf(" => T:%X D:%X\n", topics, data)
 
-		case CREATE: // 0xF0
+		case CREATE, CREATE2: // 0xF0, 0xF5
+			if vm.readOnly {
+				return nil, firstErr(err, ErrWriteProtection)
+			}
 			if !HasPermission(vm.appState, callee, ptypes.CreateContract) {
 				return nil, ErrPermission{"create_contract"}
 			}
 			contractValue := stack.Pop64()
 			offset, size := stack.Pop64(), stack.Pop64()
+			if vm.expandMemory(&memory, gas, offset, size, &err) {
+				return nil, err
+			}
 			input, ok := subslice(memory, offset, size)
 			if !ok {
 				return nil, firstErr(err, ErrMemoryOutOfBounds)
@@ -784,21 +1145,47 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 				return nil, firstErr(err, ErrInsufficientBalance)
 			}
 
-			// TODO charge for gas to create account _ the code length * GasCreateByte
-
-			newAccount := vm.appState.CreateAccount(callee)
+			var newAccount *Account
+			if op == CREATE {
+				newAccount = vm.appState.CreateAccount(callee)
+			} else {
+				salt := stack.Pop()
+				// We need to hash the init code to find the address
+				if useGasPerWord(gas, gs.Sha3, gs.Sha3Word, size, ErrMemoryOutOfBounds, &err) {
+					return nil, err
+				}
+				newAccount = vm.appState.CreateAccount2(callee, salt, input)
+				if newAccount == nil {
+					// Address is already taken
+					dbg.Printf(" => address collision for salt %X\n", salt)
+					returnData = nil
+					stack.Push(Zero256)
+					break
+				}
+			}
 
 			// Run the input to get the contract code.
 			// NOTE: no need to copy 'input' as per Call contract.
+			revert := vm.snapshot()
 			ret, err_ := vm.Call(callee, newAccount, input, input, contractValue, gas)
+			// Only a reverted contract creation leaves data to return
+			returnData = nil
 			if err_ != nil {
+				if err_ == ErrExecutionReverted {
+					returnData = ret
+				}
+				stack.Push(Zero256)
+			} else if depositGas := int64(len(ret)) * gs.CreateDataByte; *gas < depositGas {
+				// Not enough gas left to pay for storing the code
+				revert()
 				stack.Push(Zero256)
 			} else {
+				*gas -= depositGas
 				newAccount.Code = ret // Set the code (ret need not be copied as per Call contract)
 				stack.Push(newAccount.Address)
 			}
 
-		case CALL, CALLCODE, DELEGATECALL: // 0xF1, 0xF2, 0xF4
+		case CALL, CALLCODE, DELEGATECALL, STATICCALL: // 0xF1, 0xF2, 0xF4, 0xFA
 			if !HasPermission(vm.appState, callee, ptypes.Call) {
 				return nil, ErrPermission{"call"}
 			}
@@ -808,14 +1195,25 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			// caller, as such it is not stored on stack as an argument
 			// for DELEGATECALL and should not be popped.  Instead previous
 			// caller value is used.  for CALL and CALLCODE value is stored
-			// on stack and needs to be overwritten from the given value.
-			if op != DELEGATECALL {
-				value = stack.Pop64()
+			// on stack and is popped into callValue, leaving the value of
+			// this frame for CALLVALUE. STATICCALL never transfers value.
+			callValue := value
+			if op == STATICCALL {
+				callValue = 0
+			} else if op != DELEGATECALL {
+				callValue = stack.Pop64()
+			}
+			if vm.readOnly && op == CALL && callValue != 0 {
+				return nil, firstErr(err, ErrWriteProtection)
 			}
 			inOffset, inSize := stack.Pop64(), stack.Pop64()   // inputs
 			retOffset, retSize := stack.Pop64(), stack.Pop64() // outputs
 			dbg.Printf(" => %X\n", addr)
 
+			if vm.expandMemory(&memory, gas, inOffset, inSize, &err) ||
+				vm.expandMemory(&memory, gas, retOffset, retSize, &err) {
+				return nil, err
+			}
 			// Get the arguments from the memory
 			args, ok := subslice(memory, inOffset, inSize)
 			if !ok {
@@ -823,20 +1221,57 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			}
 			args = copyslice(args)
 
-			// Ensure that gasLimit is reasonable
-			if *gas < gasLimit {
+			transfersValue := (op == CALL || op == CALLCODE) && callValue != 0
+			if transfersValue {
+				if useGasNegative(gas, gs.CallValueTransfer, &err) {
+					return nil, err
+				}
+			}
+
+			// Ensure that gasLimit is reasonable, and not negative, which
+			// would give this frame gas
+			if gasLimit < 0 || *gas < gasLimit {
 				return nil, firstErr(err, ErrInsufficientGas)
 			} else {
 				*gas -= gasLimit
 				// NOTE: we will return any used gas later.
 			}
+			// The callee gets a little gas for free when sent value
+			if transfersValue {
+				gasLimit += gs.CallStipend
+			}
+
+			// Everything called from within a STATICCALL is read-only too
+			readOnly := vm.readOnly
+			if op == STATICCALL {
+				vm.readOnly = true
+			}
 
 			// Begin execution
 			var ret []byte
 			var err error
 			if nativeContract := registeredNativeContracts[addr]; nativeContract != nil {
 				// Native contract
-				ret, err = nativeContract(vm.appState, callee, args, &gasLimit)
+				if useGasNegative(&gasLimit, gs.NativeContractGas(addr, args), &err) {
+					// Running out of gas fails the call to the native contract
+					// (using up its gas under schedules that say so) but not
+					// this frame
+					if gs.NativeOutOfGasBurnsGas {
+						gasLimit = 0
+					}
+				} else if vm.readOnly {
+					// SNatives and other native contracts cannot check for
+					// themselves so we hand them an AppState that drops writes,
+					// and a copy of the caller in case they change it in place
+					readOnlyAppState := newReadOnlyAppState(vm.appState)
+					calleeCopy := *callee
+					ret, err = nativeContract(readOnlyAppState, &calleeCopy, args, &gasLimit)
+					if err == nil && readOnlyAppState.written {
+						ret, err = nil, ErrWriteProtection
+					}
+				} else {
+					ret, err = nativeContract(vm.appState, callee, args, &gasLimit)
+				}
 
 				// for now we fire the Call event. maybe later we'll fire more particulars
 				var exception string
@@ -844,10 +1279,10 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 					exception = err.Error()
 				}
 				// NOTE: these fire call events and not particular events for eg name reg or permissions
-				vm.fireCallEvent(&exception, &ret, callee, &Account{Address: addr}, args, value, &gasLimit)
+				vm.fireCallEvent(&exception, &ret, callee, &Account{Address: addr}, args, callValue, &gasLimit)
 			} else {
 				// EVM contract
-				if useGasNegative(gas, GasGetAccount, &err) {
+				if useGasNegative(gas, gs.GetAccount, &err) {
 					return nil, err
 				}
 				acc := vm.appState.GetAccount(addr)
@@ -859,32 +1294,43 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 					if acc == nil {
 						return nil, firstErr(err, ErrUnknownAddress)
 					}
-					ret, err = vm.Call(callee, callee, acc.Code, args, value, &gasLimit)
+					ret, err = vm.Call(callee, callee, acc.Code, args, callValue, &gasLimit)
 				} else if op == DELEGATECALL {
 					if acc == nil {
 						return nil, firstErr(err, ErrUnknownAddress)
 					}
-					ret, err = vm.DelegateCall(caller, callee, acc.Code, args, value, &gasLimit)
+					ret, err = vm.DelegateCall(caller, callee, acc.Code, args, callValue, &gasLimit)
+				} else if acc == nil && vm.readOnly {
+					// A read-only call to an account that does not exist does nothing
+					// (and must not create it)
 				} else {
 					// nil account means we're sending funds to a new account
 					if acc == nil {
 						if !HasPermission(vm.appState, caller, ptypes.CreateAccount) {
 							return nil, ErrPermission{"create_account"}
 						}
+						if callValue != 0 && useGasNegative(gas, gs.CallNewAccount, &err) {
+							return nil, err
+						}
 						acc = &Account{Address: addr}
 					}
 					// add account to the tx cache
 					vm.appState.UpdateAccount(acc)
-					ret, err = vm.Call(callee, acc, acc.Code, args, value, &gasLimit)
+					ret, err = vm.Call(callee, acc, acc.Code, args, callValue, &gasLimit)
 				}
 			}
+			vm.readOnly = readOnly
 
 			// Push result
+			returnData = ret
 			if err != nil {
 				dbg.Printf("error on call: %s\n", err.Error())
 				stack.Push(Zero256)
 			} else {
 				stack.Push(One256)
+			}
+			// A reverted call still hands its output back to the caller
+			if err == nil || err == ErrExecutionReverted {
 				dest, ok := subslice(memory, retOffset, retSize)
 				if !ok {
 					return nil, firstErr(err, ErrMemoryOutOfBounds)
@@ -899,6 +1345,9 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 
 		case RETURN: // 0xF3
 			offset, size := stack.Pop64(), stack.Pop64()
+			if vm.expandMemory(&memory, gas, offset, size, &err) {
+				return nil, err
+			}
 			ret, ok := subslice(memory, offset, size)
 			if !ok {
 				return nil, firstErr(err, ErrMemoryOutOfBounds)
@@ -907,9 +1356,25 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 			output = copyslice(ret)
 			return output, nil
 
+		case REVERT: // 0xFD
+			offset, size := stack.Pop64(), stack.Pop64()
+			if vm.expandMemory(&memory, gas, offset, size, &err) {
+				return nil, err
+			}
+			ret, ok := subslice(memory, offset, size)
+			if !ok {
+				return nil, firstErr(err, ErrMemoryOutOfBounds)
+			}
+			dbg.Printf(" => [%v, %v] (%d) 0x%X\n", offset, size, len(ret), ret)
+			output = copyslice(ret)
+			return output, ErrExecutionReverted
+
 		case SUICIDE: // 0xFF
+			if vm.readOnly {
+				return nil, firstErr(err, ErrWriteProtection)
+			}
 			addr := stack.Pop()
-			if useGasNegative(gas, GasGetAccount, &err) {
+			if useGasNegative(gas, gs.GetAccount, &err) {
 				return nil, err
 			}
 			// TODO if the receiver is , then make it the fee. (?)
@@ -937,9 +1402,40 @@ func (vm *VM) call(caller, callee *Account, code, input []byte, value int64, gas
 	}
 }
 
+// Solidity encodes the reason given to revert(...) or require(..., ...) as
+// if it were a call to a function with the signature Error(string)
+var revertReasonSelector = sha3.Sha3([]byte("Error(string)"))[:4]
+
+// Decodes the reason string from the output of a reverted call. Returns false
+// if the output is not an ABI encoded Error(string).
+func RevertReason(output []byte) (string, bool) {
+	if len(output) < 4+2*32 || !bytes.Equal(output[:4], revertReasonSelector) {
+		return "", false
+	}
+	data := output[4:]
+	offset := Uint64FromWord256(LeftPadWord256(data[:32]))
+	if offset > uint64(len(data)-32) {
+		return "", false
+	}
+	length := Uint64FromWord256(LeftPadWord256(data[offset : offset+32]))
+	if length > uint64(len(data))-offset-32 {
+		return "", false
+	}
+	return string(data[offset+32 : offset+32+length]), true
+}
+
+// Whether a shift amount is at least 256, which shifts out every bit
+func shiftOutOfRange(shift Word256) bool {
+	return !IsZeros(shift[:31])
+}
+
 func subslice(data []byte, offset, length int64) (ret []byte, ok bool) {
 	size := int64(len(data))
-	if size < offset {
+	if length == 0 {
+		// Memory is only grown to cover non-empty accesses, which are all
+		// that need to be in bounds
+		return []byte{}, true
+	} else if size < offset {
 		return nil, false
 	} else if size < offset+length {
 		ret, ok = data[offset:], true
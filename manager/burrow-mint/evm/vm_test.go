@@ -18,6 +18,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -54,6 +55,8 @@ func newParams() Params {
 		BlockHash:   Zero256,
 		BlockTime:   0,
 		GasLimit:    0,
+		// Most tests here count gas as the legacy schedule charges it
+		GasSchedule: LegacyGasSchedule(),
 	}
 }
 
@@ -255,6 +258,229 @@ func TestDelegateCallGas(t *testing.T) {
 	assert.Error(t, err, "Should have insufficient funds for call")
 }
 
+// Test that REVERT rolls back storage changes made by the reverting frame but
+// still passes its output back to the caller
+func TestRevert(t *testing.T) {
+	appState := newAppState()
+	ourVm := NewVM(appState, newParams(), Zero256, nil)
+
+	key, value := LeftPadWord256([]byte("key")), LeftPadWord256([]byte("value"))
+	message := RightPadWord256([]byte("I'm not having that"))
+	calleeAccount, calleeAddress := makeAccountWithCode(appState, "callee",
+		Bytecode(PUSH32, value, PUSH32, key, SSTORE,
+			PUSH32, message, PUSH1, 0, MSTORE, PUSH1, 32, PUSH1, 0, REVERT))
+	callerAccount, _ := makeAccountWithCode(appState, "caller", nil)
+
+	var gas int64 = 100000
+	output, err := ourVm.Call(callerAccount, calleeAccount, calleeAccount.Code, []byte{}, 0, &gas)
+	assert.Equal(t, ErrExecutionReverted, err)
+	assert.Equal(t, message.Bytes(), output)
+	assert.Equal(t, Zero256, appState.GetStorage(calleeAccount.Address, key))
+
+	// Now call the reverting contract from another contract, whose own storage
+	// change should survive the revert
+	callerKey := LeftPadWord256([]byte("caller key"))
+	callerAccount.Code = Bytecode(PUSH1, 1, PUSH32, callerKey, SSTORE,
+		PUSH1, 32, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH20, calleeAddress,
+		PUSH2, 0xff, 0xff, CALL, POP, returnWord())
+	output, err = runVMWaitError(ourVm, callerAccount, callerAccount, calleeAddress,
+		callerAccount.Code, 100000)
+	assert.Equal(t, ErrExecutionReverted.Error(), err.Error())
+	assert.Equal(t, message.Bytes(), output)
+	assert.Equal(t, Zero256, appState.GetStorage(calleeAccount.Address, key))
+	assert.Equal(t, Int64ToWord256(1), appState.GetStorage(callerAccount.Address, callerKey))
+}
+
+func TestReturnData(t *testing.T) {
+	appState := newAppState()
+	ourVm := NewVM(appState, newParams(), Zero256, nil)
+
+	returnValue := int64(0x696969)
+	calleeAccount, calleeAddress := makeAccountWithCode(appState, "callee",
+		Bytecode(PUSH3, 0x69, 0x69, 0x69, return1()))
+	// Call with no space for output then copy it out of the return data buffer
+	callerCode := Bytecode(PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0,
+		PUSH20, calleeAddress, PUSH2, 0xff, 0xff, CALL, POP,
+		RETURNDATASIZE, PUSH1, 0, PUSH1, 0, RETURNDATACOPY, returnWord())
+	callerAccount, _ := makeAccountWithCode(appState, "caller", callerCode)
+
+	var gas int64 = 100000
+	output, err := ourVm.Call(callerAccount, callerAccount, callerCode, []byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(returnValue).Bytes(), output)
+
+	// Size of return data
+	callerCode = Bytecode(PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0,
+		PUSH20, calleeAddress, PUSH2, 0xff, 0xff, CALL, POP,
+		RETURNDATASIZE, return1())
+	output, err = ourVm.Call(callerAccount, callerAccount, callerCode, []byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(32).Bytes(), output)
+
+	// Reading past the end of the return data is an error
+	callerCode = Bytecode(PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0,
+		PUSH20, calleeAddress, PUSH2, 0xff, 0xff, CALL, POP,
+		PUSH1, 33, PUSH1, 0, PUSH1, 0, RETURNDATACOPY)
+	_, err = ourVm.Call(callerAccount, callerAccount, callerCode, []byte{}, 0, &gas)
+	assert.Equal(t, ErrReturnDataOutOfBounds, err)
+	// Even when the offset and length would overflow
+	callerCode = Bytecode(PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0, PUSH1, 0,
+		PUSH20, calleeAddress, PUSH2, 0xff, 0xff, CALL, POP,
+		PUSH1, 1, PUSH32, Int64ToWord256(math.MaxInt64), PUSH1, 0, RETURNDATACOPY)
+	_, err = ourVm.Call(callerAccount, callerAccount, callerCode, []byte{}, 0, &gas)
+	assert.Equal(t, ErrReturnDataOutOfBounds, err)
+
+	// No calls made yet
+	_, err = ourVm.Call(callerAccount, calleeAccount, Bytecode(RETURNDATASIZE, return1()),
+		[]byte{}, 0, &gas)
+	assert.NoError(t, err)
+}
+
+func TestRevertReason(t *testing.T) {
+	reason := "not enough ether"
+	output := Bytecode(revertReasonSelector, Int64ToWord256(32),
+		Int64ToWord256(int64(len(reason))), RightPadWord256([]byte(reason)))
+	decoded, ok := RevertReason(output)
+	assert.True(t, ok)
+	assert.Equal(t, reason, decoded)
+
+	_, ok = RevertReason(RightPadWord256([]byte(reason)).Bytes())
+	assert.False(t, ok)
+
+	// Length runs off the end
+	output = Bytecode(revertReasonSelector, Int64ToWord256(32),
+		Int64ToWord256(64), RightPadWord256([]byte(reason)))
+	_, ok = RevertReason(output)
+	assert.False(t, ok)
+}
+
+// Test that memory grows as it is used up to the limit given in the Params and
+// that exceeding it is reported as the exception of the call
+func TestMemoryLimit(t *testing.T) {
+	appState := newAppState()
+	params := newParams()
+	params.MemoryLimit = 64
+	ourVm := NewVM(appState, params, Zero256, nil)
+
+	// Writing the 34th byte grows memory to two words
+	account, address := makeAccountWithCode(appState, "memory",
+		Bytecode(PUSH1, 1, PUSH1, 33, MSTORE8, MSIZE, PUSH1, 0, MSTORE, returnWord()))
+	var gas int64 = 1000
+	output, err := ourVm.Call(account, account, account.Code, []byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(64).Bytes(), output)
+
+	// Writing a word at 64 needs a third
+	account.Code = Bytecode(PUSH1, 1, PUSH1, 64, MSTORE, STOP)
+	eventCh := make(chan txs.EventData)
+	_, err = runVM(eventCh, ourVm, account, account, address, account.Code, 1000)
+	expectedErr := ErrMemoryLimitExceeded{Limit: 64, Requested: 96}
+	assert.Equal(t, expectedErr, err)
+	assert.Equal(t, expectedErr.Error(), (<-eventCh).(txs.EventDataCall).Exception)
+}
+
+func TestStaticCall(t *testing.T) {
+	appState := newAppState()
+	ourVm := NewVM(appState, newParams(), Zero256, nil)
+	var gas int64 = 100000
+
+	key := LeftPadWord256([]byte("key"))
+	_, storerAddress := makeAccountWithCode(appState, "storer",
+		Bytecode(PUSH1, 1, PUSH32, key, SSTORE, PUSH1, 1, return1()))
+	_, loggerAddress := makeAccountWithCode(appState, "logger",
+		Bytecode(PUSH1, 0, PUSH1, 0, LOG0, PUSH1, 1, return1()))
+	_, readerAddress := makeAccountWithCode(appState, "reader",
+		Bytecode(PUSH32, key, SLOAD, return1()))
+	// Makes an ordinary CALL to the storer and returns whether it succeeded
+	_, middleAddress := makeAccountWithCode(appState, "middle",
+		Bytecode(callCode(CALL, storerAddress, nil), return1()))
+	callerAccount, _ := makeAccountWithCode(appState, "caller", nil)
+
+	// Writes fail whether directly or in a nested call
+	for _, address := range [][]byte{storerAddress, loggerAddress} {
+		output, err := ourVm.Call(callerAccount, callerAccount,
+			Bytecode(callCode(STATICCALL, address, nil), return1()), []byte{}, 0, &gas)
+		assert.NoError(t, err)
+		assert.Equal(t, Zero256.Bytes(), output)
+	}
+	output, err := runVMWaitError(ourVm, callerAccount, callerAccount, storerAddress,
+		Bytecode(callCode(STATICCALL, middleAddress, nil), PUSH1, 32, PUSH1, 0, RETURN), gas)
+	assert.Equal(t, ErrWriteProtection.Error(), err.Error())
+	assert.Equal(t, Zero256.Bytes(), output)
+	assert.Equal(t, Zero256, appState.GetStorage(LeftPadWord256(storerAddress), key))
+	// But we are not stuck in read-only mode afterwards
+	output, err = ourVm.Call(callerAccount, callerAccount,
+		Bytecode(callCode(STATICCALL, middleAddress, nil), callCode(CALL, storerAddress, nil),
+			return1()), []byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, One256.Bytes(), output)
+
+	// Reads are fine
+	output, err = ourVm.Call(callerAccount, callerAccount,
+		Bytecode(callCode(STATICCALL, readerAddress, nil), return1()), []byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, One256.Bytes(), output)
+
+	// And leave the value of the calling frame alone
+	callerAccount.Balance = 100
+	output, err = ourVm.Call(callerAccount, callerAccount,
+		Bytecode(callCode(STATICCALL, readerAddress, nil), POP, CALLVALUE, return1()), []byte{}, 5, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, Int64ToWord256(5).Bytes(), output)
+
+	// And the same goes for SNatives
+	permissions := SNativeContracts()["Permissions"]
+	callerAccount.Permissions = allAccountPermissions()
+	addRole, _ := permissions.FunctionByName("addRole")
+	hasRole, _ := permissions.FunctionByName("hasRole")
+	addRoleID, hasRoleID := addRole.ID(), hasRole.ID()
+	role := RightPadWord256([]byte("chuckle"))
+	output, err = ourVm.Call(callerAccount, callerAccount,
+		Bytecode(callCode(STATICCALL, permissions.AddressBytes(),
+			Bytecode(addRoleID[:], callerAccount.Address, role)), return1()),
+		[]byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, Zero256.Bytes(), output)
+	assert.False(t, callerAccount.Permissions.HasRole("chuckle"))
+	output, err = ourVm.Call(callerAccount, callerAccount,
+		Bytecode(callCode(STATICCALL, permissions.AddressBytes(),
+			Bytecode(hasRoleID[:], callerAccount.Address, role)), return1()),
+		[]byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, One256.Bytes(), output)
+
+	// Including those that pay out of the caller's balance
+	validators := SNativeContracts()["Validators"]
+	bond, _ := validators.FunctionByName("bond")
+	bondID := bond.ID()
+	callerAccount.Balance = 1000
+	output, err = ourVm.Call(callerAccount, callerAccount,
+		Bytecode(callCode(STATICCALL, validators.AddressBytes(),
+			Bytecode(bondID[:], role, Int64ToWord256(100), Int64ToWord256(96), Int64ToWord256(64),
+				Zero256, Zero256)), return1()),
+		[]byte{}, 0, &gas)
+	assert.NoError(t, err)
+	assert.Equal(t, Zero256.Bytes(), output)
+	assert.Equal(t, int64(1000), callerAccount.Balance)
+}
+
+// Calls address with input using the given CALL-like opcode (with zero value
+// and almost all our gas) leaving the success flag on the stack and any output
+// in memory at 0
+func callCode(op OpCode, address []byte, input []byte) []byte {
+	// Put the input into memory a word at a time
+	code := []byte{}
+	for i := 0; i < len(input); i += 32 {
+		code = Bytecode(code, PUSH32, RightPadWord256(input[i:]), PUSH1, i, MSTORE)
+	}
+	if op == STATICCALL || op == DELEGATECALL {
+		return Bytecode(code, PUSH1, 32, PUSH1, 0, PUSH1, len(input), PUSH1, 0,
+			PUSH20, address, PUSH1, 100, GAS, SUB, op)
+	}
+	return Bytecode(code, PUSH1, 32, PUSH1, 0, PUSH1, len(input), PUSH1, 0,
+		PUSH1, 0, PUSH20, address, PUSH1, 100, GAS, SUB, op)
+}
+
 // Store the top element of the stack (which is a 32-byte word) in memory
 // and return it. Useful for a simple return value.
 func return1() []byte {
@@ -346,6 +572,45 @@ func callContractCode(addr []byte) []byte {
 		PUSH1, retOff, RETURN)
 }
 
+// Block hashes by height
+type blockHashes map[int64]Word256
+
+func (bh blockHashes) GetBlockHash(height int64) Word256 {
+	return bh[height]
+}
+
+func TestBlockHash(t *testing.T) {
+	appState := newAppState()
+	params := newParams()
+	params.BlockHeight = 300
+	params.BlockHashes = blockHashes{
+		43:  Int64ToWord256(43),
+		44:  Int64ToWord256(44),
+		299: Int64ToWord256(299),
+		300: Int64ToWord256(300),
+	}
+	ourVm := NewVM(appState, params, Zero256, nil)
+	// Beyond int64 but with the low bytes of a known height
+	huge := Int64ToWord256(299)
+	huge[0] = 1
+
+	for number, expected := range map[Word256]Word256{
+		Int64ToWord256(299): Int64ToWord256(299),
+		Int64ToWord256(44):  Int64ToWord256(44),
+		// Only the 256 blocks before this one are available
+		Int64ToWord256(43):  Zero256,
+		Int64ToWord256(300): Zero256,
+		huge:                Zero256,
+	} {
+		account, _ := makeAccountWithCode(appState, "blockhash",
+			Bytecode(PUSH32, number, BLOCKHASH, PUSH1, 0, MSTORE, returnWord()))
+		var gas int64 = 1000
+		output, err := ourVm.Call(account, account, account.Code, []byte{}, 0, &gas)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.Bytes(), output, "BLOCKHASH of %X", number)
+	}
+}
+
 func TestBytecode(t *testing.T) {
 	assert.Equal(t,
 		Bytecode(1, 2, 3, 4, 5, 6),
@@ -75,6 +75,7 @@ func NewBurrowMintPipe(moduleConfig *config.ModuleConfig,
 	if err != nil {
 		return nil, fmt.Errorf("Failed to start state: %v", err)
 	}
+	startedState.SetHistoryBlocks(moduleConfig.Config.GetInt("state_history_blocks"))
 	logger = logging.WithScope(logger, "BurrowMintPipe")
 	// assert ChainId matches genesis ChainId
 	logging.InfoMsg(logger, "Loaded state",
@@ -85,7 +86,7 @@ func NewBurrowMintPipe(moduleConfig *config.ModuleConfig,
 	burrowMint := NewBurrowMint(startedState, eventSwitch, logger)
 
 	// initialise the components of the pipe
-	events := edb_event.NewEvents(eventSwitch, logger)
+	events := newDecodingEvents(edb_event.NewEvents(eventSwitch, logger), burrowMint)
 	accounts := newAccounts(burrowMint)
 	namereg := newNameReg(burrowMint)
 
@@ -119,10 +120,7 @@ func NewBurrowMintPipe(moduleConfig *config.ModuleConfig,
 	// aware of/depend on Pipe.
 	transactor := newTransactor(moduleConfig.ChainId, eventSwitch, burrowMint,
 		events,
-		func(tx txs.Tx) error {
-			_, err := pipe.BroadcastTxSync(tx)
-			return err
-		})
+		pipe.BroadcastTxSync)
 
 	pipe.transactor = transactor
 	return pipe, nil
@@ -256,6 +254,12 @@ func (pipe *burrowMintPipe) Subscribe(event string,
 	}
 	pipe.consensusAndManagerEvents().Subscribe(subscriptionId, event,
 		func(eventData txs.EventData) {
+			if newBlock, ok := eventData.(txs.EventDataNewBlock); ok && newBlock.Block != nil {
+				// The block is committed before the event fires, so its fees
+				// are in the state
+				newBlock.Fees = pipe.burrowMint.GetState().GetBlockFees(newBlock.Block.Height)
+				eventData = newBlock
+			}
 			result := rpc_tm_types.BurrowResult(&rpc_tm_types.ResultEvent{event,
 				txs.EventData(eventData)})
 			// NOTE: EventSwitch callbacks must be nonblocking
@@ -343,18 +347,45 @@ func (pipe *burrowMintPipe) Genesis() (*rpc_tm_types.ResultGenesis, error) {
 	}, nil
 }
 
+// Returns the state as it was after committing the block at height, or the
+// latest state for height 0
+func (pipe *burrowMintPipe) stateAtHeight(height int) (*state.State, error) {
+	st := pipe.burrowMint.GetState()
+	if height == 0 {
+		return st, nil
+	}
+	if height < 0 || height > st.LastBlockHeight {
+		return nil, fmt.Errorf("No block has been committed at height %v", height)
+	}
+	historicalState := state.LoadStateAtHeight(st.DB, height)
+	if historicalState == nil {
+		return nil, fmt.Errorf("The state at height %v is no longer retained", height)
+	}
+	return historicalState, nil
+}
+
 // Accounts
-func (pipe *burrowMintPipe) GetAccount(address []byte) (*rpc_tm_types.ResultGetAccount,
+func (pipe *burrowMintPipe) GetAccount(address []byte, height int) (*rpc_tm_types.ResultGetAccount,
 	error) {
-	cache := pipe.burrowMint.GetCheckCache()
-	account := cache.GetAccount(address)
-	return &rpc_tm_types.ResultGetAccount{Account: account}, nil
+	if height == 0 {
+		cache := pipe.burrowMint.GetCheckCache()
+		account := cache.GetAccount(address)
+		return &rpc_tm_types.ResultGetAccount{Account: account}, nil
+	}
+	state, err := pipe.stateAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc_tm_types.ResultGetAccount{Account: state.GetAccount(address)}, nil
 }
 
-func (pipe *burrowMintPipe) ListAccounts() (*rpc_tm_types.ResultListAccounts, error) {
+func (pipe *burrowMintPipe) ListAccounts(height int) (*rpc_tm_types.ResultListAccounts, error) {
 	var blockHeight int
 	var accounts []*account.Account
-	state := pipe.burrowMint.GetState()
+	state, err := pipe.stateAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
 	blockHeight = state.LastBlockHeight
 	state.GetAccounts().Iterate(func(key []byte, value []byte) bool {
 		accounts = append(accounts, account.DecodeAccount(value))
@@ -363,10 +394,12 @@ func (pipe *burrowMintPipe) ListAccounts() (*rpc_tm_types.ResultListAccounts, er
 	return &rpc_tm_types.ResultListAccounts{blockHeight, accounts}, nil
 }
 
-func (pipe *burrowMintPipe) GetStorage(address, key []byte) (*rpc_tm_types.ResultGetStorage,
+func (pipe *burrowMintPipe) GetStorage(address, key []byte, height int) (*rpc_tm_types.ResultGetStorage,
 	error) {
-	state := pipe.burrowMint.GetState()
-	// state := consensusState.GetState()
+	state, err := pipe.stateAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
 	account := state.GetAccount(address)
 	if account == nil {
 		return nil, fmt.Errorf("UnknownAddress: %X", address)
@@ -383,9 +416,12 @@ func (pipe *burrowMintPipe) GetStorage(address, key []byte) (*rpc_tm_types.Resul
 	return &rpc_tm_types.ResultGetStorage{key, value}, nil
 }
 
-func (pipe *burrowMintPipe) DumpStorage(address []byte) (*rpc_tm_types.ResultDumpStorage,
+func (pipe *burrowMintPipe) DumpStorage(address []byte, height int) (*rpc_tm_types.ResultDumpStorage,
 	error) {
-	state := pipe.burrowMint.GetState()
+	state, err := pipe.stateAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
 	account := state.GetAccount(address)
 	if account == nil {
 		return nil, fmt.Errorf("UnknownAddress: %X", address)
@@ -405,9 +441,12 @@ func (pipe *burrowMintPipe) DumpStorage(address []byte) (*rpc_tm_types.ResultDum
 // NOTE: this function is used from 46657 and has sibling on 1337
 // in transactor.go
 // TODO: [ben] resolve incompatibilities in byte representation for 0.12.0 release
-func (pipe *burrowMintPipe) Call(fromAddress, toAddress, data []byte) (*rpc_tm_types.ResultCall,
+func (pipe *burrowMintPipe) Call(fromAddress, toAddress, data []byte, height int) (*rpc_tm_types.ResultCall,
 	error) {
-	st := pipe.burrowMint.GetState()
+	st, err := pipe.stateAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
 	cache := state.NewBlockCache(st)
 	outAcc := cache.GetAccount(toAddress)
 	if outAcc == nil {
@@ -420,23 +459,25 @@ func (pipe *burrowMintPipe) Call(fromAddress, toAddress, data []byte) (*rpc_tm_t
 	caller := &vm.Account{Address: word256.LeftPadWord256(fromAddress)}
 	txCache := state.NewTxCache(cache)
 	gasLimit := st.GetGasLimit()
-	params := vm.Params{
-		BlockHeight: int64(st.LastBlockHeight),
-		BlockHash:   word256.LeftPadWord256(st.LastBlockHash),
-		BlockTime:   st.LastBlockTime.Unix(),
-		GasLimit:    gasLimit,
-	}
+	params := st.VMParams()
 
 	vmach := vm.NewVM(txCache, params, caller.Address, nil)
 	gas := gasLimit
 	ret, err := vmach.Call(caller, callee, callee.Code, data, 0, &gas)
-	if err != nil {
+	if err != nil && err != vm.ErrExecutionReverted {
 		return nil, err
 	}
 	gasUsed := gasLimit - gas
 	// here return bytes are not hex encoded; on the sibling function
 	// they are
-	return &rpc_tm_types.ResultCall{Return: ret, GasUsed: gasUsed}, nil
+	result := &rpc_tm_types.ResultCall{Return: ret, GasUsed: gasUsed}
+	if err != nil {
+		result.Exception = err.Error()
+		result.RevertReason, _ = vm.RevertReason(ret)
+		ret = nil
+	}
+	result.Decoded = decodeCall(st, toAddress, data, ret)
+	return result, nil
 }
 
 func (pipe *burrowMintPipe) CallCode(fromAddress, code, data []byte) (*rpc_tm_types.ResultCall,
@@ -447,21 +488,123 @@ func (pipe *burrowMintPipe) CallCode(fromAddress, code, data []byte) (*rpc_tm_ty
 	caller := &vm.Account{Address: word256.LeftPadWord256(fromAddress)}
 	txCache := state.NewTxCache(cache)
 	gasLimit := st.GetGasLimit()
-	params := vm.Params{
-		BlockHeight: int64(st.LastBlockHeight),
-		BlockHash:   word256.LeftPadWord256(st.LastBlockHash),
-		BlockTime:   st.LastBlockTime.Unix(),
-		GasLimit:    gasLimit,
-	}
+	params := st.VMParams()
 
 	vmach := vm.NewVM(txCache, params, caller.Address, nil)
 	gas := gasLimit
 	ret, err := vmach.Call(caller, callee, code, data, 0, &gas)
-	if err != nil {
+	if err != nil && err != vm.ErrExecutionReverted {
 		return nil, err
 	}
 	gasUsed := gasLimit - gas
-	return &rpc_tm_types.ResultCall{Return: ret, GasUsed: gasUsed}, nil
+	result := &rpc_tm_types.ResultCall{Return: ret, GasUsed: gasUsed}
+	if err != nil {
+		result.Exception = err.Error()
+		result.RevertReason, _ = vm.RevertReason(ret)
+	}
+	return result, nil
+}
+
+func (pipe *burrowMintPipe) TraceCall(fromAddress, toAddress, data []byte) (
+	*rpc_tm_types.ResultTrace, error) {
+	trace, err := pipe.transactor.TraceCall(fromAddress, toAddress, data)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc_tm_types.ResultTrace{Trace: trace}, nil
+}
+
+func (pipe *burrowMintPipe) TraceTx(tx txs.Tx) (*rpc_tm_types.ResultTrace, error) {
+	trace, err := pipe.transactor.TraceTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc_tm_types.ResultTrace{Trace: trace}, nil
+}
+
+func (pipe *burrowMintPipe) EstimateGas(fromAddress, toAddress, data []byte) (
+	*rpc_tm_types.ResultEstimateGas, error) {
+	gasLimit, err := pipe.transactor.EstimateGas(fromAddress, toAddress, data)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc_tm_types.ResultEstimateGas{GasLimit: gasLimit}, nil
+}
+
+func (pipe *burrowMintPipe) GetTxReceipt(txHash []byte) (
+	*rpc_tm_types.ResultGetTxReceipt, error) {
+	receipt, err := pipe.transactor.TxReceipt(txHash)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc_tm_types.ResultGetTxReceipt{Receipt: receipt}, nil
+}
+
+func (pipe *burrowMintPipe) SearchTxs(filter *txs.TxFilter) (
+	*rpc_tm_types.ResultSearchTxs, error) {
+	result, err := pipe.transactor.SearchTxs(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc_tm_types.ResultSearchTxs{Total: result.Total, Receipts: result.Receipts}, nil
+}
+
+func (pipe *burrowMintPipe) GetLogs(filter *txs.LogFilter) (
+	*rpc_tm_types.ResultGetLogs, error) {
+	result, err := pipe.transactor.GetLogs(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &rpc_tm_types.ResultGetLogs{Logs: result.Logs, More: result.More}, nil
+}
+
+// Re-executes the tx with hash txHash in the block at height against the
+// state it originally ran on, after the txs before it in the block. Nothing
+// is persisted.
+func (pipe *burrowMintPipe) ReplayTx(height int, txHash []byte,
+	trace bool) (*rpc_tm_types.ResultReplayTx, error) {
+	if pipe.blockchain == nil {
+		return nil, fmt.Errorf("Blockchain not initialised in burrowmint pipe.")
+	}
+	if height < 1 || height > pipe.blockchain.Height() {
+		return nil, fmt.Errorf("No block has been committed at height %v", height)
+	}
+	st := state.LoadStateAtHeight(pipe.burrowMint.GetState().DB, height-1)
+	if st == nil {
+		return nil, fmt.Errorf("No state was saved for height %v", height-1)
+	}
+	block := pipe.blockchain.Block(height)
+	st.BeginBlock(block.Hash(), block.Time)
+	blockCache := state.NewBlockCache(st) // XXX: DON'T SYNC THIS CACHE
+	for _, txBytes := range block.Txs {
+		tx, err := txs.DecodeTx(txBytes)
+		if err != nil {
+			// DeliverTx skipped it too
+			continue
+		}
+		if !bytes.Equal(txs.TxHash(st.ChainID, tx), txHash) {
+			// DeliverTx ignored the errors of the txs before ours as well
+			state.ExecTx(blockCache, tx, true, nil)
+			continue
+		}
+		recorder := new(eventRecorder)
+		var structLogger *vm.StructLogger
+		var tracer vm.Tracer
+		if trace {
+			structLogger = vm.NewStructLogger(maxTraceSteps)
+			tracer = structLogger
+		}
+		callResult, err := state.TraceTx(blockCache, tx, recorder, tracer)
+		if err != nil {
+			return nil, err
+		}
+		result := &rpc_tm_types.ResultReplayTx{Height: height, Events: recorder.events}
+		if callResult != nil {
+			result.Trace = newCallTrace(callResult, structLogger)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("Transaction %X is not in the block at height %v", txHash, height)
 }
 
 // TODO: [ben] deprecate as we should not allow unsafe behaviour
@@ -487,6 +630,10 @@ func (pipe *burrowMintPipe) SignTransaction(tx txs.Tx,
 		callTx := tx.(*txs.CallTx)
 		callTx.Input.PubKey = privAccounts[0].PubKey
 		callTx.Input.Signature = privAccounts[0].Sign(pipe.transactor.chainID, callTx)
+	case *txs.LegacyCallTx:
+		callTx := tx.(*txs.LegacyCallTx)
+		callTx.Input.PubKey = privAccounts[0].PubKey
+		callTx.Input.Signature = privAccounts[0].Sign(pipe.transactor.chainID, callTx)
 	case *txs.BondTx:
 		bondTx := tx.(*txs.BondTx)
 		// the first privaccount corresponds to the BondTx pub key.
@@ -507,8 +654,11 @@ func (pipe *burrowMintPipe) SignTransaction(tx txs.Tx,
 }
 
 // Name registry
-func (pipe *burrowMintPipe) GetName(name string) (*rpc_tm_types.ResultGetName, error) {
-	currentState := pipe.burrowMint.GetState()
+func (pipe *burrowMintPipe) GetName(name string, height int) (*rpc_tm_types.ResultGetName, error) {
+	currentState, err := pipe.stateAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
 	entry := currentState.GetNameRegEntry(name)
 	if entry == nil {
 		return nil, fmt.Errorf("Name %s not found", name)
@@ -516,10 +666,13 @@ func (pipe *burrowMintPipe) GetName(name string) (*rpc_tm_types.ResultGetName, e
 	return &rpc_tm_types.ResultGetName{entry}, nil
 }
 
-func (pipe *burrowMintPipe) ListNames() (*rpc_tm_types.ResultListNames, error) {
+func (pipe *burrowMintPipe) ListNames(height int) (*rpc_tm_types.ResultListNames, error) {
 	var blockHeight int
 	var names []*core_types.NameRegEntry
-	currentState := pipe.burrowMint.GetState()
+	currentState, err := pipe.stateAtHeight(height)
+	if err != nil {
+		return nil, err
+	}
 	blockHeight = currentState.LastBlockHeight
 	currentState.GetNames().Iterate(func(key []byte, value []byte) bool {
 		names = append(names, state.DecodeNameRegEntry(value))
@@ -567,26 +720,20 @@ func (pipe *burrowMintPipe) BroadcastTxSync(tx txs.Tx) (*rpc_tm_types.ResultBroa
 	if responseCheckTx == nil {
 		return nil, fmt.Errorf("Error, application did not return CheckTx response.")
 	}
-	resultBroadCastTx := &rpc_tm_types.ResultBroadcastTx{
-		Code: responseCheckTx.Code,
-		Data: responseCheckTx.Data,
-		Log:  responseCheckTx.Log,
-	}
-	switch responseCheckTx.Code {
-	case abci_types.CodeType_OK:
-		return resultBroadCastTx, nil
-	case abci_types.CodeType_EncodingError:
-		return resultBroadCastTx, fmt.Errorf(resultBroadCastTx.Log)
-	case abci_types.CodeType_InternalError:
-		return resultBroadCastTx, fmt.Errorf(resultBroadCastTx.Log)
-	default:
-		logging.InfoMsg(pipe.logger, "Unknown error returned from Tendermint CheckTx on BroadcastTxSync",
-			"application", GetBurrowMintVersion().GetVersionString(),
+	// A tx rejected by CheckTx is not an error of the broadcast: the code of
+	// the result says why it was rejected and its data is the tx's receipt
+	// carrying the same code
+	if responseCheckTx.Code != abci_types.CodeType_OK {
+		logging.InfoMsg(pipe.logger, "Transaction rejected by CheckTx on BroadcastTxSync",
 			"abci_code_type", responseCheckTx.Code,
 			"abci_log", responseCheckTx.Log,
 		)
-		return resultBroadCastTx, fmt.Errorf("Unknown error returned: " + responseCheckTx.Log)
 	}
+	return &rpc_tm_types.ResultBroadcastTx{
+		Code: responseCheckTx.Code,
+		Data: responseCheckTx.Data,
+		Log:  responseCheckTx.Log,
+	}, nil
 }
 
 func (pipe *burrowMintPipe) ListUnconfirmedTxs(maxTxs int) (*rpc_tm_types.ResultListUnconfirmedTxs, error) {
@@ -666,3 +813,17 @@ func (pipe *burrowMintPipe) DumpConsensusState() (*rpc_tm_types.ResultDumpConsen
 	}
 	return &dump, nil
 }
+
+//------------------------------------------------------------------------------
+// Helper functions
+
+// Keeps the events fired at it in order
+type eventRecorder struct {
+	events []*rpc_tm_types.ResultEvent
+}
+
+func (er *eventRecorder) FireEvent(event string, data go_events.EventData) {
+	if eventData, ok := data.(txs.EventData); ok {
+		er.events = append(er.events, &rpc_tm_types.ResultEvent{Event: event, Data: eventData})
+	}
+}
@@ -41,15 +41,26 @@ type BlockCache struct {
 	accounts map[string]accountInfo
 	storages map[Tuple256]storageInfo
 	names    map[string]nameInfo
+	// Validator infos changed since they were last synced, which are the
+	// changes to the validator set
+	validatorInfos map[string]*ValidatorInfo
+	// Fees taken by the txs run since the fees were last distributed
+	fees int64
+	// Creators of the contracts created since the cache was last synced, by
+	// the address of the contract
+	creators map[string][]byte
 }
 
 func NewBlockCache(backend *State) *BlockCache {
 	return &BlockCache{
-		db:       backend.DB,
+		db:       backend.treeDB,
 		backend:  backend,
 		accounts: make(map[string]accountInfo),
 		storages: make(map[Tuple256]storageInfo),
 		names:    make(map[string]nameInfo),
+
+		validatorInfos: make(map[string]*ValidatorInfo),
+		creators:       make(map[string][]byte),
 	}
 }
 
@@ -164,6 +175,63 @@ func (cache *BlockCache) RemoveNameRegEntry(name string) {
 
 // BlockCache.names
 //-------------------------------------
+// BlockCache.validators
+
+func (cache *BlockCache) GetValidatorInfo(address []byte) *ValidatorInfo {
+	if valInfo, ok := cache.validatorInfos[string(address)]; ok {
+		return valInfo
+	}
+	return cache.backend.GetValidatorInfo(address)
+}
+
+func (cache *BlockCache) UpdateValidatorInfo(valInfo *ValidatorInfo) {
+	cache.validatorInfos[string(valInfo.Address)] = valInfo
+}
+
+// Returns the validator infos updated since the last Sync, in order of address
+func (cache *BlockCache) ValidatorInfoUpdates() []*ValidatorInfo {
+	addrStrs := make([]string, 0, len(cache.validatorInfos))
+	for addrStr := range cache.validatorInfos {
+		addrStrs = append(addrStrs, addrStr)
+	}
+	sort.Strings(addrStrs)
+	valInfos := make([]*ValidatorInfo, len(addrStrs))
+	for i, addrStr := range addrStrs {
+		valInfos[i] = cache.validatorInfos[addrStr]
+	}
+	return valInfos
+}
+
+// BlockCache.validators
+//-------------------------------------
+// BlockCache.fees
+
+func (cache *BlockCache) AddFee(fee int64) {
+	cache.fees += fee
+}
+
+// The fees taken by the txs run since the fees were last distributed
+func (cache *BlockCache) Fees() int64 {
+	return cache.fees
+}
+
+// BlockCache.fees
+//-------------------------------------
+// BlockCache.creators
+
+func (cache *BlockCache) GetContractCreator(addr []byte) []byte {
+	if creator, ok := cache.creators[string(addr)]; ok {
+		return creator
+	}
+	return cache.backend.GetContractCreator(addr)
+}
+
+func (cache *BlockCache) SetContractCreator(addr, creator []byte) {
+	cache.creators[string(addr)] = creator
+}
+
+// BlockCache.creators
+//-------------------------------------
 
 // CONTRACT the updates are in deterministic order.
 func (cache *BlockCache) Sync() {
@@ -269,6 +337,23 @@ func (cache *BlockCache) Sync() {
 		}
 	}
 
+	// Update validator infos, after which they are no longer updates
+	for _, valInfo := range cache.ValidatorInfoUpdates() {
+		cache.backend.UpdateValidatorInfo(valInfo)
+	}
+	cache.validatorInfos = make(map[string]*ValidatorInfo)
+
+	// Set in order, since the shape of the tree depends on it
+	creatorAddrs := make([]string, 0, len(cache.creators))
+	for addrStr := range cache.creators {
+		creatorAddrs = append(creatorAddrs, addrStr)
+	}
+	sort.Strings(creatorAddrs)
+	for _, addrStr := range creatorAddrs {
+		cache.backend.setContractCreator([]byte(addrStr), cache.creators[addrStr])
+	}
+	cache.creators = make(map[string][]byte)
+
 }
 
 //-----------------------------------------------------------------------------
@@ -0,0 +1,114 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/burrow/txs"
+
+	abci "github.com/tendermint/abci/types"
+)
+
+// The kinds of error ExecTx returns, so that they can be told apart without
+// parsing their messages. Each is reported with its own ABCI code.
+type ErrorCode int
+
+const (
+	// Anything not classified below
+	ErrorCodeGeneric ErrorCode = iota
+	ErrorCodeUnknownAddress
+	ErrorCodeDuplicateAddress
+	// Malformed or out of range fields of the tx
+	ErrorCodeInvalidInput
+	ErrorCodeInvalidPubKey
+	ErrorCodeUnknownPubKey
+	ErrorCodeInvalidSignature
+	ErrorCodeInvalidSequence
+	ErrorCodeInsufficientFunds
+	ErrorCodeInsufficientFee
+	ErrorCodeInsufficientGasPrice
+	ErrorCodePermissionDenied
+)
+
+// ABCI has no code for a CallTx that ran but whose call the VM threw on. The
+// tx still takes its fee and gas, so it is not an error of ExecTx.
+const CodeTypeVMException abci.CodeType = 1000
+
+var abciCodes = map[ErrorCode]abci.CodeType{
+	ErrorCodeGeneric:              abci.CodeType_InternalError,
+	ErrorCodeUnknownAddress:       abci.CodeType_BaseUnknownAddress,
+	ErrorCodeDuplicateAddress:     abci.CodeType_BaseDuplicateAddress,
+	ErrorCodeInvalidInput:         abci.CodeType_BaseInvalidInput,
+	ErrorCodeInvalidPubKey:        abci.CodeType_BaseInvalidPubKey,
+	ErrorCodeUnknownPubKey:        abci.CodeType_BaseUnknownPubKey,
+	ErrorCodeInvalidSignature:     abci.CodeType_BaseInvalidSignature,
+	ErrorCodeInvalidSequence:      abci.CodeType_BaseInvalidSequence,
+	ErrorCodeInsufficientFunds:    abci.CodeType_BaseInsufficientFunds,
+	ErrorCodeInsufficientFee:      abci.CodeType_BaseInsufficientFees,
+	ErrorCodeInsufficientGasPrice: abci.CodeType_BaseInsufficientGasPrice,
+	ErrorCodePermissionDenied:     abci.CodeType_Unauthorized,
+}
+
+func (code ErrorCode) ABCICode() abci.CodeType {
+	return abciCodes[code]
+}
+
+// An error of ExecTx of a kind not told by its type alone
+type ExecError struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e ExecError) Error() string {
+	return e.Err.Error()
+}
+
+func execErrorf(code ErrorCode, format string, a ...interface{}) ExecError {
+	return ExecError{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// Returns the kind of an error returned by ExecTx
+func ErrorCodeOf(err error) ErrorCode {
+	switch err := err.(type) {
+	case ExecError:
+		return err.Code
+	case txs.ErrTxInvalidSequence:
+		return ErrorCodeInvalidSequence
+	case txs.ErrTxInvalidString:
+		return ErrorCodeInvalidInput
+	}
+	switch err {
+	case txs.ErrTxInvalidAddress:
+		return ErrorCodeUnknownAddress
+	case txs.ErrTxDuplicateAddress:
+		return ErrorCodeDuplicateAddress
+	case txs.ErrTxInvalidAmount:
+		return ErrorCodeInvalidInput
+	case txs.ErrTxInsufficientFunds:
+		return ErrorCodeInsufficientFunds
+	case txs.ErrTxInsufficientGasPrice:
+		return ErrorCodeInsufficientGasPrice
+	case txs.ErrTxUnknownPubKey:
+		return ErrorCodeUnknownPubKey
+	case txs.ErrTxInvalidPubKey:
+		return ErrorCodeInvalidPubKey
+	case txs.ErrTxInvalidSignature:
+		return ErrorCodeInvalidSignature
+	case txs.ErrTxPermissionDenied:
+		return ErrorCodePermissionDenied
+	}
+	return ErrorCodeGeneric
+}
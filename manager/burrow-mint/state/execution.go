@@ -16,13 +16,18 @@ package state
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 
 	acm "github.com/hyperledger/burrow/account"
 	"github.com/hyperledger/burrow/common/sanity"
 	core_types "github.com/hyperledger/burrow/core/types"
 	"github.com/hyperledger/burrow/manager/burrow-mint/evm"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
 	ptypes "github.com/hyperledger/burrow/permission/types" // for GlobalPermissionAddress ...
 	"github.com/hyperledger/burrow/txs"
 	. "github.com/hyperledger/burrow/word256"
@@ -200,7 +205,7 @@ func getOrMakeOutputs(state AccountGetter, accounts map[string]*acm.Account, out
 		if acc == nil {
 			if !checkedCreatePerms {
 				if !hasCreateAccountPermission(state, accounts) {
-					return nil, fmt.Errorf("At least one input does not have permission to create accounts")
+					return nil, execErrorf(ErrorCodePermissionDenied, "At least one input does not have permission to create accounts")
 				}
 				checkedCreatePerms = true
 			}
@@ -317,9 +322,304 @@ func adjustByOutputs(accounts map[string]*acm.Account, outs []*txs.TxOutput) {
 // If the tx is invalid, an error will be returned.
 // Unlike ExecBlock(), state will not be altered.
 func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable) (err error) {
+	return execTx(blockCache, tx, runCall, evc, nil, nil)
+}
+
+// What the VM did when running the call of a CallTx
+type TxCallResult struct {
+	Return  []byte
+	GasUsed int64
+	// Set if the call failed, in which case the tx still took its fee
+	Exception error
+}
+
+// Runs tx like ExecTx (as a block would) with tracer, if not nil, attached to
+// the VM. For a CallTx that gets as far as running the VM returns what it did,
+// otherwise returns nil.
+func TraceTx(blockCache *BlockCache, tx txs.Tx, evc events.Fireable,
+	tracer vm.Tracer) (*TxCallResult, error) {
+	callResult := new(TxCallResult)
+	ran := false
+	err := execTx(blockCache, tx, true, evc, tracer, func(result TxCallResult) {
+		*callResult = result
+		ran = true
+	})
+	if err != nil || !ran {
+		return nil, err
+	}
+	return callResult, nil
+}
+
+// Sends the bonds of the validators that have been unbonding for the unbonding
+// period to their UnbondTo outputs. Run as part of the block at height.
+func ReleaseValidators(blockCache *BlockCache, height int) {
+	var released []*ValidatorInfo
+	// Validators bonded or unbonded in this block are not yet in the state,
+	// but nor can they be released
+	blockCache.State().validatorInfos.Iterate(func(address, _ []byte) bool {
+		valInfo := blockCache.GetValidatorInfo(address)
+		if valInfo.releasableAt(height) {
+			released = append(released, valInfo)
+		}
+		return false
+	})
+	for _, valInfo := range released {
+		for _, out := range valInfo.UnbondTo {
+			creditAccount(blockCache, out.Address, out.Amount)
+		}
+		valInfo.ReleasedHeight = height
+		blockCache.UpdateValidatorInfo(valInfo)
+	}
+}
+
+// Credits the fees taken by the txs of the block to the treasury or, if there
+// is none, to the bonded validators in proportion to their voting power, the
+// remainder of the division going to the most powerful. The proposer of the
+// block is not known to the app so cannot be paid alone. Run as part of the
+// block, after its txs. Returns the fees distributed.
+func DistributeFees(blockCache *BlockCache) int64 {
+	fees := blockCache.fees
+	blockCache.fees = 0
+	blockCache.State().blockFees = fees
+	if fees == 0 {
+		return 0
+	}
+	if treasury := blockCache.State().GetTreasury(); treasury != nil {
+		creditAccount(blockCache, treasury, fees)
+		return fees
+	}
+
+	var validators []*ValidatorInfo
+	var totalPower int64
+	var mostPowerful *ValidatorInfo
+	// Validators bonded in this block are not yet in the state so take no
+	// share of its fees
+	blockCache.State().validatorInfos.Iterate(func(address, _ []byte) bool {
+		valInfo := blockCache.GetValidatorInfo(address)
+		if power := valInfo.VotingPower(); power > 0 {
+			validators = append(validators, valInfo)
+			totalPower += power
+			if mostPowerful == nil || power > mostPowerful.VotingPower() {
+				mostPowerful = valInfo
+			}
+		}
+		return false
+	})
+	if mostPowerful == nil {
+		// Nobody to pay, so the fees are burnt
+		return fees
+	}
+	// The product of the fees and a power may not fit in an int64 but the
+	// share does as it is no more than the fees
+	remainder := fees
+	for _, valInfo := range validators {
+		share := new(big.Int).Mul(big.NewInt(fees), big.NewInt(valInfo.VotingPower()))
+		share.Div(share, big.NewInt(totalPower))
+		creditAccount(blockCache, valInfo.Address, share.Int64())
+		remainder -= share.Int64()
+	}
+	creditAccount(blockCache, mostPowerful.Address, remainder)
+	return fees
+}
+
+func creditAccount(blockCache *BlockCache, address []byte, amount int64) {
+	acc := blockCache.GetAccount(address)
+	if acc == nil {
+		acc = &acm.Account{
+			Address:     address,
+			Permissions: ptypes.ZeroAccountPermissions,
+		}
+	}
+	acc.Balance += amount
+	blockCache.UpdateAccount(acc)
+}
+
+// Errors if the fee paid by the tx is below the minimum set in the genesis doc
+// for its kind
+func checkFee(st *State, tx txs.Tx, fee int64) error {
+	if minFee := st.GetMinFee(tx); fee < minFee {
+		return execErrorf(ErrorCodeInsufficientFee,
+			"Fee of %v is less than the minimum of %v for this kind of tx",
+			fee, minFee)
+	}
+	return nil
+}
+
+// Errors if the gas price of the CallTx is below the minimum, otherwise returns
+// the cost of its gas limit at that price
+func checkGasPrice(st *State, tx *txs.CallTx) (int64, error) {
+	if minGasPrice := st.GetMinGasPrice(); tx.GasPrice < minGasPrice || tx.GasPrice < 0 {
+		return 0, execErrorf(ErrorCodeInsufficientGasPrice, "Gas price of %v is less than the minimum of %v",
+			tx.GasPrice, minGasPrice)
+	}
+	if tx.GasPrice == 0 {
+		return 0, nil
+	}
+	if tx.GasLimit < 0 || tx.GasLimit > math.MaxInt64/tx.GasPrice {
+		return 0, execErrorf(ErrorCodeInvalidInput, "Gas limit of %v at a price of %v is out of range",
+			tx.GasLimit, tx.GasPrice)
+	}
+	return tx.GasLimit * tx.GasPrice, nil
+}
+
+// Takes the fee for the gas the CallTx used out of the gasCost it paid up
+// front, as checkGasPrice gave it, and refunds the rest to its input. The gas
+// left is kept within the gas limit, so that the refund is no more than
+// gasCost and the fee not negative, and is returned.
+func chargeGas(blockCache *BlockCache, tx *txs.CallTx, gasCost, gasLeft int64) int64 {
+	if gasLeft < 0 {
+		gasLeft = 0
+	} else if gasLeft > tx.GasLimit {
+		gasLeft = tx.GasLimit
+	}
+	if tx.GasPrice > 0 {
+		// Since gasLeft is within the gas limit this is no more than gasCost
+		refund := gasLeft * tx.GasPrice
+		blockCache.AddFee(gasCost - refund)
+		creditAccount(blockCache, tx.Input.Address, refund)
+	}
+	return gasLeft
+}
+
+// Checks the name and data of a name registry update. Data registered under
+// an ABI name must parse as an ABI since they are decoded whenever a contract
+// is called.
+func validateNameRegStrings(name, data string) error {
+	if err := (&txs.NameTx{Name: name, Data: data}).ValidateStrings(); err != nil {
+		return err
+	}
+	if strings.HasPrefix(name, txs.ContractABINamePrefix) && len(data) > 0 {
+		if _, err := abi.JSON([]byte(data)); err != nil {
+			return execErrorf(ErrorCodeInvalidInput, "Invalid ABI for %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+type ContractCreatorGetter interface {
+	GetContractCreator(addr []byte) []byte
+}
+
+// Whether owner may register the ABI of the contract at address, which only
+// the contract itself and the account that created it may. Contracts with no
+// recorded creator, from genesis or from before creators were kept, may also
+// have theirs registered by an account with the Root permission.
+func IsContractABIOwner(creators ContractCreatorGetter, accounts AccountGetter,
+	address, owner []byte) bool {
+	if bytes.Equal(owner, address) {
+		return true
+	}
+	creator := creators.GetContractCreator(address)
+	if len(creator) > 0 {
+		return bytes.Equal(owner, creator)
+	}
+	ownerAcc := accounts.GetAccount(owner)
+	return ownerAcc != nil && HasPermission(accounts, ownerAcc, ptypes.Root)
+}
+
+// Errors if name is an ABI name that owner may not register, since the calls
+// and logs of the contract are decoded against the ABI registered under it
+func validateContractABIOwner(creators ContractCreatorGetter, accounts AccountGetter, owner []byte,
+	name string) error {
+	if !strings.HasPrefix(name, txs.ContractABINamePrefix) {
+		return nil
+	}
+	address, err := hex.DecodeString(strings.TrimPrefix(name, txs.ContractABINamePrefix))
+	if err != nil || len(address) != 20 || txs.ContractABIName(address) != name {
+		return execErrorf(ErrorCodeInvalidInput, "%s is not the ABI name of a contract address", name)
+	}
+	if !IsContractABIOwner(creators, accounts, address, owner) {
+		return execErrorf(ErrorCodePermissionDenied, "Only contract %X or its creator may register %s",
+			address, name)
+	}
+	return nil
+}
+
+// Works out the name registry entry that results from owner paying value to
+// register data under name when entry (nil if there is none) is the current
+// one. These are the rules of NameTx, shared with the NameReg SNative. Returns
+// a new entry, leaving entry untouched, or nil if the entry is to be removed.
+func updateNameRegEntry(entry *core_types.NameRegEntry, owner []byte, name, data string,
+	value int64, lastBlockHeight int) (*core_types.NameRegEntry, error) {
+	// let's say cost of a name for one block is len(data) + 32
+	costPerBlock := txs.NameCostPerBlock(txs.NameBaseCost(name, data))
+	expiresIn := int(value / costPerBlock)
+
+	log.Info("Updating namereg", "name", name, "value", value, "costPerBlock", costPerBlock,
+		"expiresIn", expiresIn, "lastBlock", lastBlockHeight)
+
+	if entry == nil {
+		if expiresIn < txs.MinNameRegistrationPeriod {
+			return nil, errors.New(fmt.Sprintf("Names must be registered for at least %d blocks", txs.MinNameRegistrationPeriod))
+		}
+		// entry does not exist, so create it
+		log.Info("Creating namereg entry", "name", name, "expiresIn", expiresIn)
+		return &core_types.NameRegEntry{
+			Name:    name,
+			Owner:   owner,
+			Data:    data,
+			Expires: lastBlockHeight + expiresIn,
+		}, nil
+	}
+
+	var expired bool
+	// if the entry already exists, and hasn't expired, we must be owner
+	if entry.Expires > lastBlockHeight {
+		// ensure we are owner
+		if bytes.Compare(entry.Owner, owner) != 0 {
+			log.Info(fmt.Sprintf("Sender %X is trying to update a name (%s) for which he is not owner", owner, name))
+			return nil, txs.ErrTxPermissionDenied
+		}
+	} else {
+		expired = true
+	}
+
+	// no value and empty data means delete the entry
+	if value == 0 && len(data) == 0 {
+		// maybe we reward you for telling us we can delete this crap
+		// (owners if not expired, anyone if expired)
+		log.Info("Removing namereg entry", "name", entry.Name)
+		return nil, nil
+	}
+
+	// update the entry by bumping the expiry and changing the data
+	entryCopy := *entry
+	newEntry := &entryCopy
+	if expired {
+		if expiresIn < txs.MinNameRegistrationPeriod {
+			return nil, errors.New(fmt.Sprintf("Names must be registered for at least %d blocks", txs.MinNameRegistrationPeriod))
+		}
+		newEntry.Expires = lastBlockHeight + expiresIn
+		newEntry.Owner = owner
+		log.Info("An old namereg entry has expired and been reclaimed", "name", name, "expiresIn", expiresIn, "owner", owner)
+	} else {
+		// since the size of the data may have changed
+		// we use the total amount of "credit"
+		oldCredit := int64(entry.Expires-lastBlockHeight) * txs.NameBaseCost(entry.Name, entry.Data)
+		credit := oldCredit + value
+		expiresIn = int(credit / costPerBlock)
+		if expiresIn < txs.MinNameRegistrationPeriod {
+			return nil, errors.New(fmt.Sprintf("Names must be registered for at least %d blocks", txs.MinNameRegistrationPeriod))
+		}
+		newEntry.Expires = lastBlockHeight + expiresIn
+		log.Info("Updated namereg entry", "name", name, "expiresIn", expiresIn, "oldCredit", oldCredit, "value", value, "credit", credit)
+	}
+	newEntry.Data = data
+	return newEntry, nil
+}
+
+// Removes the validator from the validator set, if it is still bonded, and
+// burns its bond so that it is never released. Run as part of the block at
+// height.
+func destroyValidator(blockCache *BlockCache, valInfo *ValidatorInfo, height int) {
+	valInfo.DestroyedHeight = height
+	valInfo.DestroyedAmount = valInfo.FirstBondAmount
+	blockCache.UpdateValidatorInfo(valInfo)
+}
+
+func execTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable,
+	tracer vm.Tracer, callComplete func(TxCallResult)) (err error) {
 
-	// TODO: do something with fees
-	fees := int64(0)
 	_s := blockCache.State() // hack to access validators and block height
 
 	// Exec tx
@@ -332,7 +632,7 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 
 		// ensure all inputs have send permissions
 		if !hasSendPermission(blockCache, accounts) {
-			return fmt.Errorf("At least one input lacks permission for SendTx")
+			return execErrorf(ErrorCodePermissionDenied, "At least one input lacks permission for SendTx")
 		}
 
 		// add outputs to accounts map
@@ -355,7 +655,9 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 			return txs.ErrTxInsufficientFunds
 		}
 		fee := inTotal - outTotal
-		fees += fee
+		if err := checkFee(_s, tx, fee); err != nil {
+			return err
+		}
 
 		// Good! Adjust accounts
 		adjustByInputs(accounts, tx.Inputs)
@@ -363,6 +665,7 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 		for _, acc := range accounts {
 			blockCache.UpdateAccount(acc)
 		}
+		blockCache.AddFee(fee)
 
 		// if the evc is nil, nothing will happen
 		if evc != nil {
@@ -389,11 +692,11 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 		createContract := len(tx.Address) == 0
 		if createContract {
 			if !hasCreateContractPermission(blockCache, inAcc) {
-				return fmt.Errorf("Account %X does not have CreateContract permission", tx.Input.Address)
+				return execErrorf(ErrorCodePermissionDenied, "Account %X does not have CreateContract permission", tx.Input.Address)
 			}
 		} else {
 			if !hasCallPermission(blockCache, inAcc) {
-				return fmt.Errorf("Account %X does not have Call permission", tx.Input.Address)
+				return execErrorf(ErrorCodePermissionDenied, "Account %X does not have Call permission", tx.Input.Address)
 			}
 		}
 
@@ -412,6 +715,17 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 			log.Info(fmt.Sprintf("Sender did not send enough to cover the fee %X", tx.Input.Address))
 			return txs.ErrTxInsufficientFunds
 		}
+		if err := checkFee(_s, tx, tx.Fee); err != nil {
+			return err
+		}
+		gasCost, err := checkGasPrice(_s, tx)
+		if err != nil {
+			return err
+		}
+		if tx.Input.Amount-tx.Fee < gasCost {
+			log.Info(fmt.Sprintf("Sender did not send enough to cover the gas %X", tx.Input.Address))
+			return txs.ErrTxInsufficientFunds
+		}
 
 		if !createContract {
 			// Validate output
@@ -421,7 +735,7 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 			}
 			// check if its a native contract
 			if vm.RegisteredNativeContract(LeftPadWord256(tx.Address)) {
-				return fmt.Errorf("NativeContracts can not be called using CallTx. Use a contract or the appropriate tx type (eg. PermissionsTx, NameTx)")
+				return execErrorf(ErrorCodeInvalidInput, "NativeContracts can not be called using CallTx. Use a contract or the appropriate tx type (eg. PermissionsTx, NameTx)")
 			}
 
 			// Output account may be nil if we are still in mempool and contract was created in same block as this tx
@@ -433,11 +747,13 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 		log.Info(fmt.Sprintf("Out account: %v", outAcc))
 
 		// Good!
-		value := tx.Input.Amount - tx.Fee
+		value := tx.Input.Amount - tx.Fee - gasCost
 
 		inAcc.Sequence += 1
-		inAcc.Balance -= tx.Fee
+		// All the gas is paid for up front and what is left over refunded
+		inAcc.Balance -= tx.Fee + gasCost
 		blockCache.UpdateAccount(inAcc)
+		blockCache.AddFee(tx.Fee)
 
 		// The logic in runCall MUST NOT return.
 		if runCall {
@@ -451,12 +767,7 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 				code    []byte      = nil
 				ret     []byte      = nil
 				txCache             = NewTxCache(blockCache)
-				params              = vm.Params{
-					BlockHeight: int64(_s.LastBlockHeight),
-					BlockHash:   LeftPadWord256(_s.LastBlockHash),
-					BlockTime:   _s.LastBlockTime.Unix(),
-					GasLimit:    _s.GetGasLimit(),
-				}
+				params              = _s.VMParams()
 			)
 
 			if !createContract && (outAcc == nil || len(outAcc.Code) == 0) {
@@ -498,6 +809,9 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 				txCache.UpdateAccount(callee)
 				vmach := vm.NewVM(txCache, params, caller.Address, txs.TxHash(_s.ChainID, tx))
 				vmach.SetFireable(evc)
+				if tracer != nil {
+					vmach.SetTracer(tracer)
+				}
 				// NOTE: Call() transfers the value from caller to callee iff call succeeds.
 				ret, err = vmach.Call(caller, callee, code, tx.Data, value, &gas)
 				if err != nil {
@@ -515,8 +829,19 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 
 		CALL_COMPLETE: // err may or may not be nil.
 
+			// Charge for the gas used, whether or not the call succeeded, and
+			// refund the rest to the caller as synced from the txCache
+			gas = chargeGas(blockCache, tx, gasCost, gas)
+
 			// Create a receipt from the ret and whether errored.
 			log.Notice("VM call complete", "caller", caller, "callee", callee, "return", ret, "err", err)
+			if callComplete != nil {
+				callComplete(TxCallResult{
+					Return:    ret,
+					GasUsed:   tx.GasLimit - gas,
+					Exception: err,
+				})
+			}
 
 			// Fire Events for sender and receiver
 			// a separate event will be fired from vm for each additional call
@@ -527,6 +852,13 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 				}
 				evc.FireEvent(txs.EventStringAccInput(tx.Input.Address), txs.EventDataTx{tx, ret, exception})
 				evc.FireEvent(txs.EventStringAccOutput(tx.Address), txs.EventDataTx{tx, ret, exception})
+				// Bonds made through the Validators SNative fire the event a
+				// BondTx would
+				if err == nil {
+					for _, bondTx := range txCache.BondTxs() {
+						evc.FireEvent(txs.EventStringBond(), txs.EventDataTx{bondTx, nil, ""})
+					}
+				}
 			}
 		} else {
 			// The mempool does not call txs until
@@ -553,7 +885,7 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 		}
 		// check permission
 		if !hasNamePermission(blockCache, inAcc) {
-			return fmt.Errorf("Account %X does not have Name permission", tx.Input.Address)
+			return execErrorf(ErrorCodePermissionDenied, "Account %X does not have Name permission", tx.Input.Address)
 		}
 		// pubKey should be present in either "inAcc" or "tx.Input"
 		if err := checkInputPubKey(inAcc, tx.Input); err != nil {
@@ -572,89 +904,40 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 			return txs.ErrTxInsufficientFunds
 		}
 
+		if err := checkFee(_s, tx, tx.Fee); err != nil {
+			return err
+		}
+
 		// validate the input strings
-		if err := tx.ValidateStrings(); err != nil {
+		if err := validateNameRegStrings(tx.Name, tx.Data); err != nil {
+			return err
+		}
+		if err := validateContractABIOwner(blockCache, blockCache, tx.Input.Address, tx.Name); err != nil {
 			return err
 		}
 
 		value := tx.Input.Amount - tx.Fee
 
-		// let's say cost of a name for one block is len(data) + 32
-		costPerBlock := txs.NameCostPerBlock(txs.NameBaseCost(tx.Name, tx.Data))
-		expiresIn := int(value / costPerBlock)
-		lastBlockHeight := _s.LastBlockHeight
-
-		log.Info("New NameTx", "value", value, "costPerBlock", costPerBlock, "expiresIn", expiresIn, "lastBlock", lastBlockHeight)
-
 		// check if the name exists
 		entry := blockCache.GetNameRegEntry(tx.Name)
-
-		if entry != nil {
-			var expired bool
-
-			// if the entry already exists, and hasn't expired, we must be owner
-			if entry.Expires > lastBlockHeight {
-				// ensure we are owner
-				if bytes.Compare(entry.Owner, tx.Input.Address) != 0 {
-					log.Info(fmt.Sprintf("Sender %X is trying to update a name (%s) for which he is not owner", tx.Input.Address, tx.Name))
-					return txs.ErrTxPermissionDenied
-				}
-			} else {
-				expired = true
-			}
-
-			// no value and empty data means delete the entry
-			if value == 0 && len(tx.Data) == 0 {
-				// maybe we reward you for telling us we can delete this crap
-				// (owners if not expired, anyone if expired)
-				log.Info("Removing namereg entry", "name", entry.Name)
-				blockCache.RemoveNameRegEntry(entry.Name)
-			} else {
-				// update the entry by bumping the expiry
-				// and changing the data
-				if expired {
-					if expiresIn < txs.MinNameRegistrationPeriod {
-						return errors.New(fmt.Sprintf("Names must be registered for at least %d blocks", txs.MinNameRegistrationPeriod))
-					}
-					entry.Expires = lastBlockHeight + expiresIn
-					entry.Owner = tx.Input.Address
-					log.Info("An old namereg entry has expired and been reclaimed", "name", entry.Name, "expiresIn", expiresIn, "owner", entry.Owner)
-				} else {
-					// since the size of the data may have changed
-					// we use the total amount of "credit"
-					oldCredit := int64(entry.Expires-lastBlockHeight) * txs.NameBaseCost(entry.Name, entry.Data)
-					credit := oldCredit + value
-					expiresIn = int(credit / costPerBlock)
-					if expiresIn < txs.MinNameRegistrationPeriod {
-						return errors.New(fmt.Sprintf("Names must be registered for at least %d blocks", txs.MinNameRegistrationPeriod))
-					}
-					entry.Expires = lastBlockHeight + expiresIn
-					log.Info("Updated namereg entry", "name", entry.Name, "expiresIn", expiresIn, "oldCredit", oldCredit, "value", value, "credit", credit)
-				}
-				entry.Data = tx.Data
-				blockCache.UpdateNameRegEntry(entry)
-			}
+		newEntry, err := updateNameRegEntry(entry, tx.Input.Address, tx.Name, tx.Data, value,
+			_s.LastBlockHeight)
+		if err != nil {
+			return err
+		}
+		if newEntry == nil {
+			blockCache.RemoveNameRegEntry(entry.Name)
 		} else {
-			if expiresIn < txs.MinNameRegistrationPeriod {
-				return errors.New(fmt.Sprintf("Names must be registered for at least %d blocks", txs.MinNameRegistrationPeriod))
-			}
-			// entry does not exist, so create it
-			entry = &core_types.NameRegEntry{
-				Name:    tx.Name,
-				Owner:   tx.Input.Address,
-				Data:    tx.Data,
-				Expires: lastBlockHeight + expiresIn,
-			}
-			log.Info("Creating namereg entry", "name", entry.Name, "expiresIn", expiresIn)
-			blockCache.UpdateNameRegEntry(entry)
+			blockCache.UpdateNameRegEntry(newEntry)
 		}
 
 		// TODO: something with the value sent?
 
 		// Good!
 		inAcc.Sequence += 1
-		inAcc.Balance -= value
+		inAcc.Balance -= tx.Input.Amount
 		blockCache.UpdateAccount(inAcc)
+		blockCache.AddFee(tx.Fee)
 
 		// TODO: maybe we want to take funds on error and allow txs in that don't do anythingi?
 
@@ -665,182 +948,189 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 
 		return nil
 
-		// Consensus related Txs inactivated for now
-		// TODO!
-		/*
-			case *txs.BondTx:
-						valInfo := blockCache.State().GetValidatorInfo(tx.PubKey.Address())
-						if valInfo != nil {
-							// TODO: In the future, check that the validator wasn't destroyed,
-							// add funds, merge UnbondTo outputs, and unbond validator.
-							return errors.New("Adding coins to existing validators not yet supported")
-						}
-
-						accounts, err := getInputs(blockCache, tx.Inputs)
-						if err != nil {
-							return err
-						}
-
-						// add outputs to accounts map
-						// if any outputs don't exist, all inputs must have CreateAccount perm
-						// though outputs aren't created until unbonding/release time
-						canCreate := hasCreateAccountPermission(blockCache, accounts)
-						for _, out := range tx.UnbondTo {
-							acc := blockCache.GetAccount(out.Address)
-							if acc == nil && !canCreate {
-								return fmt.Errorf("At least one input does not have permission to create accounts")
-							}
-						}
-
-						bondAcc := blockCache.GetAccount(tx.PubKey.Address())
-						if !hasBondPermission(blockCache, bondAcc) {
-							return fmt.Errorf("The bonder does not have permission to bond")
-						}
-
-						if !hasBondOrSendPermission(blockCache, accounts) {
-							return fmt.Errorf("At least one input lacks permission to bond")
-						}
-
-						signBytes := acm.SignBytes(_s.ChainID, tx)
-						inTotal, err := validateInputs(accounts, signBytes, tx.Inputs)
-						if err != nil {
-							return err
-						}
-						if !tx.PubKey.VerifyBytes(signBytes, tx.Signature) {
-							return txs.ErrTxInvalidSignature
-						}
-						outTotal, err := validateOutputs(tx.UnbondTo)
-						if err != nil {
-							return err
-						}
-						if outTotal > inTotal {
-							return txs.ErrTxInsufficientFunds
-						}
-						fee := inTotal - outTotal
-						fees += fee
-
-						// Good! Adjust accounts
-						adjustByInputs(accounts, tx.Inputs)
-						for _, acc := range accounts {
-							blockCache.UpdateAccount(acc)
-						}
-						// Add ValidatorInfo
-						_s.SetValidatorInfo(&txs.ValidatorInfo{
-							Address:         tx.PubKey.Address(),
-							PubKey:          tx.PubKey,
-							UnbondTo:        tx.UnbondTo,
-							FirstBondHeight: _s.LastBlockHeight + 1,
-							FirstBondAmount: outTotal,
-						})
-						// Add Validator
-						added := _s.BondedValidators.Add(&txs.Validator{
-							Address:     tx.PubKey.Address(),
-							PubKey:      tx.PubKey,
-							BondHeight:  _s.LastBlockHeight + 1,
-							VotingPower: outTotal,
-							Accum:       0,
-						})
-						if !added {
-							PanicCrisis("Failed to add validator")
-						}
-						if evc != nil {
-							// TODO: fire for all inputs
-							evc.FireEvent(txs.EventStringBond(), txs.EventDataTx{tx, nil, ""})
-						}
-						return nil
-
-					case *txs.UnbondTx:
-						// The validator must be active
-						_, val := _s.BondedValidators.GetByAddress(tx.Address)
-						if val == nil {
-							return txs.ErrTxInvalidAddress
-						}
-
-						// Verify the signature
-						signBytes := acm.SignBytes(_s.ChainID, tx)
-						if !val.PubKey.VerifyBytes(signBytes, tx.Signature) {
-							return txs.ErrTxInvalidSignature
-						}
-
-						// tx.Height must be greater than val.LastCommitHeight
-						if tx.Height <= val.LastCommitHeight {
-							return errors.New("Invalid unbond height")
-						}
-
-						// Good!
-						_s.unbondValidator(val)
-						if evc != nil {
-							evc.FireEvent(txs.EventStringUnbond(), txs.EventDataTx{tx, nil, ""})
-						}
-						return nil
-
-					case *txs.RebondTx:
-						// The validator must be inactive
-						_, val := _s.UnbondingValidators.GetByAddress(tx.Address)
-						if val == nil {
-							return txs.ErrTxInvalidAddress
-						}
-
-						// Verify the signature
-						signBytes := acm.SignBytes(_s.ChainID, tx)
-						if !val.PubKey.VerifyBytes(signBytes, tx.Signature) {
-							return txs.ErrTxInvalidSignature
-						}
-
-						// tx.Height must be in a suitable range
-						minRebondHeight := _s.LastBlockHeight - (validatorTimeoutBlocks / 2)
-						maxRebondHeight := _s.LastBlockHeight + 2
-						if !((minRebondHeight <= tx.Height) && (tx.Height <= maxRebondHeight)) {
-							return errors.New(Fmt("Rebond height not in range.  Expected %v <= %v <= %v",
-								minRebondHeight, tx.Height, maxRebondHeight))
-						}
-
-						// Good!
-						_s.rebondValidator(val)
-						if evc != nil {
-							evc.FireEvent(txs.EventStringRebond(), txs.EventDataTx{tx, nil, ""})
-						}
-						return nil
-
-					case *txs.DupeoutTx:
-						// Verify the signatures
-						_, accused := _s.BondedValidators.GetByAddress(tx.Address)
-						if accused == nil {
-							_, accused = _s.UnbondingValidators.GetByAddress(tx.Address)
-							if accused == nil {
-								return txs.ErrTxInvalidAddress
-							}
-						}
-						voteASignBytes := acm.SignBytes(_s.ChainID, &tx.VoteA)
-						voteBSignBytes := acm.SignBytes(_s.ChainID, &tx.VoteB)
-						if !accused.PubKey.VerifyBytes(voteASignBytes, tx.VoteA.Signature) ||
-							!accused.PubKey.VerifyBytes(voteBSignBytes, tx.VoteB.Signature) {
-							return txs.ErrTxInvalidSignature
-						}
-
-						// Verify equivocation
-						// TODO: in the future, just require one vote from a previous height that
-						// doesn't exist on this chain.
-						if tx.VoteA.Height != tx.VoteB.Height {
-							return errors.New("DupeoutTx heights don't match")
-						}
-						if tx.VoteA.Round != tx.VoteB.Round {
-							return errors.New("DupeoutTx rounds don't match")
-						}
-						if tx.VoteA.Type != tx.VoteB.Type {
-							return errors.New("DupeoutTx types don't match")
-						}
-						if bytes.Equal(tx.VoteA.BlockHash, tx.VoteB.BlockHash) {
-							return errors.New("DupeoutTx blockhashes shouldn't match")
-						}
-
-						// Good! (Bad validator!)
-						_s.destroyValidator(accused)
-						if evc != nil {
-							evc.FireEvent(txs.EventStringDupeout(), txs.EventDataTx{tx, nil, ""})
-						}
-						return nil
-		*/
+	case *txs.BondTx:
+		valInfo := blockCache.GetValidatorInfo(tx.PubKey.Address())
+		if valInfo != nil {
+			// TODO: In the future, check that the validator wasn't destroyed,
+			// add funds, merge UnbondTo outputs, and unbond validator.
+			return errors.New("Adding coins to existing validators not yet supported")
+		}
+
+		accounts, err := getInputs(blockCache, tx.Inputs)
+		if err != nil {
+			return err
+		}
+
+		// add outputs to accounts map
+		// if any outputs don't exist, all inputs must have CreateAccount perm
+		// though outputs aren't created until unbonding/release time
+		canCreate := hasCreateAccountPermission(blockCache, accounts)
+		for _, out := range tx.UnbondTo {
+			acc := blockCache.GetAccount(out.Address)
+			if acc == nil && !canCreate {
+				return execErrorf(ErrorCodePermissionDenied, "At least one input does not have permission to create accounts")
+			}
+		}
+
+		bondAcc := blockCache.GetAccount(tx.PubKey.Address())
+		if !hasBondPermission(blockCache, bondAcc) {
+			return execErrorf(ErrorCodePermissionDenied, "The bonder does not have permission to bond")
+		}
+
+		if !hasBondOrSendPermission(blockCache, accounts) {
+			return execErrorf(ErrorCodePermissionDenied, "At least one input lacks permission to bond")
+		}
+
+		signBytes := acm.SignBytes(_s.ChainID, tx)
+		inTotal, err := validateInputs(accounts, signBytes, tx.Inputs)
+		if err != nil {
+			return err
+		}
+		if !tx.PubKey.VerifyBytes(signBytes, tx.Signature) {
+			return txs.ErrTxInvalidSignature
+		}
+		outTotal, err := validateOutputs(tx.UnbondTo)
+		if err != nil {
+			return err
+		}
+		if outTotal < minBondAmount {
+			return execErrorf(ErrorCodeInvalidInput, "Bond of %v is less than the minimum of %v", outTotal, minBondAmount)
+		}
+		if outTotal > inTotal {
+			return txs.ErrTxInsufficientFunds
+		}
+		fee := inTotal - outTotal
+		if err := checkFee(_s, tx, fee); err != nil {
+			return err
+		}
+
+		// Good! Adjust accounts
+		adjustByInputs(accounts, tx.Inputs)
+		for _, acc := range accounts {
+			blockCache.UpdateAccount(acc)
+		}
+		blockCache.AddFee(fee)
+		// Add the validator, whose coins are held until it is released
+		blockCache.UpdateValidatorInfo(&ValidatorInfo{
+			Address:         tx.PubKey.Address(),
+			PubKey:          tx.PubKey,
+			UnbondTo:        tx.UnbondTo,
+			FirstBondHeight: _s.LastBlockHeight + 1,
+			FirstBondAmount: outTotal,
+			BondHeight:      _s.LastBlockHeight + 1,
+		})
+		if evc != nil {
+			for _, i := range tx.Inputs {
+				evc.FireEvent(txs.EventStringAccInput(i.Address), txs.EventDataTx{tx, nil, ""})
+			}
+			evc.FireEvent(txs.EventStringBond(), txs.EventDataTx{tx, nil, ""})
+		}
+		return nil
+
+	case *txs.UnbondTx:
+		// The validator must be bonded
+		valInfo := blockCache.GetValidatorInfo(tx.Address)
+		if valInfo == nil || !valInfo.Bonded() {
+			return txs.ErrTxInvalidAddress
+		}
+
+		// Verify the signature
+		signBytes := acm.SignBytes(_s.ChainID, tx)
+		if !valInfo.PubKey.VerifyBytes(signBytes, tx.Signature) {
+			return txs.ErrTxInvalidSignature
+		}
+
+		// tx.Height must be after the validator bonded, so that an unbond
+		// cannot be replayed once the validator has rebonded
+		if tx.Height <= valInfo.BondHeight || tx.Height > _s.LastBlockHeight+1 {
+			return execErrorf(ErrorCodeInvalidInput, "Invalid unbond height %v, expected %v < height <= %v",
+				tx.Height, valInfo.BondHeight, _s.LastBlockHeight+1)
+		}
+
+		// Good!
+		valInfo.UnbondHeight = _s.LastBlockHeight + 1
+		blockCache.UpdateValidatorInfo(valInfo)
+		if evc != nil {
+			evc.FireEvent(txs.EventStringUnbond(), txs.EventDataTx{tx, nil, ""})
+		}
+		return nil
+
+	case *txs.RebondTx:
+		// The validator must be unbonding
+		valInfo := blockCache.GetValidatorInfo(tx.Address)
+		if valInfo == nil || valInfo.Bonded() || valInfo.ReleasedHeight != 0 ||
+			valInfo.DestroyedHeight != 0 {
+			return txs.ErrTxInvalidAddress
+		}
+
+		// Verify the signature
+		signBytes := acm.SignBytes(_s.ChainID, tx)
+		if !valInfo.PubKey.VerifyBytes(signBytes, tx.Signature) {
+			return txs.ErrTxInvalidSignature
+		}
+
+		// tx.Height must be in a suitable range, and after the validator
+		// unbonded so that a rebond cannot be replayed
+		minRebondHeight := _s.LastBlockHeight - (validatorTimeoutBlocks / 2)
+		if minRebondHeight <= valInfo.UnbondHeight {
+			minRebondHeight = valInfo.UnbondHeight + 1
+		}
+		maxRebondHeight := _s.LastBlockHeight + 2
+		if !((minRebondHeight <= tx.Height) && (tx.Height <= maxRebondHeight)) {
+			return execErrorf(ErrorCodeInvalidInput, "Rebond height not in range.  Expected %v <= %v <= %v",
+				minRebondHeight, tx.Height, maxRebondHeight)
+		}
+
+		// Good!
+		valInfo.BondHeight = _s.LastBlockHeight + 1
+		valInfo.UnbondHeight = 0
+		blockCache.UpdateValidatorInfo(valInfo)
+		if evc != nil {
+			evc.FireEvent(txs.EventStringRebond(), txs.EventDataTx{tx, nil, ""})
+		}
+		return nil
+
+	case *txs.DupeoutTx:
+		// The accused must have a bond to destroy, bonded or unbonding
+		accused := blockCache.GetValidatorInfo(tx.Address)
+		if accused == nil || accused.ReleasedHeight != 0 || accused.DestroyedHeight != 0 {
+			return txs.ErrTxInvalidAddress
+		}
+
+		// Verify the signatures
+		if !bytes.Equal(tx.VoteA.ValidatorAddress, tx.Address) ||
+			!bytes.Equal(tx.VoteB.ValidatorAddress, tx.Address) {
+			return errors.New("DupeoutTx votes are not from the accused validator")
+		}
+		voteASignBytes := acm.SignBytes(_s.ChainID, &tx.VoteA)
+		voteBSignBytes := acm.SignBytes(_s.ChainID, &tx.VoteB)
+		if !accused.PubKey.VerifyBytes(voteASignBytes, tx.VoteA.Signature) ||
+			!accused.PubKey.VerifyBytes(voteBSignBytes, tx.VoteB.Signature) {
+			return txs.ErrTxInvalidSignature
+		}
+
+		// Verify equivocation
+		// TODO: in the future, just require one vote from a previous height that
+		// doesn't exist on this chain.
+		if tx.VoteA.Height != tx.VoteB.Height {
+			return errors.New("DupeoutTx heights don't match")
+		}
+		if tx.VoteA.Round != tx.VoteB.Round {
+			return errors.New("DupeoutTx rounds don't match")
+		}
+		if tx.VoteA.Type != tx.VoteB.Type {
+			return errors.New("DupeoutTx types don't match")
+		}
+		if bytes.Equal(tx.VoteA.BlockID.Hash, tx.VoteB.BlockID.Hash) {
+			return errors.New("DupeoutTx blockhashes shouldn't match")
+		}
+
+		// Good! (Bad validator!)
+		destroyValidator(blockCache, accused, _s.LastBlockHeight+1)
+		if evc != nil {
+			evc.FireEvent(txs.EventStringDupeout(), txs.EventDataTx{tx, nil, ""})
+		}
+		return nil
 
 	case *txs.PermissionsTx:
 		var inAcc *acm.Account
@@ -855,7 +1145,7 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 		permFlag := tx.PermArgs.PermFlag()
 		// check permission
 		if !HasPermission(blockCache, inAcc, permFlag) {
-			return fmt.Errorf("Account %X does not have moderator permission %s (%b)", tx.Input.Address, ptypes.PermFlagToString(permFlag), permFlag)
+			return execErrorf(ErrorCodePermissionDenied, "Account %X does not have moderator permission %s (%b)", tx.Input.Address, ptypes.PermFlagToString(permFlag), permFlag)
 		}
 
 		// pubKey should be present in either "inAcc" or "tx.Input"
@@ -881,12 +1171,12 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 			return fmt.Errorf("HasBase is for contracts, not humans. Just look at the blockchain")
 		case *ptypes.SetBaseArgs:
 			if permAcc = blockCache.GetAccount(args.Address); permAcc == nil {
-				return fmt.Errorf("Trying to update permissions for unknown account %X", args.Address)
+				return execErrorf(ErrorCodeUnknownAddress, "Trying to update permissions for unknown account %X", args.Address)
 			}
 			err = permAcc.Permissions.Base.Set(args.Permission, args.Value)
 		case *ptypes.UnsetBaseArgs:
 			if permAcc = blockCache.GetAccount(args.Address); permAcc == nil {
-				return fmt.Errorf("Trying to update permissions for unknown account %X", args.Address)
+				return execErrorf(ErrorCodeUnknownAddress, "Trying to update permissions for unknown account %X", args.Address)
 			}
 			err = permAcc.Permissions.Base.Unset(args.Permission)
 		case *ptypes.SetGlobalArgs:
@@ -898,14 +1188,14 @@ func ExecTx(blockCache *BlockCache, tx txs.Tx, runCall bool, evc events.Fireable
 			return fmt.Errorf("HasRole is for contracts, not humans. Just look at the blockchain")
 		case *ptypes.AddRoleArgs:
 			if permAcc = blockCache.GetAccount(args.Address); permAcc == nil {
-				return fmt.Errorf("Trying to update roles for unknown account %X", args.Address)
+				return execErrorf(ErrorCodeUnknownAddress, "Trying to update roles for unknown account %X", args.Address)
 			}
 			if !permAcc.Permissions.AddRole(args.Role) {
 				return fmt.Errorf("Role (%s) already exists for account %X", args.Role, args.Address)
 			}
 		case *ptypes.RmRoleArgs:
 			if permAcc = blockCache.GetAccount(args.Address); permAcc == nil {
-				return fmt.Errorf("Trying to update roles for unknown account %X", args.Address)
+				return execErrorf(ErrorCodeUnknownAddress, "Trying to update roles for unknown account %X", args.Address)
 			}
 			if !permAcc.Permissions.RmRole(args.Role) {
 				return fmt.Errorf("Role (%s) does not exist for account %X", args.Role, args.Address)
@@ -950,9 +1240,12 @@ func HasPermission(state AccountGetter, acc *acm.Account, perm ptypes.PermFlag)
 	}
 
 	if acc == nil {
-		// TODO
-		// this needs to fall back to global or do some other specific things
-		// eg. a bondAcc may be nil and so can only bond if global bonding is true
+		// Accounts that do not exist yet, such as that of a validator's key
+		// when it bonds, have the global permissions
+		if state == nil {
+			sanity.PanicSanity("Checking the permissions of a nil account with no state")
+		}
+		return HasPermission(nil, state.GetAccount(ptypes.GlobalPermissionsAddress), perm)
 	}
 	permString := ptypes.PermFlagToString(perm)
 
@@ -25,9 +25,11 @@ import (
 	acm "github.com/hyperledger/burrow/account"
 	"github.com/hyperledger/burrow/common/random"
 	genesis "github.com/hyperledger/burrow/genesis"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm"
 	ptypes "github.com/hyperledger/burrow/permission/types"
 
 	tdb "github.com/tendermint/go-db"
+	"github.com/tendermint/go-wire"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -106,6 +108,40 @@ func TestGenesisMakeState(t *testing.T) {
 	}
 }
 
+func TestGenesisVMParams(t *testing.T) {
+	genDoc := genesis.GenesisDocFromJSON([]byte(g1))
+	db := tdb.NewMemDB()
+	st := MakeGenesisState(db, genDoc)
+	if name := st.GetGasSchedule().Name; name != vm.LegacyGasScheduleName {
+		t.Fatalf("Expected legacy gas schedule, got %s", name)
+	}
+	if limit := st.GetMemoryLimit(); limit != vm.DefaultMemoryLimit {
+		t.Fatalf("Expected default memory limit, got %v", limit)
+	}
+
+	genDoc.Params = &genesis.GenesisParams{
+		GasSchedule: vm.LegacyGasScheduleName,
+		MemoryLimit: 4096,
+	}
+	st = MakeGenesisState(db, genDoc)
+	if name := st.Copy().GetGasSchedule().Name; name != vm.LegacyGasScheduleName {
+		t.Fatalf("Expected legacy gas schedule, got %s", name)
+	}
+
+	// The params are recovered from the genesis doc when state is loaded
+	st.Save()
+	buf, n, err := new(bytes.Buffer), new(int), new(error)
+	wire.WriteJSON(genDoc, buf, n, err)
+	db.Set(genesis.GenDocKey, buf.Bytes())
+	st = LoadState(db)
+	if name := st.GetGasSchedule().Name; name != vm.LegacyGasScheduleName {
+		t.Fatalf("Expected legacy gas schedule after load, got %s", name)
+	}
+	if limit := st.GetMemoryLimit(); limit != 4096 {
+		t.Fatalf("Expected memory limit of 4096 after load, got %v", limit)
+	}
+}
+
 //-------------------------------------------------------
 
 func RandGenesisState(numAccounts int, randBalance bool, minBalance int64, numValidators int, randBonded bool, minBonded int64) (*State, []*acm.PrivAccount, []*types.PrivValidator) {
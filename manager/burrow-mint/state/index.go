@@ -0,0 +1,385 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/burrow/txs"
+	"github.com/hyperledger/burrow/util"
+
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/go-wire"
+)
+
+// Committed txs are indexed in the state's DB so that they and their logs can
+// be searched without running blocks again. A tx is found by its position,
+// the height of its block and its index in it, under which its receipt is
+// kept, and by its hash, under which the position of its receipt is kept. The
+// same tx may be included in more than one block, where it fails after the
+// first time, so the hash keeps the position of the first receipt of the tx
+// that succeeded, or failing that the first. Each account, log address and log topic keeps the
+// positions of the txs it appears in as a list linked back from the latest,
+// so that indexing a tx writes a key for each of them and nothing more.
+
+const (
+	// The most txs or logs a search returns at once
+	maxSearchResults = 100
+	// The most blocks a search covers
+	maxSearchBlocks = 10000
+)
+
+var (
+	txIndexKey         = []byte("txIndex")
+	accountIndexKey    = []byte("accountIndex")
+	logAddressIndexKey = []byte("logAddressIndex")
+	logTopicIndexKey   = []byte("logTopicIndex")
+)
+
+type txPosition struct {
+	Height int
+	Index  int
+}
+
+func (pos txPosition) before(other txPosition) bool {
+	return pos.Height < other.Height ||
+		(pos.Height == other.Height && pos.Index < other.Index)
+}
+
+type txPositions []txPosition
+
+func (p txPositions) Len() int           { return len(p) }
+func (p txPositions) Less(i, j int) bool { return p[i].before(p[j]) }
+func (p txPositions) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// A tx of the block being run, with the addresses of the accounts it involves
+type indexedTx struct {
+	receipt  *txs.TxReceipt
+	accounts [][]byte
+}
+
+func txIndexKeyAt(pos txPosition) []byte {
+	return []byte(fmt.Sprintf("%s/%d/%d", txIndexKey, pos.Height, pos.Index))
+}
+
+func txCountKeyAtHeight(height int) []byte {
+	return []byte(fmt.Sprintf("%s/%d", txIndexKey, height))
+}
+
+func indexListKey(indexKey, key []byte) []byte {
+	return []byte(fmt.Sprintf("%s/%X", indexKey, key))
+}
+
+func indexListKeyAt(listKey []byte, pos txPosition) []byte {
+	return []byte(fmt.Sprintf("%s/%d/%d", listKey, pos.Height, pos.Index))
+}
+
+// The addresses of the accounts a tx involves, so that it is found by them
+func txAccounts(tx txs.Tx, receipt *txs.TxReceipt) [][]byte {
+	var accounts [][]byte
+	switch tx := tx.(type) {
+	case *txs.SendTx:
+		for _, input := range tx.Inputs {
+			accounts = append(accounts, input.Address)
+		}
+		for _, output := range tx.Outputs {
+			accounts = append(accounts, output.Address)
+		}
+	case *txs.CallTx:
+		accounts = append(accounts, tx.Input.Address)
+		if len(tx.Address) > 0 {
+			accounts = append(accounts, tx.Address)
+		} else if len(receipt.ContractAddr) > 0 {
+			accounts = append(accounts, receipt.ContractAddr)
+		}
+	case *txs.NameTx:
+		accounts = append(accounts, tx.Input.Address)
+	case *txs.BondTx:
+		accounts = append(accounts, tx.PubKey.Address())
+		for _, input := range tx.Inputs {
+			accounts = append(accounts, input.Address)
+		}
+		for _, output := range tx.UnbondTo {
+			accounts = append(accounts, output.Address)
+		}
+	case *txs.UnbondTx:
+		accounts = append(accounts, tx.Address)
+	case *txs.RebondTx:
+		accounts = append(accounts, tx.Address)
+	case *txs.DupeoutTx:
+		accounts = append(accounts, tx.Address)
+	case *txs.PermissionsTx:
+		accounts = append(accounts, tx.Input.Address)
+	}
+	return accounts
+}
+
+// Stores the receipts of committed txs and indexes them by position, account
+// and the addresses and topics of their logs. Txs that could not be decoded
+// have no receipt, so each block also keeps how many txs it had up to the
+// last one indexed for its txs to be scanned past the gaps.
+func (s *State) indexTxs(indexedTxs []indexedTx) {
+	txCounts := make(map[int]int)
+	for _, itx := range indexedTxs {
+		receipt := itx.receipt
+		pos := txPosition{Height: receipt.Height, Index: receipt.Index}
+		s.DB.Set(txIndexKeyAt(pos), wire.BinaryBytes(receipt))
+		if first := s.GetTxReceipt(receipt.TxHash); first == nil ||
+			(first.Code != abci.CodeType_OK && receipt.Code == abci.CodeType_OK) {
+			s.DB.Set(txReceiptKeyOfHash(receipt.TxHash), wire.BinaryBytes(pos))
+		}
+		if pos.Index >= txCounts[pos.Height] {
+			txCounts[pos.Height] = pos.Index + 1
+		}
+
+		var listKeys [][]byte
+		for _, address := range itx.accounts {
+			listKeys = append(listKeys, indexListKey(accountIndexKey, address))
+		}
+		for _, log := range receipt.Logs {
+			listKeys = append(listKeys, indexListKey(logAddressIndexKey, log.Address.Postfix(20)))
+			for _, topic := range log.Topics {
+				listKeys = append(listKeys, indexListKey(logTopicIndexKey, topic.Bytes()))
+			}
+		}
+		for _, listKey := range listKeys {
+			s.linkPosition(listKey, pos)
+		}
+	}
+	for height, txCount := range txCounts {
+		s.DB.Set(txCountKeyAtHeight(height), wire.BinaryBytes(txCount))
+	}
+}
+
+// Adds the position to the front of the list, unless it is there already
+func (s *State) linkPosition(listKey []byte, pos txPosition) {
+	latest := s.readPosition(listKey)
+	if latest.Height > 0 && !latest.before(pos) {
+		return
+	}
+	s.DB.Set(indexListKeyAt(listKey, pos), wire.BinaryBytes(latest))
+	s.DB.Set(listKey, wire.BinaryBytes(pos))
+}
+
+// Reads a position stored under the key, or the zero position, which ends a
+// list, if there is none
+func (s *State) readPosition(key []byte) txPosition {
+	var pos txPosition
+	posBytes := s.DB.Get(key)
+	if len(posBytes) == 0 {
+		return pos
+	}
+	if err := wire.ReadBinaryBytes(posBytes, &pos); err != nil {
+		util.Fatalf("Could not decode tx position under key %s: %v", key, err)
+	}
+	return pos
+}
+
+// The positions in the list of the txs of blocks from height from to height
+// to, in the order the txs ran
+func (s *State) listPositions(listKey []byte, from, to int) []txPosition {
+	var positions []txPosition
+	pos := s.readPosition(listKey)
+	for pos.Height > 0 && pos.Height >= from {
+		if pos.Height <= to {
+			positions = append(positions, pos)
+		}
+		pos = s.readPosition(indexListKeyAt(listKey, pos))
+	}
+	for i, j := 0, len(positions)-1; i < j; i, j = i+1, j-1 {
+		positions[i], positions[j] = positions[j], positions[i]
+	}
+	return positions
+}
+
+// The positions in any of the lists, in the order the txs ran
+func (s *State) listsPositions(listKeys [][]byte, from, to int) []txPosition {
+	seen := make(map[txPosition]bool)
+	var positions []txPosition
+	for _, listKey := range listKeys {
+		for _, pos := range s.listPositions(listKey, from, to) {
+			if !seen[pos] {
+				seen[pos] = true
+				positions = append(positions, pos)
+			}
+		}
+	}
+	sort.Sort(txPositions(positions))
+	return positions
+}
+
+// The positions of all txs of blocks from height from to height to
+func (s *State) positionsInRange(from, to int) []txPosition {
+	var positions []txPosition
+	for height := from; height <= to; height++ {
+		txCountBytes := s.DB.Get(txCountKeyAtHeight(height))
+		if len(txCountBytes) == 0 {
+			// Blocks indexed before their tx counts were kept end at their
+			// first tx without a receipt
+			for pos := (txPosition{Height: height}); len(s.DB.Get(txIndexKeyAt(pos))) > 0; pos.Index++ {
+				positions = append(positions, pos)
+			}
+			continue
+		}
+		var txCount int
+		if err := wire.ReadBinaryBytes(txCountBytes, &txCount); err != nil {
+			util.Fatalf("Could not decode tx count of block %v: %v", height, err)
+		}
+		for pos := (txPosition{Height: height}); pos.Index < txCount; pos.Index++ {
+			if len(s.DB.Get(txIndexKeyAt(pos))) > 0 {
+				positions = append(positions, pos)
+			}
+		}
+	}
+	return positions
+}
+
+func (s *State) receiptAt(pos txPosition) *txs.TxReceipt {
+	receiptBytes := s.DB.Get(txIndexKeyAt(pos))
+	if len(receiptBytes) == 0 {
+		util.Fatalf("No receipt for indexed tx %v in block %v", pos.Index, pos.Height)
+	}
+	receipt := new(txs.TxReceipt)
+	if err := wire.ReadBinaryBytes(receiptBytes, receipt); err != nil {
+		util.Fatalf("Could not decode the receipt of tx %v in block %v: %v", pos.Index, pos.Height, err)
+	}
+	return receipt
+}
+
+// Resolves the heights a search covers and checks its page
+func (s *State) searchRange(fromHeight, toHeight, offset, limit int) (int, int, error) {
+	if fromHeight < 0 || toHeight < 0 {
+		return 0, 0, fmt.Errorf("Heights searched must not be negative")
+	}
+	if offset < 0 || limit < 0 {
+		return 0, 0, fmt.Errorf("Offset and limit of a search must not be negative")
+	}
+	if toHeight == 0 || toHeight > s.LastBlockHeight {
+		toHeight = s.LastBlockHeight
+	}
+	if fromHeight == 0 {
+		fromHeight = toHeight - maxSearchBlocks + 1
+		if fromHeight < 1 {
+			fromHeight = 1
+		}
+	}
+	if toHeight-fromHeight >= maxSearchBlocks {
+		return 0, 0, fmt.Errorf("A search covers at most %v blocks but heights %v to %v were asked for",
+			maxSearchBlocks, fromHeight, toHeight)
+	}
+	return fromHeight, toHeight, nil
+}
+
+// The most results a page of a search has
+func searchLimit(limit int) int {
+	if limit == 0 || limit > maxSearchResults {
+		return maxSearchResults
+	}
+	return limit
+}
+
+// The start and end of the page of n results at offset
+func searchPage(n, offset, limit int) (int, int) {
+	limit = searchLimit(limit)
+	if offset > n {
+		offset = n
+	}
+	if offset+limit > n {
+		return offset, n
+	}
+	return offset, offset + limit
+}
+
+// Returns the receipts of the committed txs the filter matches, in the order
+// they ran
+func (s *State) SearchTxs(filter *txs.TxFilter) (*txs.TxSearchResult, error) {
+	from, to, err := s.searchRange(filter.FromHeight, filter.ToHeight, filter.Offset, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	var positions []txPosition
+	if len(filter.Account) > 0 {
+		positions = s.listPositions(indexListKey(accountIndexKey, filter.Account), from, to)
+	} else {
+		positions = s.positionsInRange(from, to)
+	}
+	start, end := searchPage(len(positions), filter.Offset, filter.Limit)
+	result := &txs.TxSearchResult{
+		Total:    len(positions),
+		Receipts: make([]*txs.TxReceipt, 0, end-start),
+	}
+	for _, pos := range positions[start:end] {
+		result.Receipts = append(result.Receipts, s.receiptAt(pos))
+	}
+	return result, nil
+}
+
+// Returns the logs of committed txs the filter matches, in the order they were
+// emitted
+func (s *State) GetLogs(filter *txs.LogFilter) (*txs.LogSearchResult, error) {
+	from, to, err := s.searchRange(filter.FromHeight, filter.ToHeight, filter.Offset, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	// Only txs with a log from one of the addresses, or failing that with one
+	// of the topics of the first position that has any, can match
+	var listKeys [][]byte
+	for _, address := range filter.Addresses {
+		listKeys = append(listKeys, indexListKey(logAddressIndexKey, address))
+	}
+	for _, topics := range filter.Topics {
+		if len(listKeys) > 0 {
+			break
+		}
+		for _, topic := range topics {
+			listKeys = append(listKeys, indexListKey(logTopicIndexKey, topic.Bytes()))
+		}
+	}
+	var positions []txPosition
+	if len(listKeys) > 0 {
+		positions = s.listsPositions(listKeys, from, to)
+	} else {
+		positions = s.positionsInRange(from, to)
+	}
+
+	// Receipts are only read until the page is full
+	end := filter.Offset + searchLimit(filter.Limit)
+	result := &txs.LogSearchResult{}
+	matched := 0
+	for _, pos := range positions {
+		receipt := s.receiptAt(pos)
+		for i, log := range receipt.Logs {
+			if !filter.Matches(log) {
+				continue
+			}
+			if matched == end {
+				result.More = true
+				return result, nil
+			}
+			if matched >= filter.Offset {
+				result.Logs = append(result.Logs, &txs.LogEntry{
+					TxHash:   receipt.TxHash,
+					Height:   receipt.Height,
+					TxIndex:  receipt.Index,
+					LogIndex: i,
+					Log:      log,
+				})
+			}
+			matched++
+		}
+	}
+	return result, nil
+}
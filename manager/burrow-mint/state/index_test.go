@@ -0,0 +1,201 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"testing"
+
+	"github.com/hyperledger/burrow/txs"
+	. "github.com/hyperledger/burrow/word256"
+
+	"github.com/stretchr/testify/assert"
+	abci "github.com/tendermint/abci/types"
+)
+
+func TestSearchTxsAndLogs(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, true, 1000, 1, true, 1000)
+	alice, bob := privAccounts[0].Address, privAccounts[1].Address
+	contractA, contractB := LeftPadBytes([]byte{0xA}, 20), LeftPadBytes([]byte{0xB}, 20)
+	topic1, topic2 := Int64ToWord256(1), Int64ToWord256(2)
+
+	send := func(from, to []byte) txs.Tx {
+		return &txs.SendTx{
+			Inputs:  []*txs.TxInput{{Address: from}},
+			Outputs: []*txs.TxOutput{{Address: to}},
+		}
+	}
+	call := func(from, to []byte) txs.Tx {
+		return &txs.CallTx{Input: &txs.TxInput{Address: from}, Address: to}
+	}
+	log := func(address []byte, topics ...Word256) *txs.EventDataLog {
+		return &txs.EventDataLog{Address: LeftPadWord256(address), Topics: topics}
+	}
+	nTxs := 0
+	commitBlock := func(blockTxs []txs.Tx, blockLogs [][]*txs.EventDataLog) {
+		for i, tx := range blockTxs {
+			nTxs++
+			state.AddTxReceipt(tx, &txs.TxReceipt{
+				TxHash: Int64ToWord256(int64(nTxs)).Bytes(),
+				Height: state.LastBlockHeight + 1,
+				Index:  i,
+				Logs:   blockLogs[i],
+			})
+		}
+		state.FinishBlock()
+		state.Save()
+	}
+	commitBlock([]txs.Tx{send(alice, bob), call(alice, contractA)},
+		[][]*txs.EventDataLog{nil, {log(contractA, topic1, topic2), log(contractA, topic2)}})
+	commitBlock([]txs.Tx{call(bob, contractB)},
+		[][]*txs.EventDataLog{{log(contractB, topic1)}})
+	commitBlock([]txs.Tx{send(bob, alice)}, [][]*txs.EventDataLog{nil})
+
+	heights := func(receipts []*txs.TxReceipt) []int {
+		var heights []int
+		for _, receipt := range receipts {
+			heights = append(heights, receipt.Height)
+		}
+		return heights
+	}
+
+	result, err := state.SearchTxs(&txs.TxFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, []int{1, 1, 2, 3}, heights(result.Receipts))
+
+	result, err = state.SearchTxs(&txs.TxFilter{Account: bob})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, heights(result.Receipts))
+
+	result, err = state.SearchTxs(&txs.TxFilter{Account: alice, FromHeight: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3}, heights(result.Receipts))
+
+	result, err = state.SearchTxs(&txs.TxFilter{Account: contractA})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Receipts, 1) {
+		assert.Equal(t, 1, result.Receipts[0].Index)
+	}
+
+	result, err = state.SearchTxs(&txs.TxFilter{Offset: 1, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, []int{1, 2}, heights(result.Receipts))
+
+	_, err = state.SearchTxs(&txs.TxFilter{Offset: -1})
+	assert.Error(t, err)
+
+	logs, err := state.GetLogs(&txs.LogFilter{Addresses: [][]byte{contractA}})
+	assert.NoError(t, err)
+	if assert.Len(t, logs.Logs, 2) {
+		assert.Equal(t, 0, logs.Logs[0].LogIndex)
+		assert.Equal(t, 1, logs.Logs[1].LogIndex)
+	}
+
+	logs, err = state.GetLogs(&txs.LogFilter{Topics: [][]Word256{{topic1}}})
+	assert.NoError(t, err)
+	if assert.Len(t, logs.Logs, 2) {
+		assert.Equal(t, 1, logs.Logs[0].Height)
+		assert.Equal(t, 2, logs.Logs[1].Height)
+	}
+
+	logs, err = state.GetLogs(&txs.LogFilter{Topics: [][]Word256{nil, {topic2}}})
+	assert.NoError(t, err)
+	if assert.Len(t, logs.Logs, 1) {
+		assert.Equal(t, 0, logs.Logs[0].LogIndex)
+	}
+
+	logs, err = state.GetLogs(&txs.LogFilter{Topics: [][]Word256{{topic1, topic2}}, ToHeight: 1})
+	assert.NoError(t, err)
+	assert.Len(t, logs.Logs, 2)
+
+	logs, err = state.GetLogs(&txs.LogFilter{Addresses: [][]byte{contractB}, Topics: [][]Word256{{topic2}}})
+	assert.NoError(t, err)
+	assert.Empty(t, logs.Logs)
+	assert.False(t, logs.More)
+
+	// Pages of logs tell whether there are more
+	logs, err = state.GetLogs(&txs.LogFilter{Topics: [][]Word256{{topic1, topic2}}, Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, logs.Logs, 2)
+	assert.True(t, logs.More)
+	logs, err = state.GetLogs(&txs.LogFilter{Topics: [][]Word256{{topic1, topic2}}, Offset: 2, Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, logs.Logs, 1) {
+		assert.Equal(t, 2, logs.Logs[0].Height)
+	}
+	assert.False(t, logs.More)
+
+	// Saving the state again does not index its txs twice
+	state.Save()
+	result, err = state.SearchTxs(&txs.TxFilter{Account: bob})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Total)
+
+	// A tx that could not be decoded has no receipt but the txs after it in
+	// its block are still found
+	state.AddTxReceipt(send(alice, bob), &txs.TxReceipt{TxHash: []byte("first"), Height: 4, Index: 0})
+	state.AddTxReceipt(send(bob, alice), &txs.TxReceipt{TxHash: []byte("third"), Height: 4, Index: 2})
+	state.FinishBlock()
+	state.Save()
+	result, err = state.SearchTxs(&txs.TxFilter{FromHeight: 4})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Receipts, 2) {
+		assert.Equal(t, 2, result.Receipts[1].Index)
+	}
+
+	// Searches cover a limited range of blocks, the latest by default
+	state.LastBlockHeight = 3 * maxSearchBlocks
+	_, err = state.SearchTxs(&txs.TxFilter{FromHeight: 1})
+	assert.Error(t, err)
+	_, err = state.SearchTxs(&txs.TxFilter{FromHeight: 1, ToHeight: maxSearchBlocks})
+	assert.NoError(t, err)
+	result, err = state.SearchTxs(&txs.TxFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Total)
+}
+
+func TestDuplicateTxReceipts(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(1, true, 1000, 1, true, 1000)
+	tx := &txs.SendTx{Inputs: []*txs.TxInput{{Address: privAccounts[0].Address}}}
+	txHash := Int64ToWord256(1).Bytes()
+	commitBlock := func(code abci.CodeType) {
+		state.AddTxReceipt(tx, &txs.TxReceipt{
+			TxHash: txHash,
+			Height: state.LastBlockHeight + 1,
+			Code:   code,
+		})
+		state.FinishBlock()
+		state.Save()
+	}
+
+	// A tx that failed is superseded by the first time it succeeds
+	commitBlock(abci.CodeType_BaseInvalidSequence)
+	assert.Equal(t, 1, state.GetTxReceipt(txHash).Height)
+	commitBlock(abci.CodeType_OK)
+	assert.Equal(t, 2, state.GetTxReceipt(txHash).Height)
+	// but not by it failing when included again
+	commitBlock(abci.CodeType_BaseInvalidSequence)
+	receipt := state.GetTxReceipt(txHash)
+	assert.Equal(t, 2, receipt.Height)
+	assert.Equal(t, abci.CodeType_OK, receipt.Code)
+
+	// Each inclusion is still found by its position
+	result, err := state.SearchTxs(&txs.TxFilter{})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Receipts, 3) {
+		assert.Equal(t, abci.CodeType_BaseInvalidSequence, result.Receipts[2].Code)
+	}
+}
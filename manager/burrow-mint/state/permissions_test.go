@@ -243,6 +243,8 @@ func TestName(t *testing.T) {
 	tx.Sign(chainID, user[0])
 	if err := ExecTx(blockCache, tx, true, nil); err == nil {
 		t.Fatal("Expected error")
+	} else if ErrorCodeOf(err) != ErrorCodePermissionDenied {
+		t.Fatalf("Expected permission denied error, got %v", err)
 	} else {
 		fmt.Println(err)
 	}
@@ -603,9 +605,8 @@ func TestCreatePermission(t *testing.T) {
 	}
 }
 
-/* TODO
 func TestBondPermission(t *testing.T) {
-	stateDB := dbm.NewDB("state",dbBackend,dbDir)
+	stateDB := dbm.NewDB("state", dbBackend, dbDir)
 	genDoc := newBaseGenDoc(PermsAllFalse, PermsAllFalse)
 	st := MakeGenesisState(stateDB, &genDoc)
 	blockCache := NewBlockCache(st)
@@ -726,7 +727,6 @@ func TestBondPermission(t *testing.T) {
 		t.Fatal("Expected error")
 	}
 }
-*/
 
 func TestCreateAccountPermission(t *testing.T) {
 	stateDB := dbm.NewDB("state", dbBackend, dbDir)
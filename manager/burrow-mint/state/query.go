@@ -0,0 +1,143 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	acm "github.com/hyperledger/burrow/account"
+	. "github.com/hyperledger/burrow/word256"
+)
+
+// Kinds of query, the first element of a query path. Queries take the paths
+// /account/<hex address>, /storage/<hex address>/<hex key> and /name/<name>.
+const (
+	QueryAccount = "account"
+	QueryStorage = "storage"
+	QueryName    = "name"
+)
+
+type Query struct {
+	Path string
+	Kind string
+	// Key of the value, or for storage of the account holding it, in the
+	// accounts or name registry tree
+	TreeKey []byte
+	// Key of the value in the account's storage tree, for storage queries
+	StorageKey []byte
+}
+
+// The answer to a query, with the proof of its value
+type QueryResult struct {
+	Path string
+	// The height of the last block committed to the state queried. Its app
+	// hash is in the header of the block after.
+	Height int
+	// As stored in the tree, so an encoded account, a storage word or an
+	// encoded name registry entry
+	Value []byte
+	Proof *QueryProof
+}
+
+type QueryProof struct {
+	// The roots of the state's trees, which hash to its app hash
+	AccountsRoot       []byte
+	ValidatorInfosRoot []byte
+	NameRegRoot        []byte
+	// Empty until the first contract is created
+	ContractCreatorsRoot []byte
+	// Proof of the value, or for storage of the account holding it, in the
+	// accounts or name registry tree
+	TreeProof []byte
+	// For storage queries, the encoded account holding the storage and the
+	// proof of the value in its storage tree
+	Account      []byte
+	StorageProof []byte
+}
+
+func ParseQueryPath(path string) (*Query, error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) == 2 {
+		query := &Query{Path: path, Kind: parts[0]}
+		switch query.Kind {
+		case QueryAccount:
+			address, err := hex.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid address in query path '%s': %v", path, err)
+			}
+			query.TreeKey = address
+			return query, nil
+		case QueryStorage:
+			keys := strings.Split(parts[1], "/")
+			if len(keys) != 2 {
+				break
+			}
+			address, err := hex.DecodeString(keys[0])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid address in query path '%s': %v", path, err)
+			}
+			key, err := hex.DecodeString(keys[1])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid storage key in query path '%s': %v", path, err)
+			}
+			query.TreeKey = address
+			query.StorageKey = LeftPadWord256(key).Bytes()
+			return query, nil
+		case QueryName:
+			query.TreeKey = []byte(parts[1])
+			return query, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown query path '%s'", path)
+}
+
+// Answers the query with a value and its proof. Values not in the state are
+// errors since their absence cannot be proved.
+func (s *State) Query(query *Query) (*QueryResult, error) {
+	tree := s.accounts
+	if query.Kind == QueryName {
+		tree = s.nameReg
+	}
+	value, treeProof, exists := tree.Proof(query.TreeKey)
+	if !exists {
+		return nil, fmt.Errorf("Nothing found at query path '%s'", query.Path)
+	}
+	result := &QueryResult{
+		Path:   query.Path,
+		Height: s.LastBlockHeight,
+		Value:  value,
+		Proof: &QueryProof{
+			AccountsRoot:       s.accounts.Hash(),
+			ValidatorInfosRoot: s.validatorInfos.Hash(),
+			NameRegRoot:        s.nameReg.Hash(),
+			TreeProof:          treeProof,
+
+			ContractCreatorsRoot: s.contractCreators.Hash(),
+		},
+	}
+	if query.Kind == QueryStorage {
+		storage := s.LoadStorage(acm.DecodeAccount(value).StorageRoot)
+		word, storageProof, exists := storage.Proof(query.StorageKey)
+		if !exists {
+			return nil, fmt.Errorf("Nothing found at query path '%s'", query.Path)
+		}
+		result.Value = word
+		result.Proof.Account = value
+		result.Proof.StorageProof = storageProof
+	}
+	return result, nil
+}
@@ -23,6 +23,7 @@ import (
 
 	acm "github.com/hyperledger/burrow/account"
 	genesis "github.com/hyperledger/burrow/genesis"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm"
 	ptypes "github.com/hyperledger/burrow/permission/types"
 	"github.com/hyperledger/burrow/txs"
 
@@ -33,11 +34,15 @@ import (
 
 	core_types "github.com/hyperledger/burrow/core/types"
 	"github.com/hyperledger/burrow/util"
+	. "github.com/hyperledger/burrow/word256"
 	"github.com/tendermint/tendermint/types"
 )
 
 var (
 	stateKey                     = []byte("stateKey")
+	blockHashKey                 = []byte("blockHash")
+	blockFeesKey                 = []byte("blockFees")
+	txReceiptKey                 = []byte("txReceipt")
 	minBondAmount                = int64(1)           // TODO adjust
 	defaultAccountsCacheCapacity = 1000               // TODO adjust
 	unbondingPeriodBlocks        = int(60 * 24 * 365) // TODO probably better to make it time based.
@@ -55,19 +60,69 @@ type State struct {
 	LastBlockHash   []byte
 	LastBlockParts  types.PartSetHeader
 	LastBlockTime   time.Time
-	//	BondedValidators     *types.ValidatorSet
-	//	LastBondedValidators *types.ValidatorSet
-	//	UnbondingValidators  *types.ValidatorSet
-	accounts       merkle.Tree // Shouldn't be accessed directly.
-	validatorInfos merkle.Tree // Shouldn't be accessed directly.
-	nameReg        merkle.Tree // Shouldn't be accessed directly.
+	accounts        merkle.Tree // Shouldn't be accessed directly.
+	validatorInfos  merkle.Tree // Shouldn't be accessed directly.
+	nameReg         merkle.Tree // Shouldn't be accessed directly.
+	// The address of the account that created each contract, by the address
+	// of the contract
+	contractCreators merkle.Tree
+
+	// The DB the trees are kept in, which keeps the nodes they orphan until
+	// the states they are in are dropped
+	treeDB *versionedDB
+
+	// Fixed by the genesis params so not saved with the rest of the state
+	gasSchedule *vm.GasSchedule
+	memoryLimit int64
+	minFees     genesis.GenesisFees
+	minGasPrice int64
+	treasury    []byte
+	// How many of the latest heights to keep the state of for historical
+	// queries, or every height if 0. Up to the node so not saved either.
+	historyBlocks int
+	// The hash and time of the block being run, from its header, which become
+	// those of the last block when it is finished
+	blockHash []byte
+	blockTime time.Time
+	// The fees collected by the block being run once they are distributed,
+	// and by the last block
+	blockFees     int64
+	lastBlockFees int64
+	// The txs of the block being run and of the last block, to be indexed
+	blockTxs     []indexedTx
+	lastBlockTxs []indexedTx
 
 	evc events.Fireable // typically an events.EventCache
 }
 
 func LoadState(db dbm.DB) *State {
-	s := &State{DB: db}
-	buf := db.Get(stateKey)
+	return loadState(db, db.Get(stateKey))
+}
+
+// Loads the state as it was saved after committing the block at height, or
+// the genesis state for height 0. Returns nil if no state was saved then.
+func LoadStateAtHeight(db dbm.DB, height int) *State {
+	return loadState(db, db.Get(stateKeyAtHeight(height)))
+}
+
+func stateKeyAtHeight(height int) []byte {
+	return []byte(fmt.Sprintf("%s/%d", stateKey, height))
+}
+
+func blockHashKeyAtHeight(height int64) []byte {
+	return []byte(fmt.Sprintf("%s/%d", blockHashKey, height))
+}
+
+func blockFeesKeyAtHeight(height int) []byte {
+	return []byte(fmt.Sprintf("%s/%d", blockFeesKey, height))
+}
+
+func txReceiptKeyOfHash(txHash []byte) []byte {
+	return []byte(fmt.Sprintf("%s/%X", txReceiptKey, txHash))
+}
+
+func loadState(db dbm.DB, buf []byte) *State {
+	s := &State{DB: db, treeDB: newVersionedDB(db)}
 	if len(buf) == 0 {
 		return nil
 	} else {
@@ -77,22 +132,25 @@ func LoadState(db dbm.DB) *State {
 		s.LastBlockHash = wire.ReadByteSlice(r, maxLoadStateElementSize, n, err)
 		s.LastBlockParts = wire.ReadBinary(types.PartSetHeader{}, r, maxLoadStateElementSize, n, err).(types.PartSetHeader)
 		s.LastBlockTime = wire.ReadTime(r, n, err)
-		// s.BondedValidators = wire.ReadBinary(&types.ValidatorSet{}, r, maxLoadStateElementSize, n, err).(*types.ValidatorSet)
-		// s.LastBondedValidators = wire.ReadBinary(&types.ValidatorSet{}, r, maxLoadStateElementSize, n, err).(*types.ValidatorSet)
-		// s.UnbondingValidators = wire.ReadBinary(&types.ValidatorSet{}, r, maxLoadStateElementSize, n, err).(*types.ValidatorSet)
 		accountsHash := wire.ReadByteSlice(r, maxLoadStateElementSize, n, err)
-		s.accounts = merkle.NewIAVLTree(defaultAccountsCacheCapacity, db)
+		s.accounts = merkle.NewIAVLTree(defaultAccountsCacheCapacity, s.treeDB)
 		s.accounts.Load(accountsHash)
-		//validatorInfosHash := wire.ReadByteSlice(r, maxLoadStateElementSize, n, err)
-		//s.validatorInfos = merkle.NewIAVLTree(wire.BasicCodec, types.ValidatorInfoCodec, 0, db)
-		//s.validatorInfos.Load(validatorInfosHash)
+		validatorInfosHash := wire.ReadByteSlice(r, maxLoadStateElementSize, n, err)
+		s.validatorInfos = merkle.NewIAVLTree(0, s.treeDB)
+		s.validatorInfos.Load(validatorInfosHash)
 		nameRegHash := wire.ReadByteSlice(r, maxLoadStateElementSize, n, err)
-		s.nameReg = merkle.NewIAVLTree(0, db)
+		s.nameReg = merkle.NewIAVLTree(0, s.treeDB)
 		s.nameReg.Load(nameRegHash)
+		// States saved before contract creators were kept end here
+		s.contractCreators = merkle.NewIAVLTree(0, s.treeDB)
+		if r.Len() > 0 {
+			s.contractCreators.Load(wire.ReadByteSlice(r, maxLoadStateElementSize, n, err))
+		}
 		if *err != nil {
 			// DATA HAS BEEN CORRUPTED OR THE SPEC HAS CHANGED
 			util.Fatalf("Data has been corrupted or its spec has changed: %v\n", *err)
 		}
+		s.loadGenesisParams()
 		// TODO: ensure that buf is completely read.
 	}
 	return s
@@ -100,20 +158,19 @@ func LoadState(db dbm.DB) *State {
 
 func (s *State) Save() {
 	s.accounts.Save()
-	//s.validatorInfos.Save()
+	s.validatorInfos.Save()
 	s.nameReg.Save()
+	s.contractCreators.Save()
 	buf, n, err := new(bytes.Buffer), new(int), new(error)
 	wire.WriteString(s.ChainID, buf, n, err)
 	wire.WriteVarint(s.LastBlockHeight, buf, n, err)
 	wire.WriteByteSlice(s.LastBlockHash, buf, n, err)
 	wire.WriteBinary(s.LastBlockParts, buf, n, err)
 	wire.WriteTime(s.LastBlockTime, buf, n, err)
-	// wire.WriteBinary(s.BondedValidators, buf, n, err)
-	// wire.WriteBinary(s.LastBondedValidators, buf, n, err)
-	// wire.WriteBinary(s.UnbondingValidators, buf, n, err)
 	wire.WriteByteSlice(s.accounts.Hash(), buf, n, err)
-	//wire.WriteByteSlice(s.validatorInfos.Hash(), buf, n, err)
+	wire.WriteByteSlice(s.validatorInfos.Hash(), buf, n, err)
 	wire.WriteByteSlice(s.nameReg.Hash(), buf, n, err)
+	wire.WriteByteSlice(s.contractCreators.Hash(), buf, n, err)
 	if *err != nil {
 		// TODO: [Silas] Do something better than this, really serialising ought to
 		// be error-free
@@ -121,6 +178,28 @@ func (s *State) Save() {
 			"cannot continue, error: %s", *err)
 	}
 	s.DB.Set(stateKey, buf.Bytes())
+	// Block hashes are small so all are kept, which lets BLOCKHASH run
+	// against the state of any height
+	if len(s.LastBlockHash) > 0 {
+		s.DB.Set(blockHashKeyAtHeight(int64(s.LastBlockHeight)), s.LastBlockHash)
+	}
+	if s.lastBlockFees > 0 {
+		s.DB.Set(blockFeesKeyAtHeight(s.LastBlockHeight), wire.BinaryBytes(s.lastBlockFees))
+	}
+	// Receipts are kept for every tx, like block hashes
+	s.indexTxs(s.lastBlockTxs)
+	// The trees are stored by the hashes of their nodes and treeDB keeps
+	// those they orphan, so keeping the state of every height keeps its
+	// accounts and names loadable
+	s.DB.Set(stateKeyAtHeight(s.LastBlockHeight), buf.Bytes())
+	s.treeDB.saveOrphans(s.LastBlockHeight)
+	if s.historyBlocks > 0 && s.LastBlockHeight >= s.historyBlocks {
+		// The nodes orphaned up to the height dropped are no longer in the
+		// tree of any state kept
+		dropHeight := s.LastBlockHeight - s.historyBlocks
+		s.DB.Delete(stateKeyAtHeight(dropHeight))
+		s.treeDB.pruneOrphans(dropHeight)
+	}
 }
 
 // CONTRACT:
@@ -129,30 +208,60 @@ func (s *State) Save() {
 func (s *State) Copy() *State {
 	return &State{
 		DB:              s.DB,
+		treeDB:          s.treeDB,
 		ChainID:         s.ChainID,
 		LastBlockHeight: s.LastBlockHeight,
 		LastBlockHash:   s.LastBlockHash,
 		LastBlockParts:  s.LastBlockParts,
 		LastBlockTime:   s.LastBlockTime,
-		// BondedValidators:     s.BondedValidators.Copy(),     // TODO remove need for Copy() here.
-		// LastBondedValidators: s.LastBondedValidators.Copy(), // That is, make updates to the validator set
-		// UnbondingValidators: s.UnbondingValidators.Copy(), // copy the valSet lazily.
-		accounts: s.accounts.Copy(),
-		//validatorInfos:       s.validatorInfos.Copy(),
-		nameReg: s.nameReg.Copy(),
-		evc:     nil,
+		accounts:        s.accounts.Copy(),
+		validatorInfos:  s.validatorInfos.Copy(),
+		nameReg:         s.nameReg.Copy(),
+		gasSchedule:     s.gasSchedule,
+		memoryLimit:     s.memoryLimit,
+		minFees:         s.minFees,
+		minGasPrice:     s.minGasPrice,
+		treasury:        s.treasury,
+		historyBlocks:   s.historyBlocks,
+		blockHash:       s.blockHash,
+		blockTime:       s.blockTime,
+		blockFees:       s.blockFees,
+		lastBlockFees:   s.lastBlockFees,
+		blockTxs:        append([]indexedTx(nil), s.blockTxs...),
+		lastBlockTxs:    s.lastBlockTxs,
+		evc:             nil,
+
+		contractCreators: s.contractCreators.Copy(),
 	}
 }
 
 // Returns a hash that represents the state data, excluding Last*
 func (s *State) Hash() []byte {
-	return merkle.SimpleHashFromMap(map[string]interface{}{
-		//"BondedValidators":    s.BondedValidators,
-		//"UnbondingValidators": s.UnbondingValidators,
-		"Accounts": s.accounts,
-		//"ValidatorInfos":      s.validatorInfos,
-		"NameRegistry": s.nameReg,
-	})
+	return AppHash(s.accounts.Hash(), s.validatorInfos.Hash(), s.nameReg.Hash(),
+		s.contractCreators.Hash())
+}
+
+// Returns the hash of a state from the root hashes of its trees, so that
+// proofs against those roots can be checked against the hash. Until a contract
+// is created the contract creators tree is empty and left out, so that states
+// from before it was kept hash as they did.
+func AppHash(accountsRoot, validatorInfosRoot, nameRegRoot, contractCreatorsRoot []byte) []byte {
+	roots := map[string]interface{}{
+		"Accounts":       rootHash(accountsRoot),
+		"ValidatorInfos": rootHash(validatorInfosRoot),
+		"NameRegistry":   rootHash(nameRegRoot),
+	}
+	if len(contractCreatorsRoot) > 0 {
+		roots["ContractCreators"] = rootHash(contractCreatorsRoot)
+	}
+	return merkle.SimpleHashFromMap(roots)
+}
+
+// Hashes to the root hash of a tree as the tree itself would
+type rootHash []byte
+
+func (rh rootHash) Hash() []byte {
+	return rh
 }
 
 /* //XXX Done by tendermint core
@@ -185,13 +294,204 @@ func (s *State) SetDB(db dbm.DB) {
 	s.DB = db
 }
 
+// Sets how many of the latest heights Save keeps the state of, or every
+// height if 0
+func (s *State) SetHistoryBlocks(historyBlocks int) {
+	s.historyBlocks = historyBlocks
+}
+
+// Sets the hash and time of the block about to be run from its header
+func (s *State) BeginBlock(hash []byte, blockTime time.Time) {
+	s.blockHash = hash
+	s.blockTime = blockTime
+}
+
+// Makes the block run the last block, ready to be saved. A block that was not
+// begun with BeginBlock gets no hash and the time of the block before.
+func (s *State) FinishBlock() {
+	s.LastBlockHeight += 1
+	s.LastBlockHash = s.blockHash
+	if !s.blockTime.IsZero() {
+		s.LastBlockTime = s.blockTime
+	}
+	s.blockHash = nil
+	s.blockTime = time.Time{}
+	s.lastBlockFees = s.blockFees
+	s.blockFees = 0
+	s.lastBlockTxs = s.blockTxs
+	s.blockTxs = nil
+}
+
+// The time of the block being run, or if none has begun of the last block
+func (s *State) BlockTime() time.Time {
+	if s.blockTime.IsZero() {
+		return s.LastBlockTime
+	}
+	return s.blockTime
+}
+
+// Returns the hash of the block committed at height, or Zero256 if there was
+// none or this state is from before it.
+// Implements vm.BlockHashGetter
+func (s *State) GetBlockHash(height int64) Word256 {
+	if height <= 0 || height > int64(s.LastBlockHeight) {
+		return Zero256
+	}
+	return LeftPadWord256(s.DB.Get(blockHashKeyAtHeight(height)))
+}
+
+// Returns the total of the fees collected by the block committed at height
+func (s *State) GetBlockFees(height int) int64 {
+	feesBytes := s.DB.Get(blockFeesKeyAtHeight(height))
+	if len(feesBytes) == 0 {
+		return 0
+	}
+	var fees int64
+	if err := wire.ReadBinaryBytes(feesBytes, &fees); err != nil {
+		util.Fatalf("Could not decode the fees of block %v: %v", height, err)
+	}
+	return fees
+}
+
+// Returns the address of the account that created the contract at address, or
+// nil if it was not created by a tx or contract, as genesis accounts are not.
+// Creators are kept in a tree of their own so that they are covered by the
+// app hash, as the owner of a contract's ABI is checked against them.
+func (s *State) GetContractCreator(address []byte) []byte {
+	_, creator, _ := s.contractCreators.Get(address)
+	return creator
+}
+
+func (s *State) setContractCreator(address, creator []byte) {
+	s.contractCreators.Set(address, creator)
+}
+
+// Keeps the receipt of a tx run in the block being run, to be stored and
+// indexed with the state once the block is finished
+func (s *State) AddTxReceipt(tx txs.Tx, receipt *txs.TxReceipt) {
+	s.blockTxs = append(s.blockTxs, indexedTx{
+		receipt:  receipt,
+		accounts: txAccounts(tx, receipt),
+	})
+}
+
+// Returns the receipt of the committed tx with the hash, or nil if no such tx
+// has been committed. A tx committed more than once gives its first receipt
+// that succeeded, or if none did its first.
+func (s *State) GetTxReceipt(txHash []byte) *txs.TxReceipt {
+	pos := s.readPosition(txReceiptKeyOfHash(txHash))
+	if pos.Height == 0 {
+		return nil
+	}
+	return s.receiptAt(pos)
+}
+
 //-------------------------------------
 // State.params
 
+// The params to run the VM with for the block being run, or if none has begun
+// for the block after the last one
+func (s *State) VMParams() vm.Params {
+	return vm.Params{
+		BlockHeight: int64(s.LastBlockHeight + 1),
+		BlockHash:   LeftPadWord256(s.LastBlockHash),
+		BlockTime:   s.BlockTime().Unix(),
+		GasLimit:    s.GetGasLimit(),
+		GasSchedule: s.GetGasSchedule(),
+		MemoryLimit: s.GetMemoryLimit(),
+		BlockHashes: s,
+	}
+}
+
 func (s *State) GetGasLimit() int64 {
 	return 1000000 // TODO
 }
 
+func (s *State) GetGasSchedule() *vm.GasSchedule {
+	if s.gasSchedule == nil {
+		return vm.LegacyGasSchedule()
+	}
+	return s.gasSchedule
+}
+
+// Returns the minimum fee for the kind of tx
+func (s *State) GetMinFee(tx txs.Tx) int64 {
+	switch tx.(type) {
+	case *txs.SendTx:
+		return s.minFees.SendTx
+	case *txs.CallTx:
+		return s.minFees.CallTx
+	case *txs.NameTx:
+		return s.minFees.NameTx
+	case *txs.BondTx:
+		return s.minFees.BondTx
+	}
+	return 0
+}
+
+func (s *State) GetMinGasPrice() int64 {
+	return s.minGasPrice
+}
+
+// Returns the address of the account credited with fees, or nil if they go to
+// the validators
+func (s *State) GetTreasury() []byte {
+	return s.treasury
+}
+
+func (s *State) GetMemoryLimit() int64 {
+	if s.memoryLimit == 0 {
+		return vm.DefaultMemoryLimit
+	}
+	return s.memoryLimit
+}
+
+// Sets the params fixed by the genesis doc stored in the DB. Databases without
+// a genesis doc get the defaults.
+func (s *State) loadGenesisParams() {
+	if len(s.DB.Get(genesis.GenDocKey)) == 0 {
+		s.setGenesisParams(nil)
+		return
+	}
+	genDoc, err := s.GetGenesisDoc()
+	if err != nil {
+		util.Fatalf("Could not load genesis params: %v", err)
+	}
+	s.setGenesisParams(genDoc.Params)
+}
+
+func (s *State) setGenesisParams(params *genesis.GenesisParams) {
+	if params == nil {
+		params = &genesis.GenesisParams{}
+	}
+	gasSchedule, err := vm.GasScheduleByName(params.GasSchedule)
+	if err != nil {
+		util.Fatalf("Invalid genesis params: %v", err)
+	}
+	if params.MemoryLimit < 0 {
+		util.Fatalf("Invalid genesis params: negative memory limit %v", params.MemoryLimit)
+	}
+	if params.MinFees != nil && (params.MinFees.SendTx < 0 || params.MinFees.CallTx < 0 ||
+		params.MinFees.NameTx < 0 || params.MinFees.BondTx < 0) {
+		util.Fatalf("Invalid genesis params: negative minimum fee in %v", *params.MinFees)
+	}
+	if params.MinGasPrice < 0 {
+		util.Fatalf("Invalid genesis params: negative minimum gas price %v", params.MinGasPrice)
+	}
+	if len(params.Treasury) != 0 && len(params.Treasury) != 20 {
+		util.Fatalf("Invalid genesis params: treasury address %X is not 20 bytes", params.Treasury)
+	}
+	s.gasSchedule = gasSchedule
+	s.memoryLimit = params.MemoryLimit
+	if params.MinFees != nil {
+		s.minFees = *params.MinFees
+	}
+	s.minGasPrice = params.MinGasPrice
+	if len(params.Treasury) != 0 {
+		s.treasury = params.Treasury
+	}
+}
+
 // State.params
 //-------------------------------------
 // State.accounts
@@ -234,117 +534,36 @@ func (s *State) SetAccounts(accounts merkle.Tree) {
 //-------------------------------------
 // State.validators
 
-// XXX: now handled by tendermint core
-
-/*
-
-// The returned ValidatorInfo is a copy, so mutating it
-// has no side effects.
-func (s *State) GetValidatorInfo(address []byte) *types.ValidatorInfo {
-	_, valInfo := s.validatorInfos.Get(address)
-	if valInfo == nil {
+// Returns nil if no validator has bonded with the given address.
+func (s *State) GetValidatorInfo(address []byte) *ValidatorInfo {
+	_, valInfoBytes, _ := s.validatorInfos.Get(address)
+	if valInfoBytes == nil {
 		return nil
 	}
-	return valInfo.(*types.ValidatorInfo).Copy()
+	return DecodeValidatorInfo(valInfoBytes)
 }
 
 // Returns false if new, true if updated.
-// The valInfo is copied before setting, so mutating it
-// afterwards has no side effects.
-func (s *State) SetValidatorInfo(valInfo *types.ValidatorInfo) (updated bool) {
-	return s.validatorInfos.Set(valInfo.Address, valInfo.Copy())
+func (s *State) UpdateValidatorInfo(valInfo *ValidatorInfo) bool {
+	return s.validatorInfos.Set(valInfo.Address, EncodeValidatorInfo(valInfo))
 }
 
+// The returned tree is a copy, so mutating it has no side effects.
 func (s *State) GetValidatorInfos() merkle.Tree {
 	return s.validatorInfos.Copy()
 }
 
-func (s *State) unbondValidator(val *types.Validator) {
-	// Move validator to UnbondingValidators
-	val, removed := s.BondedValidators.Remove(val.Address)
-	if !removed {
-		PanicCrisis("Couldn't remove validator for unbonding")
-	}
-	val.UnbondHeight = s.LastBlockHeight + 1
-	added := s.UnbondingValidators.Add(val)
-	if !added {
-		PanicCrisis("Couldn't add validator for unbonding")
-	}
-}
-
-func (s *State) rebondValidator(val *types.Validator) {
-	// Move validator to BondingValidators
-	val, removed := s.UnbondingValidators.Remove(val.Address)
-	if !removed {
-		PanicCrisis("Couldn't remove validator for rebonding")
-	}
-	val.BondHeight = s.LastBlockHeight + 1
-	added := s.BondedValidators.Add(val)
-	if !added {
-		PanicCrisis("Couldn't add validator for rebonding")
-	}
-}
-
-func (s *State) releaseValidator(val *types.Validator) {
-	// Update validatorInfo
-	valInfo := s.GetValidatorInfo(val.Address)
-	if valInfo == nil {
-		PanicSanity("Couldn't find validatorInfo for release")
-	}
-	valInfo.ReleasedHeight = s.LastBlockHeight + 1
-	s.SetValidatorInfo(valInfo)
-
-	// Send coins back to UnbondTo outputs
-	accounts, err := getOrMakeOutputs(s, nil, valInfo.UnbondTo)
-	if err != nil {
-		PanicSanity("Couldn't get or make unbondTo accounts")
-	}
-	adjustByOutputs(accounts, valInfo.UnbondTo)
-	for _, acc := range accounts {
-		s.UpdateAccount(acc)
-	}
-
-	// Remove validator from UnbondingValidators
-	_, removed := s.UnbondingValidators.Remove(val.Address)
-	if !removed {
-		PanicCrisis("Couldn't remove validator for release")
-	}
-}
-
-func (s *State) destroyValidator(val *types.Validator) {
-	// Update validatorInfo
-	valInfo := s.GetValidatorInfo(val.Address)
-	if valInfo == nil {
-		PanicSanity("Couldn't find validatorInfo for release")
-	}
-	valInfo.DestroyedHeight = s.LastBlockHeight + 1
-	valInfo.DestroyedAmount = val.VotingPower
-	s.SetValidatorInfo(valInfo)
-
-	// Remove validator
-	_, removed := s.BondedValidators.Remove(val.Address)
-	if !removed {
-		_, removed := s.UnbondingValidators.Remove(val.Address)
-		if !removed {
-			PanicCrisis("Couldn't remove validator for destruction")
-		}
-	}
-
-}
-
 // Set the validator infos tree
 func (s *State) SetValidatorInfos(validatorInfos merkle.Tree) {
 	s.validatorInfos = validatorInfos
 }
 
-*/
-
 // State.validators
 //-------------------------------------
 // State.storage
 
 func (s *State) LoadStorage(hash []byte) (storage merkle.Tree) {
-	storage = merkle.NewIAVLTree(1024, s.DB)
+	storage = merkle.NewIAVLTree(1024, s.treeDB)
 	storage.Load(hash)
 	return storage
 }
@@ -438,8 +657,10 @@ func MakeGenesisState(db dbm.DB, genDoc *genesis.GenesisDoc) *State {
 		genDoc.GenesisTime = time.Unix(1479442162, 0)
 	}
 
+	treeDB := newVersionedDB(db)
+
 	// Make accounts state tree
-	accounts := merkle.NewIAVLTree(defaultAccountsCacheCapacity, db)
+	accounts := merkle.NewIAVLTree(defaultAccountsCacheCapacity, treeDB)
 	for _, genAcc := range genDoc.Accounts {
 		perm := ptypes.ZeroAccountPermissions
 		if genAcc.Permissions != nil {
@@ -474,60 +695,52 @@ func MakeGenesisState(db dbm.DB, genDoc *genesis.GenesisDoc) *State {
 	}
 	accounts.Set(permsAcc.Address, acm.EncodeAccount(permsAcc))
 
-	// Make validatorInfos state tree && validators slice
-	/*
-		validatorInfos := merkle.NewIAVLTree(wire.BasicCodec, types.ValidatorInfoCodec, 0, db)
-		validators := make([]*types.Validator, len(genDoc.Validators))
-		for i, val := range genDoc.Validators {
-			pubKey := val.PubKey
-			address := pubKey.Address()
-
-			// Make ValidatorInfo
-			valInfo := &types.ValidatorInfo{
-				Address:         address,
-				PubKey:          pubKey,
-				UnbondTo:        make([]*types.TxOutput, len(val.UnbondTo)),
-				FirstBondHeight: 0,
-				FirstBondAmount: val.Amount,
-			}
-			for i, unbondTo := range val.UnbondTo {
-				valInfo.UnbondTo[i] = &types.TxOutput{
-					Address: unbondTo.Address,
-					Amount:  unbondTo.Amount,
-				}
-			}
-			validatorInfos.Set(address, valInfo)
-
-			// Make validator
-			validators[i] = &types.Validator{
-				Address:     address,
-				PubKey:      pubKey,
-				VotingPower: val.Amount,
+	// Make validatorInfos state tree
+	validatorInfos := merkle.NewIAVLTree(0, treeDB)
+	for _, val := range genDoc.Validators {
+		valInfo := &ValidatorInfo{
+			Address:         val.PubKey.Address(),
+			PubKey:          val.PubKey,
+			UnbondTo:        make([]*txs.TxOutput, len(val.UnbondTo)),
+			FirstBondHeight: 0,
+			FirstBondAmount: val.Amount,
+		}
+		for i, unbondTo := range val.UnbondTo {
+			valInfo.UnbondTo[i] = &txs.TxOutput{
+				Address: unbondTo.Address,
+				Amount:  unbondTo.Amount,
 			}
 		}
-	*/
+		validatorInfos.Set(valInfo.Address, EncodeValidatorInfo(valInfo))
+	}
 
 	// Make namereg tree
-	nameReg := merkle.NewIAVLTree(0, db)
+	nameReg := merkle.NewIAVLTree(0, treeDB)
 	// TODO: add names, contracts to genesis.json
 
+	// Genesis accounts were created by nobody
+	contractCreators := merkle.NewIAVLTree(0, treeDB)
+
 	// IAVLTrees must be persisted before copy operations.
 	accounts.Save()
-	//validatorInfos.Save()
+	validatorInfos.Save()
 	nameReg.Save()
+	contractCreators.Save()
 
-	return &State{
+	s := &State{
 		DB:              db,
+		treeDB:          treeDB,
 		ChainID:         genDoc.ChainID,
 		LastBlockHeight: 0,
 		LastBlockHash:   nil,
 		LastBlockParts:  types.PartSetHeader{},
 		LastBlockTime:   genDoc.GenesisTime,
-		//BondedValidators:     types.NewValidatorSet(validators),
-		//LastBondedValidators: types.NewValidatorSet(nil),
-		//UnbondingValidators:  types.NewValidatorSet(nil),
-		accounts: accounts,
-		//validatorInfos:       validatorInfos,
-		nameReg: nameReg,
+		accounts:        accounts,
+		validatorInfos:  validatorInfos,
+		nameReg:         nameReg,
+
+		contractCreators: contractCreators,
 	}
+	s.setGenesisParams(genDoc.Params)
+	return s
 }
@@ -17,14 +17,20 @@ package state
 import (
 	"bytes"
 	"encoding/hex"
+	"math"
 	"testing"
 
+	acm "github.com/hyperledger/burrow/account"
 	core_types "github.com/hyperledger/burrow/core/types"
 	evm "github.com/hyperledger/burrow/manager/burrow-mint/evm"
+	ptypes "github.com/hyperledger/burrow/permission/types"
 	"github.com/hyperledger/burrow/txs"
 	"github.com/hyperledger/burrow/word256"
 
+	"github.com/tendermint/go-crypto"
+	dbm "github.com/tendermint/go-db"
 	"github.com/tendermint/tendermint/config/tendermint_test"
+	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 func init() {
@@ -90,6 +96,17 @@ func TestCopyState(t *testing.T) {
 	if !bytes.Equal(s0Hash, s0Copy.Hash()) {
 		t.Error("Expected state copy hash to have not changed")
 	}
+
+	// Receipts kept by a copy must not overwrite those of the original
+	for index := 0; index < 3; index++ {
+		s0.AddTxReceipt(&txs.SendTx{}, &txs.TxReceipt{Index: index})
+	}
+	s0Copy = s0.Copy()
+	s0Copy.AddTxReceipt(&txs.SendTx{}, &txs.TxReceipt{Index: -1})
+	s0.AddTxReceipt(&txs.SendTx{}, &txs.TxReceipt{Index: 3})
+	if index := s0Copy.blockTxs[3].receipt.Index; index != -1 {
+		t.Errorf("Expected receipt kept by the copy, got that of tx %v", index)
+	}
 }
 
 /*
@@ -192,6 +209,357 @@ func TestGenesisSaveLoad(t *testing.T) {
 }
 */
 
+func TestLoadStateAtHeight(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, true, 1000, 1, true, 1000)
+	state.Save()
+
+	acc0 := state.GetAccount(privAccounts[0].PubKey.Address())
+	acc1 := state.GetAccount(privAccounts[1].PubKey.Address())
+	tx := txs.NewSendTx()
+	tx.AddInputWithNonce(privAccounts[0].PubKey, 10, acc0.Sequence+1)
+	tx.AddOutput(acc1.Address, 10)
+	tx.SignInput(state.ChainID, 0, privAccounts[0])
+	if err := execTxWithStateNewBlock(state, tx, true); err != nil {
+		t.Fatalf("Got error in executing send transaction, %v", err)
+	}
+	state.Save()
+
+	genesisState := LoadStateAtHeight(state.DB, 0)
+	if genesisState == nil || genesisState.LastBlockHeight != 0 {
+		t.Fatalf("Expected to load the genesis state")
+	}
+	if balance := genesisState.GetAccount(acc1.Address).Balance; balance != acc1.Balance {
+		t.Errorf("Expected balance %v at genesis, got %v", acc1.Balance, balance)
+	}
+	if balance := LoadStateAtHeight(state.DB, 1).GetAccount(acc1.Address).Balance; balance != acc1.Balance+10 {
+		t.Errorf("Expected balance %v at height 1, got %v", acc1.Balance+10, balance)
+	}
+	if LoadStateAtHeight(state.DB, 2) != nil {
+		t.Errorf("Expected no state beyond the last saved height")
+	}
+}
+
+func TestStateHistoryBlocks(t *testing.T) {
+	state, _, _ := RandGenesisState(1, true, 1000, 1, true, 1000)
+	state.SetHistoryBlocks(2)
+	for height := 0; height <= 3; height++ {
+		state.LastBlockHeight = height
+		state.Save()
+	}
+	for height, retained := range []bool{false, false, true, true} {
+		if (LoadStateAtHeight(state.DB, height) != nil) != retained {
+			t.Errorf("Expected state at height %v retained to be %v", height, retained)
+		}
+	}
+}
+
+// The trees delete the nodes a save orphans from their DB in the batch of
+// their next save, as go-merkle does
+func TestVersionedDBKeepsOrphans(t *testing.T) {
+	vdb := newVersionedDB(dbm.NewMemDB())
+	nodeA, nodeB := []byte("nodeA"), []byte("nodeB")
+	save := func(height int, set, orphan [][]byte) {
+		batch := vdb.NewBatch()
+		for _, node := range set {
+			batch.Set(node, node)
+		}
+		for _, node := range orphan {
+			batch.Delete(node)
+		}
+		batch.Write()
+		vdb.saveOrphans(height)
+	}
+	save(1, [][]byte{nodeA, nodeB}, nil)
+	save(2, nil, [][]byte{nodeA, nodeB})
+	if vdb.Get(nodeA) == nil || vdb.Get(nodeB) == nil {
+		t.Fatalf("Expected orphaned nodes to be kept")
+	}
+	// nodeB is in the tree again so must outlive the height it was orphaned at
+	save(3, [][]byte{nodeB}, nil)
+	vdb.pruneOrphans(1)
+	if vdb.Get(nodeA) == nil {
+		t.Errorf("Expected node orphaned at a later height to be kept")
+	}
+	vdb.pruneOrphans(2)
+	if vdb.Get(nodeA) != nil {
+		t.Errorf("Expected node orphaned at the pruned height to be deleted")
+	}
+	if vdb.Get(nodeB) == nil {
+		t.Errorf("Expected node saved again since it was orphaned to be kept")
+	}
+	if vdb.Get(orphansKeyAtHeight(2)) != nil {
+		t.Errorf("Expected orphans of the pruned height to be forgotten")
+	}
+}
+
+func TestBondUnbondRebond(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, true, 1000, 1, true, 1000)
+	bonder, unbondTo := privAccounts[0], privAccounts[1]
+	balance := state.GetAccount(bonder.Address).Balance
+
+	bondTx, _ := txs.NewBondTx(bonder.PubKey)
+	bondTx.AddInput(state, bonder.PubKey, 100)
+	bondTx.AddOutput(unbondTo.Address, 90)
+	bondTx.SignInput(state.ChainID, 0, bonder)
+	bondTx.SignBond(state.ChainID, bonder)
+	if err := execTxWithStateNewBlock(state, bondTx, true); err != nil {
+		t.Fatalf("Got error in executing bond transaction, %v", err)
+	}
+	valInfo := state.GetValidatorInfo(bonder.Address)
+	if valInfo == nil || !valInfo.Bonded() || valInfo.VotingPower() != 90 {
+		t.Fatalf("Expected validator bonded with power 90, got %v", valInfo)
+	}
+	if newBalance := state.GetAccount(bonder.Address).Balance; newBalance != balance-100 {
+		t.Errorf("Expected balance %v after bonding, got %v", balance-100, newBalance)
+	}
+	if err := execTxWithState(state, bondTx, true); err == nil {
+		t.Errorf("Expected error bonding an existing validator")
+	}
+
+	// Unbonding at or before the bond height could replay an old unbond
+	unbondTx := txs.NewUnbondTx(bonder.Address, state.LastBlockHeight)
+	unbondTx.Sign(state.ChainID, bonder)
+	if err := execTxWithState(state, unbondTx, true); err == nil {
+		t.Errorf("Expected error unbonding at the bond height")
+	}
+	unbondTx = txs.NewUnbondTx(bonder.Address, state.LastBlockHeight+1)
+	unbondTx.Sign(state.ChainID, bonder)
+	if err := execTxWithStateNewBlock(state, unbondTx, true); err != nil {
+		t.Fatalf("Got error in executing unbond transaction, %v", err)
+	}
+	if valInfo := state.GetValidatorInfo(bonder.Address); valInfo.Bonded() || valInfo.VotingPower() != 0 {
+		t.Fatalf("Expected validator unbonded, got %v", valInfo)
+	}
+
+	rebondTx := txs.NewRebondTx(bonder.Address, state.LastBlockHeight+1)
+	rebondTx.Sign(state.ChainID, bonder)
+	if err := execTxWithStateNewBlock(state, rebondTx, true); err != nil {
+		t.Fatalf("Got error in executing rebond transaction, %v", err)
+	}
+	if valInfo := state.GetValidatorInfo(bonder.Address); !valInfo.Bonded() {
+		t.Fatalf("Expected validator rebonded, got %v", valInfo)
+	}
+	if err := execTxWithState(state, unbondTx, true); err == nil {
+		t.Errorf("Expected error replaying unbond after rebonding")
+	}
+}
+
+func TestReleaseValidators(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, true, 1000, 1, true, 1000)
+	unbondTo := privAccounts[1]
+	balance := state.GetAccount(unbondTo.Address).Balance
+	state.UpdateValidatorInfo(&ValidatorInfo{
+		Address:         privAccounts[0].Address,
+		PubKey:          privAccounts[0].PubKey,
+		UnbondTo:        []*txs.TxOutput{{Address: unbondTo.Address, Amount: 90}},
+		FirstBondAmount: 90,
+		BondHeight:      1,
+		UnbondHeight:    2,
+	})
+
+	cache := NewBlockCache(state)
+	ReleaseValidators(cache, 1+unbondingPeriodBlocks)
+	if len(cache.ValidatorInfoUpdates()) != 0 {
+		t.Fatalf("Expected no validator released before the unbonding period")
+	}
+	ReleaseValidators(cache, 2+unbondingPeriodBlocks)
+	cache.Sync()
+	if valInfo := state.GetValidatorInfo(privAccounts[0].Address); valInfo.ReleasedHeight != 2+unbondingPeriodBlocks {
+		t.Errorf("Expected validator released, got %v", valInfo)
+	}
+	if newBalance := state.GetAccount(unbondTo.Address).Balance; newBalance != balance+90 {
+		t.Errorf("Expected balance %v after release, got %v", balance+90, newBalance)
+	}
+}
+
+func TestDupeoutTx(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(1, true, 1000, 1, true, 1000)
+	accused := privAccounts[0]
+	state.UpdateValidatorInfo(&ValidatorInfo{
+		Address:         accused.Address,
+		PubKey:          accused.PubKey,
+		FirstBondAmount: 100,
+	})
+	signVote := func(blockHash []byte) tmtypes.Vote {
+		vote := tmtypes.Vote{
+			ValidatorAddress: accused.Address,
+			Height:           1,
+			Type:             2,
+			BlockID:          tmtypes.BlockID{Hash: blockHash},
+		}
+		vote.Signature = accused.Sign(state.ChainID, &vote).(crypto.SignatureEd25519)
+		return vote
+	}
+
+	tx := &txs.DupeoutTx{Address: accused.Address, VoteA: signVote([]byte("a")),
+		VoteB: signVote([]byte("a"))}
+	if err := execTxWithState(state, tx, true); err == nil {
+		t.Errorf("Expected error for votes for the same block")
+	}
+	tx.VoteB = signVote([]byte("b"))
+	tx.VoteB.Signature = tx.VoteA.Signature
+	if err := execTxWithState(state, tx, true); err != txs.ErrTxInvalidSignature {
+		t.Errorf("Expected invalid signature error, got %v", err)
+	}
+
+	tx.VoteB = signVote([]byte("b"))
+	if err := execTxWithState(state, tx, true); err != nil {
+		t.Fatalf("Got error in executing dupeout transaction, %v", err)
+	}
+	valInfo := state.GetValidatorInfo(accused.Address)
+	if valInfo.VotingPower() != 0 || valInfo.DestroyedAmount != 100 {
+		t.Errorf("Expected validator destroyed, got %v", valInfo)
+	}
+	if err := execTxWithState(state, tx, true); err != txs.ErrTxInvalidAddress {
+		t.Errorf("Expected error destroying a destroyed validator, got %v", err)
+	}
+}
+
+func TestMinFees(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, true, 1000, 1, true, 1000)
+	state.minFees.SendTx = 10
+	acc0 := state.GetAccount(privAccounts[0].Address)
+	acc1 := state.GetAccount(privAccounts[1].Address)
+	sendTx := func(fee int64) *txs.SendTx {
+		tx := txs.NewSendTx()
+		tx.AddInputWithNonce(privAccounts[0].PubKey, 100+fee, acc0.Sequence+1)
+		tx.AddOutput(acc1.Address, 100)
+		tx.Inputs[0].Signature = privAccounts[0].Sign(state.ChainID, tx)
+		return tx
+	}
+
+	cache := NewBlockCache(state)
+	if err := ExecTx(cache, sendTx(9), true, nil); ErrorCodeOf(err) != ErrorCodeInsufficientFee {
+		t.Errorf("Expected insufficient fee error, got %v", err)
+	}
+	if err := ExecTx(cache, sendTx(10), true, nil); err != nil {
+		t.Fatalf("Got error in executing send transaction, %v", err)
+	}
+	if cache.Fees() != 10 {
+		t.Errorf("Expected fees of 10 taken, got %v", cache.Fees())
+	}
+	if balance := cache.GetAccount(acc0.Address).Balance; balance != acc0.Balance-110 {
+		t.Errorf("Expected balance %v after paying the fee, got %v", acc0.Balance-110, balance)
+	}
+}
+
+func TestGasPrice(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(1, true, 1000000, 1, true, 1000)
+	state.minGasPrice = 2
+	caller := state.GetAccount(privAccounts[0].Address)
+	// PUSH1 1, PUSH1 0, SSTORE, STOP
+	contract := &acm.Account{
+		Address: word256.Int64ToWord256(1234).Postfix(20),
+		Code:    []byte{0x60, 0x01, 0x60, 0x00, 0x55, 0x00},
+	}
+	state.UpdateAccount(contract)
+	var gasLimit, fee int64 = 100000, 10
+	callTx := func(gasPrice int64) *txs.CallTx {
+		tx := txs.NewCallTxWithNonce(privAccounts[0].PubKey, contract.Address, nil,
+			fee+gasLimit*gasPrice, gasLimit, fee, caller.Sequence+1)
+		tx.GasPrice = gasPrice
+		tx.Sign(state.ChainID, privAccounts[0])
+		return tx
+	}
+
+	cache := NewBlockCache(state)
+	if err := ExecTx(cache, callTx(1), true, nil); ErrorCodeOf(err) != ErrorCodeInsufficientGasPrice {
+		t.Errorf("Expected insufficient gas price error, got %v", err)
+	}
+	tx := callTx(2)
+	tx.Input.Amount -= 1
+	tx.Sign(state.ChainID, privAccounts[0])
+	if err := ExecTx(cache, tx, true, nil); err != txs.ErrTxInsufficientFunds {
+		t.Errorf("Expected insufficient funds for the gas limit, got %v", err)
+	}
+	result, err := TraceTx(cache, callTx(2), nil, nil)
+	if err != nil {
+		t.Fatalf("Got error in executing call transaction, %v", err)
+	}
+	gasUsed := result.GasUsed
+	if gasUsed == 0 || gasUsed >= gasLimit {
+		t.Fatalf("Expected some but not all gas used, got %v", gasUsed)
+	}
+	// The unused gas is refunded and the used gas taken with the fee
+	if balance := cache.GetAccount(caller.Address).Balance; balance != caller.Balance-fee-gasUsed*2 {
+		t.Errorf("Expected balance %v after the call, got %v", caller.Balance-fee-gasUsed*2, balance)
+	}
+	if cache.Fees() != fee+gasUsed*2 {
+		t.Errorf("Expected fees of %v taken, got %v", fee+gasUsed*2, cache.Fees())
+	}
+
+	// Gas left outside the gas limit refunds no more than was paid for it
+	cache = NewBlockCache(state)
+	tx = callTx(1000)
+	gasCost := gasLimit * tx.GasPrice
+	if gasLeft := chargeGas(cache, tx, gasCost, 2*gasLimit); gasLeft != gasLimit {
+		t.Errorf("Expected gas left of %v, got %v", gasLimit, gasLeft)
+	}
+	if balance := cache.GetAccount(caller.Address).Balance; balance != caller.Balance+gasCost {
+		t.Errorf("Expected balance %v after the refund, got %v", caller.Balance+gasCost, balance)
+	}
+	if gasLeft := chargeGas(cache, tx, gasCost, -gasLimit); gasLeft != 0 {
+		t.Errorf("Expected no gas left, got %v", gasLeft)
+	}
+	if cache.Fees() != gasCost {
+		t.Errorf("Expected fees of %v taken, got %v", gasCost, cache.Fees())
+	}
+}
+
+func TestDistributeFees(t *testing.T) {
+	state, privAccounts, privValidators := RandGenesisState(1, true, 1000, 3, false, 10)
+	cache := NewBlockCache(state)
+	cache.AddFee(100)
+	if fees := DistributeFees(cache); fees != 100 {
+		t.Errorf("Expected 100 fees distributed, got %v", fees)
+	}
+	if cache.Fees() != 0 {
+		t.Errorf("Expected no fees left after distributing, got %v", cache.Fees())
+	}
+	// Powers are equal so the lowest address takes the remainder
+	for i, privVal := range privValidators {
+		expected := int64(33)
+		if i == 0 {
+			expected = 34
+		}
+		if balance := cache.GetAccount(privVal.Address).Balance; balance != expected {
+			t.Errorf("Expected validator %X paid %v, got %v", privVal.Address, expected, balance)
+		}
+	}
+	cache.Sync()
+	state.FinishBlock()
+	state.Save()
+	if fees := state.GetBlockFees(state.LastBlockHeight); fees != 100 {
+		t.Errorf("Expected fees of 100 stored for the block, got %v", fees)
+	}
+
+	treasury := privAccounts[0]
+	balance := state.GetAccount(treasury.Address).Balance
+	state.treasury = treasury.Address
+	cache = NewBlockCache(state)
+	cache.AddFee(50)
+	DistributeFees(cache)
+	if newBalance := cache.GetAccount(treasury.Address).Balance; newBalance != balance+50 {
+		t.Errorf("Expected treasury balance %v, got %v", balance+50, newBalance)
+	}
+
+	// Shares are worked out without overflowing however large the fees
+	state, _, privValidators = RandGenesisState(1, true, 1000, 3, false, 10)
+	cache = NewBlockCache(state)
+	fees := int64(math.MaxInt64 / 4)
+	cache.AddFee(fees)
+	DistributeFees(cache)
+	for i, privVal := range privValidators {
+		expected := fees / 3
+		if i == 0 {
+			expected += fees % 3
+		}
+		if balance := cache.GetAccount(privVal.Address).Balance; balance != expected {
+			t.Errorf("Expected validator %X paid %v, got %v", privVal.Address, expected, balance)
+		}
+	}
+}
+
 func TestTxSequence(t *testing.T) {
 
 	state, privAccounts, _ := RandGenesisState(3, true, 1000, 1, true, 1000)
@@ -396,6 +764,72 @@ func TestNameTxs(t *testing.T) {
 	if entry != nil {
 		t.Fatal("Expected removed entry to be nil")
 	}
+
+	// contract ABIs must parse
+	createTx := txs.NewCallTxWithNonce(privAccounts[0].PubKey, nil, []byte{0x00}, 1, 1000, 0,
+		state.GetAccount(privAccounts[0].Address).Sequence+1)
+	createTx.Sign(state.ChainID, privAccounts[0])
+	if err := execTxWithState(state, createTx, true); err != nil {
+		t.Fatal(err)
+	}
+	contract := NewContractAddress(privAccounts[0].Address, createTx.Input.Sequence)
+	name = txs.ContractABIName(contract)
+	data = `[{"type":"function","name":"f","inputs":[{"name":"x","type":"uint7"}]}]`
+	amt = fee + int64(numDesiredBlocks)*txs.NameByteCostMultiplier*txs.NameBlockCostMultiplier*txs.NameBaseCost(name, data)
+	tx, _ = txs.NewNameTx(state, privAccounts[0].PubKey, name, data, amt, fee)
+	tx.Sign(state.ChainID, privAccounts[0])
+	if err := execTxWithState(state, tx, true); err == nil {
+		t.Fatalf("Expected invalid ABI error from %s", data)
+	}
+
+	// and may only be registered by the contract or its creator
+	data = `[{"type":"function","name":"f","inputs":[{"name":"x","type":"uint8"}]}]`
+	amt = fee + int64(numDesiredBlocks)*txs.NameByteCostMultiplier*txs.NameBlockCostMultiplier*txs.NameBaseCost(name, data)
+	tx, _ = txs.NewNameTx(state, privAccounts[1].PubKey, name, data, amt, fee)
+	tx.Sign(state.ChainID, privAccounts[1])
+	if err := execTxWithState(state, tx, true); err == nil {
+		t.Fatal("Expected an error registering the ABI of another's contract")
+	}
+	tx, _ = txs.NewNameTx(state, privAccounts[0].PubKey, name, data, amt, fee)
+	tx.Sign(state.ChainID, privAccounts[0])
+	if err := execTxWithState(state, tx, true); err != nil {
+		t.Fatal(err)
+	}
+	entry = state.GetNameRegEntry(name)
+	validateEntry(t, entry, name, data, privAccounts[0].Address, state.LastBlockHeight+numDesiredBlocks)
+
+	// creators are part of the saved state and its hash
+	if !bytes.Equal(state.GetContractCreator(contract), privAccounts[0].Address) {
+		t.Fatalf("Expected %X to be recorded as the creator of %X", privAccounts[0].Address, contract)
+	}
+	if bytes.Equal(state.Hash(), AppHash(state.accounts.Hash(), state.validatorInfos.Hash(),
+		state.nameReg.Hash(), nil)) {
+		t.Fatal("Expected the contract creators to be part of the app hash")
+	}
+	state.Save()
+	if loaded := LoadState(state.DB); !bytes.Equal(loaded.GetContractCreator(contract),
+		privAccounts[0].Address) || !bytes.Equal(loaded.Hash(), state.Hash()) {
+		t.Fatal("Expected the contract creators to be loaded with the state")
+	}
+
+	// the ABI of a contract with no recorded creator, as at genesis, may be
+	// registered by a root account
+	genesisContract := privAccounts[2].Address
+	name = txs.ContractABIName(genesisContract)
+	amt = fee + int64(numDesiredBlocks)*txs.NameByteCostMultiplier*txs.NameBlockCostMultiplier*txs.NameBaseCost(name, data)
+	tx, _ = txs.NewNameTx(state, privAccounts[1].PubKey, name, data, amt, fee)
+	tx.Sign(state.ChainID, privAccounts[1])
+	if err := execTxWithState(state, tx, true); err == nil {
+		t.Fatal("Expected an error registering the ABI of a contract without root")
+	}
+	rootAcc := state.GetAccount(privAccounts[1].Address)
+	rootAcc.Permissions.Base.Set(ptypes.Root, true)
+	state.UpdateAccount(rootAcc)
+	tx, _ = txs.NewNameTx(state, privAccounts[1].PubKey, name, data, amt, fee)
+	tx.Sign(state.ChainID, privAccounts[1])
+	if err := execTxWithState(state, tx, true); err != nil {
+		t.Fatal(err)
+	}
 }
 
 // Test creating a contract from futher down the call stack
@@ -798,6 +1232,48 @@ func TestSuicide(t *testing.T) {
 
 }
 
+func TestTraceTx(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, true, 1000, 1, true, 1000)
+
+	acc0 := state.GetAccount(privAccounts[0].PubKey.Address())
+	acc0PubKey := privAccounts[0].PubKey
+	acc1 := state.GetAccount(privAccounts[1].PubKey.Address())
+
+	// store 0x1 at 0x1
+	newAcc1 := state.GetAccount(acc1.Address)
+	newAcc1.Code = []byte{0x60, 0x01, 0x60, 0x01, 0x55, 0x00}
+	state.UpdateAccount(newAcc1)
+
+	tx := txs.NewCallTxWithNonce(acc0PubKey, acc1.Address, nil, 1, 1000, 0, acc0.Sequence+1)
+	tx.Input.Signature = privAccounts[0].Sign(state.ChainID, tx)
+
+	structLogger := evm.NewStructLogger(0)
+	result, err := TraceTx(NewBlockCache(state), tx, nil, structLogger)
+	if err != nil {
+		t.Fatalf("Got error in tracing call transaction, %v", err)
+	}
+	if result == nil || result.Exception != nil || result.GasUsed <= 0 {
+		t.Fatalf("Unexpected result of traced call %v", result)
+	}
+	logs := structLogger.StructLogs()
+	if len(logs) != 4 || logs[2].Op != "SSTORE" {
+		t.Fatalf("Expected 4 ops ending SSTORE, STOP, got %v", logs)
+	}
+	if len(logs[2].Storage) != 1 || !logs[2].Storage[0].Write {
+		t.Errorf("Expected SSTORE to log a storage write, got %v", logs[2].Storage)
+	}
+
+	// Sends do not run the VM
+	sendTx := txs.NewSendTx()
+	sendTx.AddInputWithNonce(acc0PubKey, 1, acc0.Sequence+1)
+	sendTx.AddOutput(acc1.Address, 1)
+	sendTx.SignInput(state.ChainID, 0, privAccounts[0])
+	result, err = TraceTx(NewBlockCache(state), sendTx, nil, evm.NewStructLogger(0))
+	if err != nil || result != nil {
+		t.Errorf("Expected no result from tracing a send, got %v, %v", result, err)
+	}
+}
+
 /* TODO
 func TestAddValidator(t *testing.T) {
 
@@ -15,10 +15,14 @@
 package state
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"sort"
 
 	acm "github.com/hyperledger/burrow/account"
 	"github.com/hyperledger/burrow/common/sanity"
+	core_types "github.com/hyperledger/burrow/core/types"
 	"github.com/hyperledger/burrow/manager/burrow-mint/evm"
 	ptypes "github.com/hyperledger/burrow/permission/types" // for GlobalPermissionAddress ...
 	"github.com/hyperledger/burrow/txs"
@@ -31,15 +35,37 @@ type TxCache struct {
 	backend  *BlockCache
 	accounts map[Word256]vmAccountInfo
 	storages map[Tuple256]Word256
+	// Name registry entries written through the NameReg SNative, nil if removed
+	names map[string]*core_types.NameRegEntry
+	// Validators bonded or unbonded through the Validators SNative
+	validatorInfos map[string]*ValidatorInfo
+	// Creators of the contracts created, which are not rolled back with the
+	// contracts since a contract's address is derived from its creator's
+	creators map[Word256][]byte
+	// The BondTxs standing for the validators bonded through the Validators
+	// SNative, which are dropped when the bond is rolled back
+	bondTxs map[string]*txs.BondTx
+
+	// Undo log of changes to accounts, storages, names and validators, used to
+	// roll back to a snapshot
+	journal   []txCacheChange
+	snapshots []txCacheSnapshot
 }
 
-var _ vm.AppState = &TxCache{}
+var _ vm.SnapshotAppState = &TxCache{}
+var _ vm.NameRegAppState = &TxCache{}
+var _ vm.ValidatorAppState = &TxCache{}
 
 func NewTxCache(backend *BlockCache) *TxCache {
 	return &TxCache{
 		backend:  backend,
 		accounts: make(map[Word256]vmAccountInfo),
 		storages: make(map[Tuple256]Word256),
+		names:    make(map[string]*core_types.NameRegEntry),
+
+		validatorInfos: make(map[string]*ValidatorInfo),
+		creators:       make(map[Word256][]byte),
+		bondTxs:        make(map[string]*txs.BondTx),
 	}
 }
 
@@ -65,7 +91,7 @@ func (cache *TxCache) UpdateAccount(acc *vm.Account) {
 	if removed {
 		sanity.PanicSanity("UpdateAccount on a removed account")
 	}
-	cache.accounts[addr] = vmAccountInfo{acc, false}
+	cache.setAccountInfo(addr, vmAccountInfo{acc, false})
 }
 
 func (cache *TxCache) RemoveAccount(acc *vm.Account) {
@@ -74,7 +100,7 @@ func (cache *TxCache) RemoveAccount(acc *vm.Account) {
 	if removed {
 		sanity.PanicSanity("RemoveAccount on a removed account")
 	}
-	cache.accounts[addr] = vmAccountInfo{acc, true}
+	cache.setAccountInfo(addr, vmAccountInfo{acc, true})
 }
 
 // Creates a 20 byte address and bumps the creator's nonce.
@@ -89,19 +115,8 @@ func (cache *TxCache) CreateAccount(creator *vm.Account) *vm.Account {
 	// Create account from address.
 	account, removed := cache.accounts[addr].unpack()
 	if removed || account == nil {
-		account = &vm.Account{
-			Address:     addr,
-			Balance:     0,
-			Code:        nil,
-			Nonce:       0,
-			Permissions: cache.GetAccount(ptypes.GlobalPermissionsAddress256).Permissions,
-			Other: vmAccountOther{
-				PubKey:      nil,
-				StorageRoot: nil,
-			},
-		}
-		cache.accounts[addr] = vmAccountInfo{account, false}
-		return account
+		cache.creators[addr] = creator.Address.Postfix(20)
+		return cache.createAccount(addr)
 	} else {
 		// either we've messed up nonce handling, or sha3 is broken
 		sanity.PanicSanity(fmt.Sprintf("Could not create account, address already exists: %X", addr))
@@ -109,6 +124,44 @@ func (cache *TxCache) CreateAccount(creator *vm.Account) *vm.Account {
 	}
 }
 
+// Creates an account at the CREATE2 address and bumps the creator's nonce.
+// Unlike CreateAccount the address can be chosen by the creator so finding an
+// account already there is not a sanity failure; we return nil.
+func (cache *TxCache) CreateAccount2(creator *vm.Account, salt Word256, initCode []byte) *vm.Account {
+	creator.Nonce += 1
+
+	addr := LeftPadWord256(NewContractAddress2(creator.Address.Postfix(20), salt.Bytes(), initCode))
+
+	if cache.GetAccount(addr) != nil {
+		return nil
+	}
+	cache.creators[addr] = creator.Address.Postfix(20)
+	return cache.createAccount(addr)
+}
+
+func (cache *TxCache) createAccount(addr Word256) *vm.Account {
+	account := &vm.Account{
+		Address:     addr,
+		Balance:     0,
+		Code:        nil,
+		Nonce:       0,
+		Permissions: cache.GetAccount(ptypes.GlobalPermissionsAddress256).Permissions,
+		Other: vmAccountOther{
+			PubKey:      nil,
+			StorageRoot: nil,
+		},
+	}
+	cache.setAccountInfo(addr, vmAccountInfo{account, false})
+	return account
+}
+
+func (cache *TxCache) GetContractCreator(addr []byte) []byte {
+	if creator, ok := cache.creators[LeftPadWord256(addr)]; ok {
+		return creator
+	}
+	return cache.backend.GetContractCreator(addr)
+}
+
 // TxCache.account
 //-------------------------------------
 // TxCache.storage
@@ -130,11 +183,268 @@ func (cache *TxCache) SetStorage(addr Word256, key Word256, value Word256) {
 	if removed {
 		sanity.PanicSanity("SetStorage() on a removed account")
 	}
-	cache.storages[Tuple256{addr, key}] = value
+	addrKey := Tuple256{addr, key}
+	prevValue, existed := cache.storages[addrKey]
+	cache.journal = append(cache.journal, txCacheChange{
+		storageKey: &addrKey,
+		storage:    prevValue,
+		existed:    existed,
+	})
+	cache.storages[addrKey] = value
 }
 
 // TxCache.storage
 //-------------------------------------
+// TxCache.names
+
+// Expired entries are not returned, though they are still there to be
+// reclaimed by UpdateNameRegEntry
+func (cache *TxCache) GetNameRegEntry(name string) *core_types.NameRegEntry {
+	entry := cache.getNameRegEntry(name)
+	if entry == nil || entry.Expires <= cache.backend.State().LastBlockHeight {
+		return nil
+	}
+	return entry
+}
+
+func (cache *TxCache) UpdateNameRegEntry(owner Word256, name, data string,
+	value int64) (*core_types.NameRegEntry, error) {
+	if err := validateNameRegStrings(name, data); err != nil {
+		return nil, err
+	}
+	if err := validateContractABIOwner(cache, cache.backend, owner.Postfix(20), name); err != nil {
+		return nil, err
+	}
+	entry, err := updateNameRegEntry(cache.getNameRegEntry(name), owner.Postfix(20), name, data,
+		value, cache.backend.State().LastBlockHeight)
+	if err != nil {
+		return nil, err
+	}
+	prevEntry, existed := cache.names[name]
+	cache.journal = append(cache.journal, txCacheChange{
+		name:      &name,
+		nameEntry: prevEntry,
+		existed:   existed,
+	})
+	cache.names[name] = entry
+	return entry, nil
+}
+
+func (cache *TxCache) getNameRegEntry(name string) *core_types.NameRegEntry {
+	if entry, ok := cache.names[name]; ok {
+		return entry
+	}
+	return cache.backend.GetNameRegEntry(name)
+}
+
+// TxCache.names
+//-------------------------------------
+// TxCache.validators
+
+func (cache *TxCache) GetValidators() []Word256 {
+	// Validators bonded in this block are not yet in the state
+	addrStrs := make(map[string]bool)
+	cache.backend.State().validatorInfos.Iterate(func(address, _ []byte) bool {
+		addrStrs[string(address)] = true
+		return false
+	})
+	for _, valInfo := range cache.backend.ValidatorInfoUpdates() {
+		addrStrs[string(valInfo.Address)] = true
+	}
+	for addrStr := range cache.validatorInfos {
+		addrStrs[addrStr] = true
+	}
+	var bondedAddrStrs []string
+	for addrStr := range addrStrs {
+		if cache.getValidatorInfo([]byte(addrStr)).Bonded() {
+			bondedAddrStrs = append(bondedAddrStrs, addrStr)
+		}
+	}
+	sort.Strings(bondedAddrStrs)
+	addresses := make([]Word256, len(bondedAddrStrs))
+	for i, addrStr := range bondedAddrStrs {
+		addresses[i] = LeftPadWord256([]byte(addrStr))
+	}
+	return addresses
+}
+
+func (cache *TxCache) GetVotingPower(address Word256) int64 {
+	valInfo := cache.getValidatorInfo(address.Postfix(20))
+	if valInfo == nil {
+		return 0
+	}
+	return valInfo.VotingPower()
+}
+
+// Follows the rules of a BondTx, with the bond paid by and released to owner.
+// The validator proves it holds its key by signing the BondTx standing for the
+// bond, which cannot be replayed since validators are never bonded twice.
+func (cache *TxCache) BondValidator(owner Word256, pubKey Word256, amount int64,
+	signature []byte) (Word256, error) {
+	validatorPubKey := crypto.PubKeyEd25519(pubKey)
+	address := validatorPubKey.Address()
+	bondTx := txs.NewSNativeBondTx(validatorPubKey, owner.Postfix(20), amount)
+	if len(signature) != len(bondTx.Signature) {
+		return Zero256, txs.ErrTxInvalidSignature
+	}
+	copy(bondTx.Signature[:], signature)
+	if !validatorPubKey.VerifyBytes(acm.SignBytes(cache.backend.State().ChainID, bondTx), bondTx.Signature) {
+		return Zero256, txs.ErrTxInvalidSignature
+	}
+	if cache.getValidatorInfo(address) != nil {
+		return Zero256, errors.New("Adding coins to existing validators not yet supported")
+	}
+	if amount < minBondAmount {
+		return Zero256, execErrorf(ErrorCodeInvalidInput, "Bond of %v is less than the minimum of %v", amount, minBondAmount)
+	}
+	var bondAcc *acm.Account
+	if vmAcc := cache.GetAccount(LeftPadWord256(address)); vmAcc != nil {
+		bondAcc = toStateAccount(vmAcc)
+	}
+	if !hasBondPermission(cache.backend, bondAcc) {
+		return Zero256, execErrorf(ErrorCodePermissionDenied, "The bonder does not have permission to bond")
+	}
+	height := cache.backend.State().LastBlockHeight + 1
+	cache.setValidatorInfo(&ValidatorInfo{
+		Address:         address,
+		PubKey:          validatorPubKey,
+		UnbondTo:        []*txs.TxOutput{{Address: owner.Postfix(20), Amount: amount}},
+		FirstBondHeight: height,
+		FirstBondAmount: amount,
+		BondHeight:      height,
+	})
+	cache.bondTxs[string(address)] = bondTx
+	return LeftPadWord256(address), nil
+}
+
+// Returns the BondTxs standing for the bonds made through the Validators
+// SNative that have not been rolled back, in order of validator address
+func (cache *TxCache) BondTxs() []*txs.BondTx {
+	addrStrs := make([]string, 0, len(cache.bondTxs))
+	for addrStr := range cache.bondTxs {
+		if _, ok := cache.validatorInfos[addrStr]; ok {
+			addrStrs = append(addrStrs, addrStr)
+		}
+	}
+	sort.Strings(addrStrs)
+	bondTxs := make([]*txs.BondTx, len(addrStrs))
+	for i, addrStr := range addrStrs {
+		bondTxs[i] = cache.bondTxs[addrStr]
+	}
+	return bondTxs
+}
+
+func (cache *TxCache) UnbondValidator(owner Word256, address Word256, anyOwner bool) error {
+	valInfo := cache.getValidatorInfo(address.Postfix(20))
+	if valInfo == nil || !valInfo.Bonded() {
+		return txs.ErrTxInvalidAddress
+	}
+	if !anyOwner && (len(valInfo.UnbondTo) != 1 ||
+		!bytes.Equal(valInfo.UnbondTo[0].Address, owner.Postfix(20))) {
+		return execErrorf(ErrorCodePermissionDenied, "Validator %X was not bonded by %X",
+			valInfo.Address, owner.Postfix(20))
+	}
+	// Copied so that reverting to a snapshot restores the original
+	valInfoCopy := *valInfo
+	valInfoCopy.UnbondHeight = cache.backend.State().LastBlockHeight + 1
+	cache.setValidatorInfo(&valInfoCopy)
+	return nil
+}
+
+func (cache *TxCache) getValidatorInfo(address []byte) *ValidatorInfo {
+	if valInfo, ok := cache.validatorInfos[string(address)]; ok {
+		return valInfo
+	}
+	return cache.backend.GetValidatorInfo(address)
+}
+
+func (cache *TxCache) setValidatorInfo(valInfo *ValidatorInfo) {
+	addrStr := string(valInfo.Address)
+	prevValInfo, existed := cache.validatorInfos[addrStr]
+	cache.journal = append(cache.journal, txCacheChange{
+		validator:     &addrStr,
+		validatorInfo: prevValInfo,
+		existed:       existed,
+	})
+	cache.validatorInfos[addrStr] = valInfo
+}
+
+// TxCache.validators
+//-------------------------------------
+// TxCache.snapshot
+
+// Snapshot records the pending changes so that they can be restored with
+// RevertToSnapshot. Since the VM mutates the accounts it is handed directly
+// we also keep a copy of every account in the cache.
+func (cache *TxCache) Snapshot() int {
+	accounts := make(map[*vm.Account]vm.Account, len(cache.accounts))
+	for _, accInfo := range cache.accounts {
+		if accInfo.account != nil {
+			accounts[accInfo.account] = *accInfo.account
+		}
+	}
+	cache.snapshots = append(cache.snapshots, txCacheSnapshot{
+		journalLength: len(cache.journal),
+		accounts:      accounts,
+	})
+	return len(cache.snapshots) - 1
+}
+
+// RevertToSnapshot discards all changes made since the snapshot was taken
+// (along with any snapshots taken since).
+func (cache *TxCache) RevertToSnapshot(snapshot int) {
+	if snapshot < 0 || snapshot >= len(cache.snapshots) {
+		sanity.PanicSanity(fmt.Sprintf("Invalid TxCache snapshot %v", snapshot))
+	}
+	snap := cache.snapshots[snapshot]
+	// Undo changes in reverse order
+	for i := len(cache.journal) - 1; i >= snap.journalLength; i-- {
+		change := cache.journal[i]
+		if change.storageKey != nil {
+			if change.existed {
+				cache.storages[*change.storageKey] = change.storage
+			} else {
+				delete(cache.storages, *change.storageKey)
+			}
+		} else if change.name != nil {
+			if change.existed {
+				cache.names[*change.name] = change.nameEntry
+			} else {
+				delete(cache.names, *change.name)
+			}
+		} else if change.validator != nil {
+			if change.existed {
+				cache.validatorInfos[*change.validator] = change.validatorInfo
+			} else {
+				delete(cache.validatorInfos, *change.validator)
+			}
+		} else {
+			if change.existed {
+				cache.accounts[change.address] = change.account
+			} else {
+				delete(cache.accounts, change.address)
+			}
+		}
+	}
+	for acc, accCopy := range snap.accounts {
+		*acc = accCopy
+	}
+	cache.journal = cache.journal[:snap.journalLength]
+	cache.snapshots = cache.snapshots[:snapshot]
+}
+
+func (cache *TxCache) setAccountInfo(addr Word256, accInfo vmAccountInfo) {
+	prevAccInfo, existed := cache.accounts[addr]
+	cache.journal = append(cache.journal, txCacheChange{
+		address: addr,
+		account: prevAccInfo,
+		existed: existed,
+	})
+	cache.accounts[addr] = accInfo
+}
+
+// TxCache.snapshot
+//-------------------------------------
 
 // These updates do not have to be in deterministic order,
 // the backend is responsible for ordering updates.
@@ -154,6 +464,24 @@ func (cache *TxCache) Sync() {
 			cache.backend.UpdateAccount(toStateAccount(acc))
 		}
 	}
+
+	// Remove or update names
+	for name, entry := range cache.names {
+		if entry != nil {
+			cache.backend.UpdateNameRegEntry(entry)
+		} else if cache.backend.GetNameRegEntry(name) != nil {
+			cache.backend.RemoveNameRegEntry(name)
+		}
+	}
+
+	// Update validators, which the backend passes on at the end of the block
+	for _, valInfo := range cache.validatorInfos {
+		cache.backend.UpdateValidatorInfo(valInfo)
+	}
+
+	for addr, creator := range cache.creators {
+		cache.backend.SetContractCreator(addr.Postfix(20), creator)
+	}
 }
 
 //-----------------------------------------------------------------------------
@@ -163,6 +491,11 @@ func NewContractAddress(caller []byte, nonce int) []byte {
 	return txs.NewContractAddress(caller, nonce)
 }
 
+// Convenience function to return address of new CREATE2 contract
+func NewContractAddress2(caller []byte, salt []byte, initCode []byte) []byte {
+	return txs.NewContractAddress2(caller, salt, initCode)
+}
+
 // Converts backend.Account to vm.Account struct.
 func toVMAccount(acc *acm.Account) *vm.Account {
 	return &vm.Account{
@@ -216,3 +549,24 @@ type vmAccountInfo struct {
 func (accInfo vmAccountInfo) unpack() (*vm.Account, bool) {
 	return accInfo.account, accInfo.removed
 }
+
+// A single change to a TxCache recording the value it replaced. Either
+// storageKey is set and storage holds the previous value, name is set and
+// nameEntry holds the previous entry, validator is set and validatorInfo holds
+// the previous info or address is set and account holds the previous account.
+type txCacheChange struct {
+	storageKey    *Tuple256
+	storage       Word256
+	name          *string
+	nameEntry     *core_types.NameRegEntry
+	validator     *string
+	validatorInfo *ValidatorInfo
+	address       Word256
+	account       vmAccountInfo
+	existed       bool
+}
+
+type txCacheSnapshot struct {
+	journalLength int
+	accounts      map[*vm.Account]vm.Account
+}
@@ -18,6 +18,14 @@ import (
 	"bytes"
 	"testing"
 
+	acm "github.com/hyperledger/burrow/account"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
+	ptypes "github.com/hyperledger/burrow/permission/types"
+	"github.com/hyperledger/burrow/txs"
+	. "github.com/hyperledger/burrow/word256"
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/go-crypto"
 	"github.com/tendermint/go-wire"
 )
 
@@ -34,3 +42,262 @@ func TestStateToFromVMAccount(t *testing.T) {
 	}
 
 }
+
+func TestTxCacheSnapshot(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, false, 1000, 1, false, 1000)
+	txCache := NewTxCache(NewBlockCache(state))
+	acc := txCache.GetAccount(LeftPadWord256(privAccounts[0].Address))
+	txCache.UpdateAccount(acc)
+	key, value := Int64ToWord256(1), Int64ToWord256(2)
+	txCache.SetStorage(acc.Address, key, value)
+
+	snapshot := txCache.Snapshot()
+	acc.Balance -= 100
+	txCache.SetStorage(acc.Address, key, Int64ToWord256(3))
+	newAcc := txCache.CreateAccount(acc)
+	txCache.SetStorage(newAcc.Address, key, value)
+	txCache.RemoveAccount(txCache.GetAccount(LeftPadWord256(privAccounts[1].Address)))
+
+	txCache.RevertToSnapshot(snapshot)
+	assert.Equal(t, int64(1000), acc.Balance)
+	assert.Equal(t, int64(0), acc.Nonce)
+	assert.Equal(t, value, txCache.GetStorage(acc.Address, key))
+	assert.Nil(t, txCache.GetAccount(newAcc.Address))
+	assert.Equal(t, Zero256, txCache.GetStorage(newAcc.Address, key))
+	assert.NotNil(t, txCache.GetAccount(LeftPadWord256(privAccounts[1].Address)))
+}
+
+func TestTxCacheNameReg(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, false, 1000, 1, false, 1000)
+	blockCache := NewBlockCache(state)
+	txCache := NewTxCache(blockCache)
+	owner := txCache.GetAccount(LeftPadWord256(privAccounts[0].Address))
+	other := txCache.GetAccount(LeftPadWord256(privAccounts[1].Address))
+	amount := uint64(txs.NameCostPerBlock(txs.NameBaseCost("foo", "bar")) * int64(txs.MinNameRegistrationPeriod))
+	expires := uint64(state.LastBlockHeight + txs.MinNameRegistrationPeriod)
+
+	// Registering too little is refused as for a NameTx
+	_, err := callSNative(txCache, "NameReg", owner, "setName", "foo", "bar", amount-1)
+	assert.Error(t, err)
+	ret, err := callSNative(txCache, "NameReg", owner, "setName", "foo", "bar", amount)
+	assert.NoError(t, err)
+	assert.Equal(t, expires, ret)
+	assert.Equal(t, int64(1000)-int64(amount), owner.Balance)
+	ret, err = callSNative(txCache, "NameReg", owner, "getName", "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", ret)
+	ret, err = callSNative(txCache, "NameReg", owner, "owner", "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, privAccounts[0].Address, addressBytes(ret))
+	ret, err = callSNative(txCache, "NameReg", owner, "expires", "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, expires, ret)
+
+	// Only the owner can update an entry that has not expired
+	_, err = callSNative(txCache, "NameReg", other, "setName", "foo", "baz", amount)
+	assert.Equal(t, txs.ErrTxPermissionDenied, err)
+
+	// Removing an entry is undone by reverting to a snapshot
+	snapshot := txCache.Snapshot()
+	ret, err = callSNative(txCache, "NameReg", owner, "setName", "foo", "", uint64(0))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), ret)
+	ret, err = callSNative(txCache, "NameReg", owner, "getName", "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "", ret)
+	txCache.RevertToSnapshot(snapshot)
+	ret, err = callSNative(txCache, "NameReg", owner, "getName", "foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", ret)
+
+	txCache.Sync()
+	entry := blockCache.GetNameRegEntry("foo")
+	if assert.NotNil(t, entry) {
+		assert.Equal(t, "bar", entry.Data)
+		assert.Equal(t, privAccounts[0].Address, entry.Owner)
+		assert.Equal(t, int(expires), entry.Expires)
+	}
+}
+
+func TestTxCacheValidators(t *testing.T) {
+	state, privAccounts, privValidators := RandGenesisState(2, false, 1000, 1, false, 1000)
+	blockCache := NewBlockCache(state)
+	txCache := NewTxCache(blockCache)
+	owner := txCache.GetAccount(LeftPadWord256(privAccounts[0].Address))
+	other := txCache.GetAccount(LeftPadWord256(privAccounts[1].Address))
+	genesisValidator := privValidators[0].Address
+	privValidator := acm.GenPrivAccount()
+	pubKey := privValidator.PubKey.(crypto.PubKeyEd25519)
+	validator := pubKey.Address()
+
+	// The validator must sign the bond
+	bondTx := txs.NewSNativeBondTx(pubKey, privAccounts[0].Address, 100)
+	bondTx.SignBond(state.ChainID, privAccounts[1])
+	_, err := callSNative(txCache, "Validators", owner, "bond", pubKey[:], uint64(100), bondTx.Signature[:])
+	assert.Equal(t, txs.ErrTxInvalidSignature, err)
+	bondTx.SignBond(state.ChainID, privValidator)
+	_, err = callSNative(txCache, "Validators", owner, "bond", pubKey[:], uint64(200), bondTx.Signature[:])
+	assert.Equal(t, txs.ErrTxInvalidSignature, err)
+	_, err = callSNative(txCache, "Validators", other, "bond", pubKey[:], uint64(100), bondTx.Signature[:])
+	assert.Equal(t, txs.ErrTxInvalidSignature, err)
+	assert.Empty(t, txCache.BondTxs())
+
+	// Bonding is undone by reverting to a snapshot, which restores the caller
+	// as the VM has written it to the cache
+	txCache.UpdateAccount(owner)
+	snapshot := txCache.Snapshot()
+	ret, err := callSNative(txCache, "Validators", owner, "bond", pubKey[:], uint64(100), bondTx.Signature[:])
+	assert.NoError(t, err)
+	assert.Equal(t, []*txs.BondTx{bondTx}, txCache.BondTxs())
+	txCache.RevertToSnapshot(snapshot)
+	assert.Empty(t, txCache.BondTxs())
+
+	ret, err = callSNative(txCache, "Validators", owner, "bond", pubKey[:], uint64(100), bondTx.Signature[:])
+	assert.NoError(t, err)
+	assert.Equal(t, validator, addressBytes(ret))
+	assert.Equal(t, int64(900), owner.Balance)
+	assert.Equal(t, []*txs.BondTx{bondTx}, txCache.BondTxs())
+	// Validators cannot yet be topped up
+	_, err = callSNative(txCache, "Validators", owner, "bond", pubKey[:], uint64(100), bondTx.Signature[:])
+	assert.Error(t, err)
+	ret, err = callSNative(txCache, "Validators", owner, "votingPower", validator)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), ret)
+	ret, err = callSNative(txCache, "Validators", owner, "listValidators")
+	assert.NoError(t, err)
+	assert.Len(t, ret, 2)
+	assert.Contains(t, addressesBytes(ret), validator)
+	assert.Contains(t, addressesBytes(ret), genesisValidator)
+	// Reading the validators needs no permission to bond
+	other.Permissions.Base.Set(ptypes.Bond, false)
+	ret, err = callSNative(txCache, "Validators", other, "votingPower", validator)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), ret)
+	ret, err = callSNative(txCache, "Validators", other, "listValidators")
+	assert.NoError(t, err)
+	assert.Len(t, ret, 2)
+	other.Permissions.Base.Set(ptypes.Bond, true)
+
+	// Only the owner can unbond a validator, unless the caller has Root
+	_, err = callSNative(txCache, "Validators", other, "unbond", validator)
+	assert.Error(t, err)
+	_, err = callSNative(txCache, "Validators", owner, "unbond", genesisValidator)
+	assert.Error(t, err)
+
+	// Unbonding is undone by reverting to a snapshot
+	snapshot = txCache.Snapshot()
+	ret, err = callSNative(txCache, "Validators", owner, "unbond", validator)
+	assert.NoError(t, err)
+	assert.Equal(t, true, ret)
+	ret, err = callSNative(txCache, "Validators", owner, "votingPower", validator)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), ret)
+	txCache.RevertToSnapshot(snapshot)
+	ret, err = callSNative(txCache, "Validators", owner, "votingPower", validator)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), ret)
+
+	other.Permissions.Base.Set(ptypes.Root, true)
+	_, err = callSNative(txCache, "Validators", other, "unbond", genesisValidator)
+	assert.NoError(t, err)
+
+	// Synced validators are passed on at the end of the block
+	txCache.Sync()
+	valInfos := blockCache.ValidatorInfoUpdates()
+	if assert.Len(t, valInfos, 2) {
+		for _, valInfo := range valInfos {
+			if bytes.Equal(valInfo.Address, validator) {
+				assert.Equal(t, int64(100), valInfo.VotingPower())
+				assert.Equal(t, privAccounts[0].Address, valInfo.UnbondTo[0].Address)
+			} else {
+				assert.Equal(t, genesisValidator, valInfo.Address)
+				assert.Equal(t, int64(0), valInfo.VotingPower())
+			}
+		}
+	}
+}
+
+func TestTxCacheBondEvent(t *testing.T) {
+	state, privAccounts, _ := RandGenesisState(2, false, 1000, 1, false, 1000)
+	blockCache := NewBlockCache(state)
+	privValidator := acm.GenPrivAccount()
+	pubKey := privValidator.PubKey.(crypto.PubKeyEd25519)
+
+	// Bond through a contract that passes its call on to the Validators SNative
+	validators := vm.SNativeContracts()["Validators"]
+	contract := blockCache.GetAccount(privAccounts[1].Address)
+	contract.Code = callContractCode(validators.AddressBytes())
+	blockCache.UpdateAccount(contract)
+	bondTx := txs.NewSNativeBondTx(pubKey, contract.Address, 100)
+	bondTx.SignBond(state.ChainID, privValidator)
+	bond, err := validators.FunctionByName("bond")
+	assert.NoError(t, err)
+	data, err := packSNativeCall(bond, pubKey[:], uint64(100), bondTx.Signature[:])
+	assert.NoError(t, err)
+	tx, _ := txs.NewCallTx(blockCache, privAccounts[0].PubKey, contract.Address, data, 100, 10000, 100)
+	tx.Sign(state.ChainID, privAccounts[0])
+
+	// fires the event of the BondTx the bond stands for
+	ev, exception := execTxWaitEvent(t, blockCache, tx, txs.EventStringBond())
+	assert.Equal(t, "", exception)
+	assert.Equal(t, txs.EventDataTx{bondTx, nil, ""}, ev)
+}
+
+func addressBytes(value interface{}) []byte {
+	address := value.(abi.Address)
+	return address[:]
+}
+
+func addressesBytes(value interface{}) [][]byte {
+	var addresses [][]byte
+	for _, address := range value.([]interface{}) {
+		addresses = append(addresses, addressBytes(address))
+	}
+	return addresses
+}
+
+// Calls a function of an SNative, returning its decoded result
+func callSNative(appState vm.AppState, contractName string, caller *vm.Account, name string,
+	args ...interface{}) (interface{}, error) {
+	contract := vm.SNativeContracts()[contractName]
+	function, err := contract.FunctionByName(name)
+	if err != nil {
+		return nil, err
+	}
+	input, err := packSNativeCall(function, args...)
+	if err != nil {
+		return nil, err
+	}
+	gas := int64(1000)
+	output, err := contract.Dispatch(appState, caller, input, &gas)
+	if err != nil {
+		return nil, err
+	}
+	returnType, err := abi.ParseType(string(function.Return.TypeName))
+	if err != nil {
+		return nil, err
+	}
+	values, err := abi.Unpack([]abi.Argument{{Type: returnType}}, output)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// Packs the input of a call to a function of an SNative
+func packSNativeCall(function *vm.SNativeFunctionDescription, args ...interface{}) ([]byte, error) {
+	inputs := make([]abi.Argument, len(function.Args))
+	for i, arg := range function.Args {
+		var err error
+		inputs[i].Type, err = abi.ParseType(string(arg.TypeName))
+		if err != nil {
+			return nil, err
+		}
+	}
+	input, err := abi.Pack(inputs, args...)
+	if err != nil {
+		return nil, err
+	}
+	funcID := function.ID()
+	return append(funcID[:], input...), nil
+}
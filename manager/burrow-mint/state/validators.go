@@ -0,0 +1,75 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"github.com/hyperledger/burrow/common/sanity"
+	"github.com/hyperledger/burrow/txs"
+
+	"github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-wire"
+)
+
+// A validator bonded in the genesis doc or by a BondTx. Its info is kept after
+// it unbonds so that it can rebond and, once released, cannot bond again.
+type ValidatorInfo struct {
+	Address []byte        `json:"address"`
+	PubKey  crypto.PubKey `json:"pub_key"`
+	// Where the bond is sent when the validator is released
+	UnbondTo        []*txs.TxOutput `json:"unbond_to"`
+	FirstBondHeight int             `json:"first_bond_height"`
+	FirstBondAmount int64           `json:"first_bond_amount"`
+	// Height of the block of the latest bond or rebond
+	BondHeight int `json:"bond_height"`
+	// Height of the block of the latest unbond, or 0 while bonded
+	UnbondHeight int `json:"unbond_height"`
+	// Height of the block in which the bond was sent to UnbondTo
+	ReleasedHeight int `json:"released_height"`
+	// Height of the block in which the bond was destroyed for double signing,
+	// and the amount destroyed
+	DestroyedHeight int   `json:"destroyed_height"`
+	DestroyedAmount int64 `json:"destroyed_amount"`
+}
+
+func (valInfo *ValidatorInfo) Bonded() bool {
+	return valInfo.UnbondHeight == 0 && valInfo.DestroyedHeight == 0
+}
+
+// The voting power of the validator, 0 unless it is bonded
+func (valInfo *ValidatorInfo) VotingPower() int64 {
+	if !valInfo.Bonded() {
+		return 0
+	}
+	return valInfo.FirstBondAmount
+}
+
+// Whether the validator has been unbonded long enough to be released at height
+func (valInfo *ValidatorInfo) releasableAt(height int) bool {
+	return valInfo.UnbondHeight != 0 && valInfo.ReleasedHeight == 0 &&
+		valInfo.DestroyedHeight == 0 && height >= valInfo.UnbondHeight+unbondingPeriodBlocks
+}
+
+func EncodeValidatorInfo(valInfo *ValidatorInfo) []byte {
+	return wire.BinaryBytes(valInfo)
+}
+
+func DecodeValidatorInfo(valInfoBytes []byte) *ValidatorInfo {
+	valInfo := new(ValidatorInfo)
+	err := wire.ReadBinaryBytes(valInfoBytes, valInfo)
+	if err != nil {
+		sanity.PanicCrisis("Could not decode validator info: " + err.Error())
+	}
+	return valInfo
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"fmt"
+	"sync"
+
+	dbm "github.com/tendermint/go-db"
+	"github.com/tendermint/go-wire"
+)
+
+// The IAVL trees delete the nodes a save orphans when they are next saved,
+// which would leave the trees of earlier heights with missing nodes. The
+// trees of the state are kept in a versionedDB instead, which keeps those
+// nodes and records them against the height they were orphaned at so that
+// they are only deleted once the state of that height is dropped.
+//
+// A node is stored under its hash so the same node may be orphaned by one
+// tree and saved again by a later one. Each orphan is marked with the height
+// it was last orphaned at and saving it again clears the mark, so a node is
+// only pruned if it is still an orphan of the height being dropped.
+type versionedDB struct {
+	dbm.DB
+	mtx sync.Mutex
+	// The nodes orphaned since the state was last saved
+	orphans map[string]struct{}
+}
+
+var (
+	orphanKey  = []byte("orphan")
+	orphansKey = []byte("orphans")
+)
+
+func newVersionedDB(db dbm.DB) *versionedDB {
+	return &versionedDB{
+		DB:      db,
+		orphans: make(map[string]struct{}),
+	}
+}
+
+func orphanKeyOfHash(hash []byte) []byte {
+	return []byte(fmt.Sprintf("%s/%X", orphanKey, hash))
+}
+
+func orphansKeyAtHeight(height int) []byte {
+	return []byte(fmt.Sprintf("%s/%d", orphansKey, height))
+}
+
+func (vdb *versionedDB) Set(key, value []byte) {
+	vdb.adopt(key)
+	vdb.DB.Set(key, value)
+	vdb.DB.Delete(orphanKeyOfHash(key))
+}
+
+func (vdb *versionedDB) SetSync(key, value []byte) {
+	vdb.adopt(key)
+	vdb.DB.SetSync(key, value)
+	vdb.DB.DeleteSync(orphanKeyOfHash(key))
+}
+
+func (vdb *versionedDB) Delete(key []byte) {
+	vdb.orphan(key)
+}
+
+func (vdb *versionedDB) DeleteSync(key []byte) {
+	vdb.orphan(key)
+}
+
+func (vdb *versionedDB) NewBatch() dbm.Batch {
+	return &versionedBatch{
+		Batch: vdb.DB.NewBatch(),
+		vdb:   vdb,
+	}
+}
+
+func (vdb *versionedDB) orphan(key []byte) {
+	vdb.mtx.Lock()
+	defer vdb.mtx.Unlock()
+	vdb.orphans[string(key)] = struct{}{}
+}
+
+func (vdb *versionedDB) adopt(key []byte) {
+	vdb.mtx.Lock()
+	defer vdb.mtx.Unlock()
+	delete(vdb.orphans, string(key))
+}
+
+// Records the nodes orphaned since the last save against height
+func (vdb *versionedDB) saveOrphans(height int) {
+	vdb.mtx.Lock()
+	orphans := vdb.orphans
+	vdb.orphans = make(map[string]struct{})
+	vdb.mtx.Unlock()
+	if len(orphans) == 0 {
+		return
+	}
+	hashes := vdb.orphansAtHeight(height)
+	batch := vdb.DB.NewBatch()
+	for hash := range orphans {
+		hashes = append(hashes, []byte(hash))
+		batch.Set(orphanKeyOfHash([]byte(hash)), wire.BinaryBytes(height))
+	}
+	batch.Set(orphansKeyAtHeight(height), wire.BinaryBytes(hashes))
+	batch.Write()
+}
+
+// Deletes the nodes orphaned at height that have not been saved again since
+func (vdb *versionedDB) pruneOrphans(height int) {
+	hashes := vdb.orphansAtHeight(height)
+	if len(hashes) == 0 {
+		return
+	}
+	batch := vdb.DB.NewBatch()
+	for _, hash := range hashes {
+		buf := vdb.DB.Get(orphanKeyOfHash(hash))
+		if len(buf) == 0 {
+			continue
+		}
+		var orphanedAt int
+		wire.ReadBinaryBytes(buf, &orphanedAt)
+		if orphanedAt == height {
+			batch.Delete(hash)
+			batch.Delete(orphanKeyOfHash(hash))
+		}
+	}
+	batch.Delete(orphansKeyAtHeight(height))
+	batch.Write()
+}
+
+func (vdb *versionedDB) orphansAtHeight(height int) [][]byte {
+	var hashes [][]byte
+	buf := vdb.DB.Get(orphansKeyAtHeight(height))
+	if len(buf) > 0 {
+		wire.ReadBinaryBytes(buf, &hashes)
+	}
+	return hashes
+}
+
+type versionedBatch struct {
+	dbm.Batch
+	vdb *versionedDB
+}
+
+func (b *versionedBatch) Set(key, value []byte) {
+	b.vdb.adopt(key)
+	b.Batch.Set(key, value)
+	b.Batch.Delete(orphanKeyOfHash(key))
+}
+
+func (b *versionedBatch) Delete(key []byte) {
+	b.vdb.orphan(key)
+}
@@ -26,13 +26,18 @@ import (
 	event "github.com/hyperledger/burrow/event"
 	"github.com/hyperledger/burrow/manager/burrow-mint/evm"
 	"github.com/hyperledger/burrow/manager/burrow-mint/state"
+	rpc_tm_types "github.com/hyperledger/burrow/rpc/tendermint/core/types"
 	"github.com/hyperledger/burrow/txs"
 	"github.com/hyperledger/burrow/word256"
 
+	abci "github.com/tendermint/abci/types"
 	"github.com/tendermint/go-crypto"
 	tEvents "github.com/tendermint/go-events"
 )
 
+// Maximum number of ops logged when tracing a call or tx
+const maxTraceSteps = 10000
+
 // Transactor is part of the pipe for BurrowMint and provides the implementation
 // for the pipe to call into the BurrowMint application
 type transactor struct {
@@ -41,12 +46,12 @@ type transactor struct {
 	burrowMint    *BurrowMint
 	eventEmitter  event.EventEmitter
 	txMtx         *sync.Mutex
-	txBroadcaster func(tx txs.Tx) error
+	txBroadcaster func(tx txs.Tx) (*rpc_tm_types.ResultBroadcastTx, error)
 }
 
 func newTransactor(chainID string, eventSwitch tEvents.Fireable,
 	burrowMint *BurrowMint, eventEmitter event.EventEmitter,
-	txBroadcaster func(tx txs.Tx) error) *transactor {
+	txBroadcaster func(tx txs.Tx) (*rpc_tm_types.ResultBroadcastTx, error)) *transactor {
 	return &transactor{
 		chainID,
 		eventSwitch,
@@ -64,7 +69,22 @@ func newTransactor(chainID string, eventSwitch tEvents.Fireable,
 // TODO: [ben] resolve incompatibilities in byte representation for 0.12.0 release
 func (this *transactor) Call(fromAddress, toAddress, data []byte) (
 	*core_types.Call, error) {
+	return this.call(fromAddress, toAddress, data, nil)
+}
 
+// Like Call but also logs every op the VM runs
+func (this *transactor) TraceCall(fromAddress, toAddress, data []byte) (
+	*core_types.CallTrace, error) {
+	structLogger := vm.NewStructLogger(maxTraceSteps)
+	call, err := this.call(fromAddress, toAddress, data, structLogger)
+	if err != nil {
+		return nil, err
+	}
+	return &core_types.CallTrace{Call: call, StructLogs: structLogger.StructLogs()}, nil
+}
+
+func (this *transactor) call(fromAddress, toAddress, data []byte, tracer vm.Tracer) (
+	*core_types.Call, error) {
 	st := this.burrowMint.GetState()
 	cache := state.NewBlockCache(st) // XXX: DON'T MUTATE THIS CACHE (used internally for CheckTx)
 	outAcc := cache.GetAccount(toAddress)
@@ -78,24 +98,96 @@ func (this *transactor) Call(fromAddress, toAddress, data []byte) (
 	caller := &vm.Account{Address: word256.LeftPadWord256(fromAddress)}
 	txCache := state.NewTxCache(cache)
 	gasLimit := st.GetGasLimit()
-	params := vm.Params{
-		BlockHeight: int64(st.LastBlockHeight),
-		BlockHash:   word256.LeftPadWord256(st.LastBlockHash),
-		BlockTime:   st.LastBlockTime.Unix(),
-		GasLimit:    gasLimit,
-	}
+	params := st.VMParams()
 
 	vmach := vm.NewVM(txCache, params, caller.Address, nil)
 	vmach.SetFireable(this.eventSwitch)
+	if tracer != nil {
+		vmach.SetTracer(tracer)
+	}
 	gas := gasLimit
 	ret, err := vmach.Call(caller, callee, callee.Code, data, 0, &gas)
-	if err != nil {
+	// When tracing we want to see how any call failed
+	if err != nil && err != vm.ErrExecutionReverted && tracer == nil {
 		return nil, err
 	}
 	gasUsed := gasLimit - gas
 	// here return bytes are hex encoded; on the sibling function
 	// they are not
-	return &core_types.Call{Return: hex.EncodeToString(ret), GasUsed: gasUsed}, nil
+	call := &core_types.Call{Return: hex.EncodeToString(ret), GasUsed: gasUsed}
+	if err != nil {
+		call.Exception = err.Error()
+		call.RevertReason, _ = vm.RevertReason(ret)
+		ret = nil
+	}
+	call.Decoded = decodeCall(st, toAddress, data, ret)
+	return call, nil
+}
+
+// Finds the least gas limit with which a CallTx from fromAddress with data
+// would succeed, by binary search over dry runs on the last committed state
+// as the next block would run them. An empty toAddress creates a contract as
+// the CallTx would.
+func (this *transactor) EstimateGas(fromAddress, toAddress, data []byte) (int64, error) {
+	st := this.burrowMint.GetState()
+	cache := state.NewBlockCache(st) // XXX: DON'T SYNC THIS CACHE
+	var callee *account.Account
+	if len(toAddress) > 0 {
+		callee = cache.GetAccount(toAddress)
+		if callee == nil {
+			return 0, fmt.Errorf("Account %x does not exist", toAddress)
+		}
+		if len(callee.Code) == 0 {
+			return 0, fmt.Errorf("Account %x has no code to call", toAddress)
+		}
+	}
+	gasLimit := st.GetGasLimit()
+	params := st.VMParams()
+
+	// Runs the CallTx on a fresh TxCache, so that no run sees another's writes
+	run := func(gas int64) (int64, []byte, error) {
+		txCache := state.NewTxCache(cache)
+		caller := &vm.Account{Address: word256.LeftPadWord256(fromAddress)}
+		if acc := cache.GetAccount(fromAddress); acc != nil {
+			caller = toVMAccount(acc)
+		}
+		// ExecTx bumps the sequence before running the VM
+		caller.Nonce += 1
+		var vmCallee *vm.Account
+		var code []byte
+		if callee == nil {
+			vmCallee = txCache.CreateAccount(caller)
+			code = data
+		} else {
+			vmCallee = toVMAccount(callee)
+			code = vmCallee.Code
+		}
+		txCache.UpdateAccount(caller)
+		txCache.UpdateAccount(vmCallee)
+		vmach := vm.NewVM(txCache, params, caller.Address, nil)
+		startGas := gas
+		ret, err := vmach.Call(caller, vmCallee, code, data, 0, &gas)
+		return startGas - gas, ret, err
+	}
+
+	gasUsed, ret, err := run(gasLimit)
+	if err != nil {
+		if reason, ok := vm.RevertReason(ret); ok {
+			return 0, fmt.Errorf("Call fails with the most gas allowed: %v: %s", err, reason)
+		}
+		return 0, fmt.Errorf("Call fails with the most gas allowed: %v", err)
+	}
+	// Refunds mean the call may need more than it uses, but never less
+	low, high := gasUsed-1, gasLimit
+	for high-low > 1 {
+		mid := low + (high-low)/2
+		if _, _, err := run(mid); err != nil {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return high, nil
 }
 
 // Run the given code on an isolated and unpersisted state
@@ -111,44 +203,109 @@ func (this *transactor) CallCode(fromAddress, code, data []byte) (
 	txCache := state.NewTxCache(cache)
 	st := this.burrowMint.GetState() // for block height, time
 	gasLimit := st.GetGasLimit()
-	params := vm.Params{
-		BlockHeight: int64(st.LastBlockHeight),
-		BlockHash:   word256.LeftPadWord256(st.LastBlockHash),
-		BlockTime:   st.LastBlockTime.Unix(),
-		GasLimit:    gasLimit,
-	}
+	params := st.VMParams()
 
 	vmach := vm.NewVM(txCache, params, caller.Address, nil)
 	gas := gasLimit
 	ret, err := vmach.Call(caller, callee, code, data, 0, &gas)
-	if err != nil {
+	if err != nil && err != vm.ErrExecutionReverted {
 		return nil, err
 	}
 	gasUsed := gasLimit - gas
 	// here return bytes are hex encoded; on the sibling function
 	// they are not
-	return &core_types.Call{Return: hex.EncodeToString(ret), GasUsed: gasUsed}, nil
+	call := &core_types.Call{Return: hex.EncodeToString(ret), GasUsed: gasUsed}
+	if err != nil {
+		call.Exception = err.Error()
+		call.RevertReason, _ = vm.RevertReason(ret)
+	}
+	return call, nil
+}
+
+// Run a signed transaction as if it were the next in a block, on an isolated
+// and unpersisted state, logging every op the VM runs. Only CallTxs run code.
+func (this *transactor) TraceTx(tx txs.Tx) (*core_types.CallTrace, error) {
+	blockCache := state.NewBlockCache(this.burrowMint.GetState()) // XXX: DON'T SYNC THIS CACHE
+	structLogger := vm.NewStructLogger(maxTraceSteps)
+	result, err := state.TraceTx(blockCache, tx, nil, structLogger)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("Transaction does not run any code")
+	}
+	return newCallTrace(result, structLogger), nil
+}
+
+// The receipt of the tx with hash txHash, once it has been committed in a block
+func (this *transactor) TxReceipt(txHash []byte) (*txs.TxReceipt, error) {
+	st := this.burrowMint.GetState()
+	receipt := st.GetTxReceipt(txHash)
+	if receipt == nil {
+		return nil, fmt.Errorf("No receipt for tx %X", txHash)
+	}
+	decodeLogs(st, receipt.Logs)
+	return receipt, nil
+}
+
+func (this *transactor) SearchTxs(filter *txs.TxFilter) (*txs.TxSearchResult, error) {
+	st := this.burrowMint.GetState()
+	result, err := st.SearchTxs(filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, receipt := range result.Receipts {
+		decodeLogs(st, receipt.Logs)
+	}
+	return result, nil
+}
+
+func (this *transactor) GetLogs(filter *txs.LogFilter) (*txs.LogSearchResult, error) {
+	st := this.burrowMint.GetState()
+	result, err := st.GetLogs(filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range result.Logs {
+		decodeLogs(st, []*txs.EventDataLog{entry.Log})
+	}
+	return result, nil
+}
+
+// Describes what the VM did when running a tx, with the ops it ran if
+// structLogger is not nil
+func newCallTrace(result *state.TxCallResult,
+	structLogger *vm.StructLogger) *core_types.CallTrace {
+	call := &core_types.Call{Return: hex.EncodeToString(result.Return), GasUsed: result.GasUsed}
+	if result.Exception != nil {
+		call.Exception = result.Exception.Error()
+		call.RevertReason, _ = vm.RevertReason(result.Return)
+	}
+	trace := &core_types.CallTrace{Call: call}
+	if structLogger != nil {
+		trace.StructLogs = structLogger.StructLogs()
+	}
+	return trace
 }
 
 // Broadcast a transaction.
+// If the tx is rejected returns its receipt, with the ABCI code saying why it
+// was rejected, as well as an error
 func (this *transactor) BroadcastTx(tx txs.Tx) (*txs.Receipt, error) {
-	err := this.txBroadcaster(tx)
+	result, err := this.txBroadcaster(tx)
 
 	if err != nil {
 		return nil, fmt.Errorf("Error broadcasting transaction: %v", err)
 	}
 
-	txHash := txs.TxHash(this.chainID, tx)
-	var createsContract uint8
-	var contractAddr []byte
-	// check if creates new contract
-	if callTx, ok := tx.(*txs.CallTx); ok {
-		if len(callTx.Address) == 0 {
-			createsContract = 1
-			contractAddr = state.NewContractAddress(callTx.Input.Address, callTx.Input.Sequence)
-		}
+	receipt := txs.GenerateReceipt(this.chainID, tx)
+	if result.Code != abci.CodeType_OK {
+		receipt.Code = result.Code
+		receipt.Log = result.Log
+		return &receipt, fmt.Errorf("Transaction rejected with code %v: %s",
+			result.Code, result.Log)
 	}
-	return &txs.Receipt{txHash, createsContract, contractAddr}, nil
+	return &receipt, nil
 }
 
 // Orders calls to BroadcastTx using lock (waits for response from core before releasing)
@@ -183,13 +340,15 @@ func (this *transactor) Transact(privKey, address, data []byte, gasLimit,
 	// someone may be using the sending of native token to payable functions but
 	// they can be served by broadcasting a token.
 
-	// We hard-code the amount to be equal to the fee which means the CallTx we
+	// We hard-code the amount to be equal to the fee and the cost of the gas
+	// limit at the chain's minimum gas price, which means the CallTx we
 	// generate transfers 0 value, which is the most sensible default since in
 	// recent solidity compilers the EVM generated will throw an error if value
 	// is transferred to a non-payable function.
+	gasPrice := this.burrowMint.GetState().GetMinGasPrice()
 	txInput := &txs.TxInput{
 		Address:  pa.Address,
-		Amount:   fee,
+		Amount:   fee + gasLimit*gasPrice,
 		Sequence: sequence,
 		PubKey:   pa.PubKey,
 	}
@@ -197,6 +356,7 @@ func (this *transactor) Transact(privKey, address, data []byte, gasLimit,
 		Input:    txInput,
 		Address:  addr,
 		GasLimit: gasLimit,
+		GasPrice: gasPrice,
 		Fee:      fee,
 		Data:     data,
 	}
@@ -405,6 +565,11 @@ func (this *transactor) SignTx(tx txs.Tx, privAccounts []*account.PrivAccount) (
 		callTx.Input.PubKey = privAccounts[0].PubKey
 		callTx.Input.Signature = privAccounts[0].Sign(this.chainID, callTx)
 		break
+	case *txs.LegacyCallTx:
+		callTx := tx.(*txs.LegacyCallTx)
+		callTx.Input.PubKey = privAccounts[0].PubKey
+		callTx.Input.Signature = privAccounts[0].Sign(this.chainID, callTx)
+		break
 	case *txs.BondTx:
 		bondTx := tx.(*txs.BondTx)
 		// the first privaccount corresponds to the BondTx pub key.
@@ -0,0 +1,116 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package burrowmint
+
+import (
+	"testing"
+
+	"github.com/hyperledger/burrow/account"
+	core_types "github.com/hyperledger/burrow/core/types"
+	genesis "github.com/hyperledger/burrow/genesis"
+	"github.com/hyperledger/burrow/logging/loggers"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
+	sm "github.com/hyperledger/burrow/manager/burrow-mint/state"
+	"github.com/hyperledger/burrow/txs"
+	"github.com/hyperledger/burrow/word256"
+	assert "github.com/stretchr/testify/assert"
+	dbm "github.com/tendermint/go-db"
+)
+
+func TestEstimateGas(t *testing.T) {
+	caller := account.GenPrivAccountFromSecret("caller")
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "estimate_gas",
+		Params:     &genesis.GenesisParams{GasSchedule: vm.ByzantiumGasScheduleName},
+		Accounts:   []genesis.GenesisAccount{{Address: caller.Address, Amount: 1000}},
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	// PUSH1 1, PUSH1 0, SSTORE, STOP
+	storeCode := []byte{0x60, 0x01, 0x60, 0x00, 0x55, 0x00}
+	storer := account.GenPrivAccountFromSecret("storer").Address
+	st.UpdateAccount(&account.Account{Address: storer, Code: storeCode})
+	// PUSH1 0, PUSH1 0, REVERT
+	reverter := account.GenPrivAccountFromSecret("reverter").Address
+	st.UpdateAccount(&account.Account{Address: reverter, Code: []byte{0x60, 0x00, 0x60, 0x00, 0xfd}})
+	burrowMint := NewBurrowMint(st, nil, loggers.NewNoopInfoTraceLogger())
+	trans := newTransactor(st.ChainID, nil, burrowMint, nil, nil)
+
+	// Two pushes and a store into an empty slot
+	gasLimit, err := trans.EstimateGas(caller.Address, storer, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3+3+20000), gasLimit)
+
+	// Txs checked but not yet committed are not run against
+	burrowMint.GetCheckCache().UpdateAccount(&account.Account{Address: storer})
+	gasLimit, err = trans.EstimateGas(caller.Address, storer, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3+3+20000), gasLimit)
+
+	// Creating a contract runs the data as code
+	gasLimit, err = trans.EstimateGas(caller.Address, nil, storeCode)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3+3+20000), gasLimit)
+
+	_, err = trans.EstimateGas(caller.Address, reverter, nil)
+	assert.Error(t, err)
+
+	_, err = trans.EstimateGas(caller.Address, validator.Address, nil)
+	assert.Error(t, err)
+}
+
+func TestCallDecoded(t *testing.T) {
+	validator := account.GenPrivAccountFromSecret("validator")
+	st := sm.MakeGenesisState(dbm.NewMemDB(), &genesis.GenesisDoc{
+		ChainID:    "call_decoded",
+		Validators: []genesis.GenesisValidator{{PubKey: validator.PubKey, Amount: 1}},
+	})
+	// Returns the word after the selector: PUSH1 32, PUSH1 4, PUSH1 0,
+	// CALLDATACOPY, PUSH1 32, PUSH1 0, RETURN
+	echo := account.GenPrivAccountFromSecret("echo").Address
+	st.UpdateAccount(&account.Account{Address: echo,
+		Code: []byte{0x60, 0x20, 0x60, 0x04, 0x60, 0x00, 0x37, 0x60, 0x20, 0x60, 0x00, 0xf3}})
+	trans := newTransactor(st.ChainID, nil, NewBurrowMint(st, nil,
+		loggers.NewNoopInfoTraceLogger()), nil, nil)
+	data := append(sha3.Sha3([]byte("echo(uint256)"))[:4], word256.Int64ToWord256(42).Bytes()...)
+
+	call, err := trans.Call(nil, echo, data)
+	assert.NoError(t, err)
+	assert.Nil(t, call.Decoded)
+
+	entry := &core_types.NameRegEntry{
+		Name:    txs.ContractABIName(echo),
+		Owner:   validator.Address,
+		Data:    `[{"type":"function","name":"echo","inputs":[{"name":"x","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]}]`,
+		Expires: 100,
+	}
+	// An ABI registered by neither the contract nor its creator is ignored
+	st.UpdateNameRegEntry(entry)
+	call, err = trans.Call(nil, echo, data)
+	assert.NoError(t, err)
+	assert.Nil(t, call.Decoded)
+
+	entry.Owner = echo
+	st.UpdateNameRegEntry(entry)
+	call, err = trans.Call(nil, echo, data)
+	assert.NoError(t, err)
+	assert.Equal(t, &abi.DecodedCall{
+		Function: "echo",
+		Args:     []abi.DecodedArg{{Name: "x", Type: "uint256", Value: "42"}},
+		Returns:  []abi.DecodedArg{{Name: "", Type: "uint256", Value: "42"}},
+	}, call.Decoded)
+}
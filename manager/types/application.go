@@ -84,13 +84,11 @@ type BlockchainAware interface {
 	// validators: genesis validators from tendermint core
 	InitChain(validators []*abci_types.Validator)
 
-	// Signals the beginning of a block;
-	// NOTE: [ben] currently not supported by tendermint
-	BeginBlock(height uint64)
+	// Signals the beginning of a block
+	BeginBlock(hash []byte, header *abci_types.Header)
 
-	// Signals the end of a blockchain
-	// validators: changed validators from app to Tendermint
-	// NOTE: [ben] currently not supported by tendermint
-	// not yet well defined what the change set contains.
-	EndBlock(height uint64) (validators []*abci_types.Validator)
+	// Signals the end of a block
+	// returns the changes to the validator set made by the block, with the
+	// new voting power of each validator changed, 0 for those removed
+	EndBlock(height uint64) abci_types.ResponseEndBlock
 }
@@ -0,0 +1,121 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The Ethereum JSON-RPC API writes numbers as quantities, hex with no leading
+// zeros, and byte strings as data, hex of every byte. Both are prefixed by 0x.
+
+func encodeQuantity(n int64) string {
+	return fmt.Sprintf("0x%x", n)
+}
+
+func encodeData(data []byte) string {
+	return "0x" + hex.EncodeToString(data)
+}
+
+func decodeQuantity(quantity string) (int64, error) {
+	if !strings.HasPrefix(quantity, "0x") || len(quantity) == 2 {
+		return 0, fmt.Errorf("Invalid quantity '%s'", quantity)
+	}
+	n, err := strconv.ParseInt(quantity[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid quantity '%s': %v", quantity, err)
+	}
+	return n, nil
+}
+
+// Decodes data, allowing an odd number of digits as some clients send for
+// storage positions
+func decodeData(data string) ([]byte, error) {
+	if !strings.HasPrefix(data, "0x") {
+		return nil, fmt.Errorf("Invalid data '%s': missing 0x prefix", data)
+	}
+	digits := data[2:]
+	if len(digits)%2 == 1 {
+		digits = "0" + digits
+	}
+	bs, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid data '%s': %v", data, err)
+	}
+	return bs, nil
+}
+
+func decodeAddress(address string) ([]byte, error) {
+	bs, err := decodeData(address)
+	if err != nil {
+		return nil, err
+	}
+	if len(bs) != 20 {
+		return nil, fmt.Errorf("Invalid address '%s': must be 20 bytes", address)
+	}
+	return bs, nil
+}
+
+// Decodes a block number, or a tag of one, to the height of a block given the
+// height of the latest. Heights start at 1, so the earliest block is the first.
+func decodeBlockNumber(blockNumber string, latestHeight int) (int, error) {
+	switch blockNumber {
+	case "", "latest", "pending":
+		return latestHeight, nil
+	case "earliest":
+		return 1, nil
+	}
+	height, err := decodeQuantity(blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	return int(height), nil
+}
+
+// Decodes the positional params of a request into args, each a pointer. Params
+// beyond those given keep their zero values so that optional ones, such as
+// block numbers, may be left out.
+func decodeParams(params []json.RawMessage, args ...interface{}) error {
+	if len(params) > len(args) {
+		return fmt.Errorf("Expected at most %v params but got %v", len(args), len(params))
+	}
+	for i, param := range params {
+		if err := json.Unmarshal(param, args[i]); err != nil {
+			return fmt.Errorf("Invalid param %v: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Decodes a param that may be a single string or a list of them, as the
+// address and topics of a log filter are
+func decodeStrings(param json.RawMessage) ([]string, error) {
+	if len(param) == 0 || string(param) == "null" {
+		return nil, nil
+	}
+	var single string
+	if err := json.Unmarshal(param, &single); err == nil {
+		return []string{single}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(param, &list); err != nil {
+		return nil, fmt.Errorf("Expected a string or a list of strings: %v", err)
+	}
+	return list, nil
+}
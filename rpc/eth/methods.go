@@ -0,0 +1,475 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	definitions "github.com/hyperledger/burrow/definitions"
+	rpc "github.com/hyperledger/burrow/rpc"
+	"github.com/hyperledger/burrow/txs"
+	. "github.com/hyperledger/burrow/word256"
+
+	tm_types "github.com/tendermint/tendermint/types"
+)
+
+// Method names
+const (
+	ETH_CALL                 = "eth_call"
+	ETH_GET_BALANCE          = "eth_getBalance"
+	ETH_GET_CODE             = "eth_getCode"
+	ETH_GET_STORAGE_AT       = "eth_getStorageAt"
+	ETH_BLOCK_NUMBER         = "eth_blockNumber"
+	ETH_GET_BLOCK_BY_NUMBER  = "eth_getBlockByNumber"
+	ETH_GET_LOGS             = "eth_getLogs"
+	ETH_SEND_RAW_TRANSACTION = "eth_sendRawTransaction"
+	NET_VERSION              = "net_version"
+)
+
+// Takes the positional params of a request and returns its result, or an
+// error with its JSON-RPC error code
+type EthHandlerFunc func(params []json.RawMessage) (interface{}, int, error)
+
+// The objects of the Ethereum API, as far as burrow has their fields
+type (
+	EthCallArgs struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Data string `json:"data"`
+		// Newer clients send the data as input
+		Input string `json:"input"`
+	}
+
+	EthLogFilter struct {
+		FromBlock string `json:"fromBlock"`
+		ToBlock   string `json:"toBlock"`
+		// A single address or a list of them
+		Address json.RawMessage `json:"address"`
+		// For each position a topic, a list of them or null for any
+		Topics []json.RawMessage `json:"topics"`
+	}
+
+	EthBlock struct {
+		Number           string `json:"number"`
+		Hash             string `json:"hash"`
+		ParentHash       string `json:"parentHash"`
+		StateRoot        string `json:"stateRoot"`
+		TransactionsRoot string `json:"transactionsRoot"`
+		Timestamp        string `json:"timestamp"`
+		GasLimit         string `json:"gasLimit"`
+		// Hashes of the transactions, or the transactions themselves
+		Transactions []interface{} `json:"transactions"`
+	}
+
+	EthTransaction struct {
+		Hash             string `json:"hash"`
+		BlockHash        string `json:"blockHash"`
+		BlockNumber      string `json:"blockNumber"`
+		TransactionIndex string `json:"transactionIndex"`
+		From             string `json:"from"`
+		// Null for a transaction creating a contract
+		To       *string `json:"to"`
+		Input    string  `json:"input"`
+		Value    string  `json:"value"`
+		Gas      string  `json:"gas"`
+		GasPrice string  `json:"gasPrice"`
+		Nonce    string  `json:"nonce"`
+	}
+
+	// Burrow numbers logs within their transaction rather than their block
+	EthLog struct {
+		Address          string   `json:"address"`
+		Topics           []string `json:"topics"`
+		Data             string   `json:"data"`
+		BlockNumber      string   `json:"blockNumber"`
+		BlockHash        string   `json:"blockHash"`
+		TransactionHash  string   `json:"transactionHash"`
+		TransactionIndex string   `json:"transactionIndex"`
+		LogIndex         string   `json:"logIndex"`
+		Removed          bool     `json:"removed"`
+	}
+)
+
+type ethMethods struct {
+	pipe definitions.Pipe
+}
+
+func newEthMethods(pipe definitions.Pipe) *ethMethods {
+	return &ethMethods{pipe: pipe}
+}
+
+func (ethMethods *ethMethods) getMethods() map[string]EthHandlerFunc {
+	return map[string]EthHandlerFunc{
+		ETH_CALL:                 ethMethods.Call,
+		ETH_GET_BALANCE:          ethMethods.GetBalance,
+		ETH_GET_CODE:             ethMethods.GetCode,
+		ETH_GET_STORAGE_AT:       ethMethods.GetStorageAt,
+		ETH_BLOCK_NUMBER:         ethMethods.BlockNumber,
+		ETH_GET_BLOCK_BY_NUMBER:  ethMethods.GetBlockByNumber,
+		ETH_GET_LOGS:             ethMethods.GetLogs,
+		ETH_SEND_RAW_TRANSACTION: ethMethods.SendRawTransaction,
+		NET_VERSION:              ethMethods.NetVersion,
+	}
+}
+
+// The accounts and transactor of the pipe answer from the latest state, so
+// queries of state at other blocks are refused rather than answered wrongly.
+// The node keeps the state of earlier blocks, which the Tendermint RPC queries
+// by height.
+func (ethMethods *ethMethods) checkLatest(blockNumber string) error {
+	latestHeight := ethMethods.pipe.Blockchain().Height()
+	height, err := decodeBlockNumber(blockNumber, latestHeight)
+	if err != nil {
+		return err
+	}
+	if height != latestHeight {
+		return fmt.Errorf("Only the state of the latest block, %v, can be queried",
+			latestHeight)
+	}
+	return nil
+}
+
+func (ethMethods *ethMethods) Call(params []json.RawMessage) (interface{}, int, error) {
+	args := new(EthCallArgs)
+	var blockNumber string
+	if err := decodeParams(params, args, &blockNumber); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	if err := ethMethods.checkLatest(blockNumber); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	var from []byte
+	if args.From != "" {
+		var err error
+		if from, err = decodeAddress(args.From); err != nil {
+			return nil, rpc.INVALID_PARAMS, err
+		}
+	}
+	to, err := decodeAddress(args.To)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	input := args.Data
+	if input == "" {
+		input = args.Input
+	}
+	var data []byte
+	if input != "" {
+		if data, err = decodeData(input); err != nil {
+			return nil, rpc.INVALID_PARAMS, err
+		}
+	}
+	call, err := ethMethods.pipe.Transactor().Call(from, to, data)
+	if err != nil {
+		return nil, rpc.INTERNAL_ERROR, err
+	}
+	if call.Exception != "" {
+		if call.RevertReason != "" {
+			return nil, rpc.INTERNAL_ERROR, fmt.Errorf("execution reverted: %s",
+				call.RevertReason)
+		}
+		return nil, rpc.INTERNAL_ERROR, fmt.Errorf("execution reverted")
+	}
+	ret, err := hex.DecodeString(call.Return)
+	if err != nil {
+		return nil, rpc.INTERNAL_ERROR, err
+	}
+	return encodeData(ret), 0, nil
+}
+
+func (ethMethods *ethMethods) GetBalance(params []json.RawMessage) (interface{}, int, error) {
+	var address, blockNumber string
+	if err := decodeParams(params, &address, &blockNumber); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	addressBytes, err := decodeAddress(address)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	if err := ethMethods.checkLatest(blockNumber); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	acc, err := ethMethods.pipe.Accounts().Account(addressBytes)
+	if err != nil {
+		return nil, rpc.INTERNAL_ERROR, err
+	}
+	return encodeQuantity(acc.Balance), 0, nil
+}
+
+func (ethMethods *ethMethods) GetCode(params []json.RawMessage) (interface{}, int, error) {
+	var address, blockNumber string
+	if err := decodeParams(params, &address, &blockNumber); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	addressBytes, err := decodeAddress(address)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	if err := ethMethods.checkLatest(blockNumber); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	acc, err := ethMethods.pipe.Accounts().Account(addressBytes)
+	if err != nil {
+		return nil, rpc.INTERNAL_ERROR, err
+	}
+	return encodeData(acc.Code), 0, nil
+}
+
+func (ethMethods *ethMethods) GetStorageAt(params []json.RawMessage) (interface{}, int, error) {
+	var address, position, blockNumber string
+	if err := decodeParams(params, &address, &position, &blockNumber); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	addressBytes, err := decodeAddress(address)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	key, err := decodeData(position)
+	if err != nil || len(key) > 32 {
+		return nil, rpc.INVALID_PARAMS, fmt.Errorf("Invalid storage position '%s'", position)
+	}
+	if err := ethMethods.checkLatest(blockNumber); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	item, err := ethMethods.pipe.Accounts().StorageAt(addressBytes, key)
+	if err != nil {
+		return nil, rpc.INTERNAL_ERROR, err
+	}
+	return encodeData(LeftPadWord256(item.Value).Bytes()), 0, nil
+}
+
+func (ethMethods *ethMethods) BlockNumber(params []json.RawMessage) (interface{}, int, error) {
+	return encodeQuantity(int64(ethMethods.pipe.Blockchain().Height())), 0, nil
+}
+
+// The network id is the chain id, which need not be a number
+func (ethMethods *ethMethods) NetVersion(params []json.RawMessage) (interface{}, int, error) {
+	return ethMethods.pipe.Blockchain().ChainId(), 0, nil
+}
+
+// Returns null for a block that has not been committed
+func (ethMethods *ethMethods) GetBlockByNumber(params []json.RawMessage) (interface{}, int, error) {
+	var blockNumber string
+	var fullTxs bool
+	if err := decodeParams(params, &blockNumber, &fullTxs); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	blockchain := ethMethods.pipe.Blockchain()
+	height, err := decodeBlockNumber(blockNumber, blockchain.Height())
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	if height < 1 || height > blockchain.Height() {
+		return nil, 0, nil
+	}
+	block := blockchain.Block(height)
+	if block == nil {
+		return nil, 0, nil
+	}
+	ethBlock := &EthBlock{
+		Number:           encodeQuantity(int64(block.Height)),
+		Hash:             encodeData(block.Hash()),
+		ParentHash:       encodeData(block.LastBlockID.Hash),
+		StateRoot:        encodeData(block.AppHash),
+		TransactionsRoot: encodeData(block.DataHash),
+		Timestamp:        encodeQuantity(block.Time.Unix()),
+		GasLimit:         encodeQuantity(0),
+		Transactions:     make([]interface{}, 0, len(block.Txs)),
+	}
+	for i, txBytes := range block.Txs {
+		tx, err := txs.DecodeTx(txBytes)
+		if err != nil {
+			// The block may hold txs that are not burrow's, which the app
+			// did not run, so they are left out
+			continue
+		}
+		txHash := encodeData(txs.TxHash(blockchain.ChainId(), tx))
+		if fullTxs {
+			ethBlock.Transactions = append(ethBlock.Transactions, newEthTransaction(txHash, block, i, tx))
+		} else {
+			ethBlock.Transactions = append(ethBlock.Transactions, txHash)
+		}
+	}
+	return ethBlock, 0, nil
+}
+
+// Returns every log the filter matches, from a single search of as many logs as
+// the node returns at once. A filter matching more is refused so that it is
+// narrowed rather than paged through.
+func (ethMethods *ethMethods) GetLogs(params []json.RawMessage) (interface{}, int, error) {
+	ethFilter := new(EthLogFilter)
+	if err := decodeParams(params, ethFilter); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	blockchain := ethMethods.pipe.Blockchain()
+	filter, err := newLogFilter(ethFilter, blockchain.Height())
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	result, err := ethMethods.pipe.Transactor().GetLogs(filter)
+	if err != nil {
+		return nil, rpc.INTERNAL_ERROR, err
+	}
+	if result.More {
+		return nil, rpc.INVALID_PARAMS, fmt.Errorf("More than %v logs match, narrow the blocks "+
+			"or the filter", len(result.Logs))
+	}
+	ethLogs := make([]*EthLog, 0, len(result.Logs))
+	blockHashes := make(map[int]string)
+	for _, entry := range result.Logs {
+		blockHash, ok := blockHashes[entry.Height]
+		if !ok {
+			if meta := blockchain.BlockMeta(entry.Height); meta != nil {
+				blockHash = encodeData(meta.Hash)
+			}
+			blockHashes[entry.Height] = blockHash
+		}
+		ethLogs = append(ethLogs, newEthLog(entry, blockHash))
+	}
+	return ethLogs, 0, nil
+}
+
+// Takes a signed CallTx encoded with go-wire as burrow encodes txs, and returns
+// its hash. Burrow's keys cannot sign Ethereum transactions, so RLP-encoded
+// transactions as Ethereum wallets send are refused.
+func (ethMethods *ethMethods) SendRawTransaction(params []json.RawMessage) (interface{}, int, error) {
+	var rawTx string
+	if err := decodeParams(params, &rawTx); err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	txBytes, err := decodeData(rawTx)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	// An RLP list, as Ethereum transactions are encoded, starts with a byte of
+	// at least 0xc0, which is no type byte of a burrow tx
+	if len(txBytes) > 0 && txBytes[0] >= 0xc0 {
+		return nil, rpc.INVALID_PARAMS, fmt.Errorf("Raw transaction must be a CallTx " +
+			"encoded with go-wire, not an RLP-encoded Ethereum transaction")
+	}
+	tx, err := txs.DecodeTx(txBytes)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, fmt.Errorf("Raw transaction must be a CallTx "+
+			"encoded with go-wire: %v", err)
+	}
+	if _, ok := tx.(*txs.CallTx); !ok {
+		return nil, rpc.INVALID_PARAMS, fmt.Errorf("Raw transaction must be a CallTx")
+	}
+	receipt, err := ethMethods.pipe.Transactor().BroadcastTx(tx)
+	if err != nil {
+		return nil, rpc.INTERNAL_ERROR, err
+	}
+	return encodeData(receipt.TxHash), 0, nil
+}
+
+func newLogFilter(ethFilter *EthLogFilter, latestHeight int) (*txs.LogFilter, error) {
+	fromHeight, err := decodeBlockNumber(ethFilter.FromBlock, latestHeight)
+	if err != nil {
+		return nil, err
+	}
+	toHeight, err := decodeBlockNumber(ethFilter.ToBlock, latestHeight)
+	if err != nil {
+		return nil, err
+	}
+	filter := &txs.LogFilter{FromHeight: fromHeight, ToHeight: toHeight}
+	if toHeight < fromHeight {
+		return nil, fmt.Errorf("fromBlock %v is after toBlock %v", fromHeight, toHeight)
+	}
+	addresses, err := decodeStrings(ethFilter.Address)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid address: %v", err)
+	}
+	for _, address := range addresses {
+		addressBytes, err := decodeAddress(address)
+		if err != nil {
+			return nil, err
+		}
+		filter.Addresses = append(filter.Addresses, addressBytes)
+	}
+	for i, topicsParam := range ethFilter.Topics {
+		topics, err := decodeStrings(topicsParam)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid topic %v: %v", i, err)
+		}
+		var words []Word256
+		for _, topic := range topics {
+			topicBytes, err := decodeData(topic)
+			if err != nil || len(topicBytes) != 32 {
+				return nil, fmt.Errorf("Invalid topic '%s': must be 32 bytes", topic)
+			}
+			words = append(words, LeftPadWord256(topicBytes))
+		}
+		filter.Topics = append(filter.Topics, words)
+	}
+	return filter, nil
+}
+
+func newEthTransaction(txHash string, block *tm_types.Block, index int,
+	tx txs.Tx) *EthTransaction {
+	ethTx := &EthTransaction{
+		Hash:             txHash,
+		BlockHash:        encodeData(block.Hash()),
+		BlockNumber:      encodeQuantity(int64(block.Height)),
+		TransactionIndex: encodeQuantity(int64(index)),
+		Input:            encodeData(nil),
+		Value:            encodeQuantity(0),
+		Gas:              encodeQuantity(0),
+		GasPrice:         encodeQuantity(0),
+		Nonce:            encodeQuantity(0),
+	}
+	switch tx := tx.(type) {
+	case *txs.CallTx:
+		ethTx.From = encodeData(tx.Input.Address)
+		if len(tx.Address) > 0 {
+			to := encodeData(tx.Address)
+			ethTx.To = &to
+		}
+		ethTx.Input = encodeData(tx.Data)
+		ethTx.Value = encodeQuantity(tx.Input.Amount - tx.Fee - tx.GasLimit*tx.GasPrice)
+		ethTx.Gas = encodeQuantity(tx.GasLimit)
+		ethTx.GasPrice = encodeQuantity(tx.GasPrice)
+		ethTx.Nonce = encodeQuantity(int64(tx.Input.Sequence))
+	case *txs.SendTx:
+		if len(tx.Inputs) > 0 {
+			ethTx.From = encodeData(tx.Inputs[0].Address)
+			ethTx.Nonce = encodeQuantity(int64(tx.Inputs[0].Sequence))
+		}
+		if len(tx.Outputs) > 0 {
+			to := encodeData(tx.Outputs[0].Address)
+			ethTx.To = &to
+			ethTx.Value = encodeQuantity(tx.Outputs[0].Amount)
+		}
+	}
+	return ethTx
+}
+
+func newEthLog(entry *txs.LogEntry, blockHash string) *EthLog {
+	topics := make([]string, len(entry.Log.Topics))
+	for i, topic := range entry.Log.Topics {
+		topics[i] = encodeData(topic.Bytes())
+	}
+	return &EthLog{
+		Address:          encodeData(entry.Log.Address.Postfix(20)),
+		Topics:           topics,
+		Data:             encodeData(entry.Log.Data),
+		BlockNumber:      encodeQuantity(int64(entry.Height)),
+		BlockHash:        blockHash,
+		TransactionHash:  encodeData(entry.TxHash),
+		TransactionIndex: encodeQuantity(int64(entry.TxIndex)),
+		LogIndex:         encodeQuantity(int64(entry.LogIndex)),
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/burrow/account"
+	blockchain_types "github.com/hyperledger/burrow/blockchain/types"
+	core_types "github.com/hyperledger/burrow/core/types"
+	definitions "github.com/hyperledger/burrow/definitions"
+	rpc "github.com/hyperledger/burrow/rpc"
+	"github.com/hyperledger/burrow/txs"
+	. "github.com/hyperledger/burrow/word256"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/go-wire"
+	tm_types "github.com/tendermint/tendermint/types"
+)
+
+// The pipe and the parts of it the methods use. The embedded interfaces are
+// nil, so anything else panics.
+type (
+	// Embedding definitions.Accounts itself would hide its Accounts method
+	accountsInterface interface {
+		definitions.Accounts
+	}
+
+	testPipe struct {
+		definitions.Pipe
+		accounts   *testAccounts
+		blockchain *testBlockchain
+		transactor *testTransactor
+	}
+
+	testAccounts struct {
+		accountsInterface
+		byAddress map[string]*account.Account
+	}
+
+	testBlockchain struct {
+		blockchain_types.Blockchain
+		blocks []*tm_types.Block
+	}
+
+	testTransactor struct {
+		definitions.Transactor
+		logs        []*txs.LogEntry
+		broadcast   []txs.Tx
+		logRequests int
+	}
+)
+
+func (pipe *testPipe) Accounts() definitions.Accounts          { return pipe.accounts }
+func (pipe *testPipe) Blockchain() blockchain_types.Blockchain { return pipe.blockchain }
+func (pipe *testPipe) Transactor() definitions.Transactor      { return pipe.transactor }
+
+func (accounts *testAccounts) Account(address []byte) (*account.Account, error) {
+	if acc, ok := accounts.byAddress[string(address)]; ok {
+		return acc, nil
+	}
+	return &account.Account{Address: address}, nil
+}
+
+func (accounts *testAccounts) StorageAt(address, key []byte) (*core_types.StorageItem, error) {
+	if LeftPadWord256(key) == Zero256 {
+		return &core_types.StorageItem{Key: key, Value: []byte{0x2a}}, nil
+	}
+	return &core_types.StorageItem{Key: key, Value: []byte{}}, nil
+}
+
+func (blockchain *testBlockchain) ChainId() string { return "eth_test" }
+func (blockchain *testBlockchain) Height() int     { return len(blockchain.blocks) }
+func (blockchain *testBlockchain) Block(height int) *tm_types.Block {
+	return blockchain.blocks[height-1]
+}
+
+func (blockchain *testBlockchain) BlockMeta(height int) *tm_types.BlockMeta {
+	return &tm_types.BlockMeta{Hash: blockchain.Block(height).Hash()}
+}
+
+func (transactor *testTransactor) Call(fromAddress, toAddress, data []byte) (*core_types.Call, error) {
+	if len(data) > 0 {
+		return &core_types.Call{Exception: "reverted", RevertReason: "no"}, nil
+	}
+	return &core_types.Call{Return: "CAFE"}, nil
+}
+
+// Serves at most two logs at once
+func (transactor *testTransactor) GetLogs(filter *txs.LogFilter) (*txs.LogSearchResult, error) {
+	transactor.logRequests++
+	var matched []*txs.LogEntry
+	for _, entry := range transactor.logs {
+		if entry.Height >= filter.FromHeight && entry.Height <= filter.ToHeight &&
+			filter.Matches(entry.Log) {
+			matched = append(matched, entry)
+		}
+	}
+	result := &txs.LogSearchResult{}
+	for i, entry := range matched[filter.Offset:] {
+		if i == 2 {
+			result.More = true
+			break
+		}
+		result.Logs = append(result.Logs, entry)
+	}
+	return result, nil
+}
+
+func (transactor *testTransactor) BroadcastTx(tx txs.Tx) (*txs.Receipt, error) {
+	transactor.broadcast = append(transactor.broadcast, tx)
+	receipt := txs.GenerateReceipt("eth_test", tx)
+	return &receipt, nil
+}
+
+func TestEthMethods(t *testing.T) {
+	caller := account.GenPrivAccountFromSecret("caller")
+	contract := LeftPadBytes([]byte{0xC}, 20)
+	callTx := txs.NewCallTxWithNonce(caller.PubKey, contract, []byte{1, 2}, 100, 10, 1, 3)
+	callTx.Sign("eth_test", caller)
+	blocks := []*tm_types.Block{
+		{
+			Header: &tm_types.Header{ChainID: "eth_test", Height: 1, Time: time.Unix(1000, 0), NumTxs: 1},
+			Data: &tm_types.Data{Txs: tm_types.Txs{[]byte("not a tx"),
+				wire.BinaryBytes(struct{ txs.Tx }{callTx})}},
+		},
+		{
+			Header: &tm_types.Header{ChainID: "eth_test", Height: 2, Time: time.Unix(1001, 0)},
+			Data:   &tm_types.Data{},
+		},
+	}
+	topic := Int64ToWord256(7)
+	transactor := &testTransactor{
+		logs: []*txs.LogEntry{
+			{Height: 1, LogIndex: 0, Log: &txs.EventDataLog{Address: LeftPadWord256(contract), Topics: []Word256{topic}}},
+			{Height: 1, LogIndex: 1, Log: &txs.EventDataLog{Address: LeftPadWord256(contract)}},
+			{Height: 2, LogIndex: 0, Log: &txs.EventDataLog{Address: Int64ToWord256(1), Topics: []Word256{topic}}},
+		},
+	}
+	service := NewEthService(&testPipe{
+		accounts: &testAccounts{byAddress: map[string]*account.Account{
+			string(caller.Address): {Address: caller.Address, Balance: 1000},
+			string(contract):       {Address: contract, Code: []byte{0x60, 0x00}},
+		}},
+		blockchain: &testBlockchain{blocks: blocks},
+		transactor: transactor,
+	})
+
+	call := func(method string, params ...interface{}) map[string]interface{} {
+		paramsJSON, err := json.Marshal(params)
+		assert.NoError(t, err)
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"%s","params":%s}`, method, paramsJSON)
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest("POST", "/eth", strings.NewReader(body))
+		assert.NoError(t, err)
+		service.Process(request, recorder)
+		response := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response), recorder.Body.String())
+		assert.Equal(t, float64(1), response["id"])
+		return response
+	}
+	result := func(method string, params ...interface{}) interface{} {
+		response := call(method, params...)
+		assert.Nil(t, response["error"], "%s: %v", method, response["error"])
+		return response["result"]
+	}
+	errorCode := func(method string, params ...interface{}) interface{} {
+		response := call(method, params...)
+		if assert.NotNil(t, response["error"], method) {
+			return response["error"].(map[string]interface{})["code"]
+		}
+		return nil
+	}
+
+	assert.Equal(t, "0x2", result(ETH_BLOCK_NUMBER))
+	assert.Equal(t, "eth_test", result(NET_VERSION))
+	assert.Equal(t, "0x3e8", result(ETH_GET_BALANCE, encodeData(caller.Address), "latest"))
+	assert.Equal(t, "0x0", result(ETH_GET_BALANCE, encodeData(contract)))
+	assert.Equal(t, float64(rpc.INVALID_PARAMS), errorCode(ETH_GET_BALANCE, encodeData(caller.Address), "0x1"))
+	assert.Equal(t, float64(rpc.INVALID_PARAMS), errorCode(ETH_GET_BALANCE, "0x1234"))
+	assert.Equal(t, "0x6000", result(ETH_GET_CODE, encodeData(contract), "latest"))
+	assert.Equal(t, encodeData(LeftPadWord256([]byte{0x2a}).Bytes()),
+		result(ETH_GET_STORAGE_AT, encodeData(contract), "0x0", "latest"))
+
+	assert.Equal(t, "0xcafe", result(ETH_CALL, map[string]string{"to": encodeData(contract)}, "latest"))
+	assert.Equal(t, float64(rpc.INTERNAL_ERROR),
+		errorCode(ETH_CALL, map[string]string{"to": encodeData(contract), "data": "0x01"}))
+
+	block := result(ETH_GET_BLOCK_BY_NUMBER, "0x1", true).(map[string]interface{})
+	assert.Equal(t, "0x1", block["number"])
+	assert.Equal(t, encodeQuantity(1000), block["timestamp"])
+	if txList := block["transactions"].([]interface{}); assert.Len(t, txList, 1) {
+		tx := txList[0].(map[string]interface{})
+		assert.Equal(t, encodeData(txs.TxHash("eth_test", callTx)), tx["hash"])
+		assert.Equal(t, encodeData(caller.Address), tx["from"])
+		assert.Equal(t, encodeData(contract), tx["to"])
+		assert.Equal(t, "0x0102", tx["input"])
+		assert.Equal(t, "0x3", tx["nonce"])
+		assert.Equal(t, "0x1", tx["transactionIndex"])
+	}
+	block = result(ETH_GET_BLOCK_BY_NUMBER, "latest", false).(map[string]interface{})
+	assert.Equal(t, "0x2", block["number"])
+	assert.Nil(t, result(ETH_GET_BLOCK_BY_NUMBER, "0x9", false))
+
+	logs := result(ETH_GET_LOGS, map[string]interface{}{
+		"fromBlock": "earliest",
+		"address":   encodeData(contract),
+	}).([]interface{})
+	assert.Len(t, logs, 2)
+	assert.Equal(t, 1, transactor.logRequests)
+	logs = result(ETH_GET_LOGS, map[string]interface{}{
+		"fromBlock": "0x1",
+		"topics":    []interface{}{encodeData(topic.Bytes())},
+	}).([]interface{})
+	if assert.Len(t, logs, 2) {
+		log := logs[1].(map[string]interface{})
+		assert.Equal(t, "0x2", log["blockNumber"])
+		assert.Equal(t, []interface{}{encodeData(topic.Bytes())}, log["topics"])
+	}
+	assert.Equal(t, float64(rpc.INVALID_PARAMS), errorCode(ETH_GET_LOGS,
+		map[string]interface{}{"fromBlock": "0x2", "toBlock": "0x1"}))
+	// More logs match than are returned at once
+	assert.Equal(t, float64(rpc.INVALID_PARAMS), errorCode(ETH_GET_LOGS,
+		map[string]interface{}{"fromBlock": "0x1"}))
+
+	rawTx := encodeData(wire.BinaryBytes(struct{ txs.Tx }{callTx}))
+	assert.Equal(t, encodeData(txs.TxHash("eth_test", callTx)), result(ETH_SEND_RAW_TRANSACTION, rawTx))
+	assert.Len(t, transactor.broadcast, 1)
+	sendTx := txs.NewSendTx()
+	sendTx.AddInputWithNonce(caller.PubKey, 1, 1)
+	assert.Equal(t, float64(rpc.INVALID_PARAMS), errorCode(ETH_SEND_RAW_TRANSACTION,
+		encodeData(wire.BinaryBytes(struct{ txs.Tx }{sendTx}))))
+	// An RLP-encoded Ethereum transaction
+	assert.Equal(t, float64(rpc.INVALID_PARAMS), errorCode(ETH_SEND_RAW_TRANSACTION,
+		"0xf86b8085012a05f200825208943535353535353535353535353535353535353535880de0b6b3a764000080"))
+
+	assert.Equal(t, float64(rpc.METHOD_NOT_FOUND), errorCode("eth_mining"))
+}
+
+func TestEthBatchRequest(t *testing.T) {
+	service := NewEthService(&testPipe{blockchain: &testBlockchain{}})
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest("POST", "/eth", bytes.NewBufferString(`[
+		{"jsonrpc":"2.0","id":"a","method":"eth_blockNumber","params":[]},
+		{"jsonrpc":"2.0","id":2,"method":"net_version"}
+	]`))
+	assert.NoError(t, err)
+	service.Process(request, recorder)
+	var responses []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responses))
+	if assert.Len(t, responses, 2) {
+		assert.Equal(t, "a", responses[0]["id"])
+		assert.Equal(t, "0x0", responses[0]["result"])
+		assert.Equal(t, float64(2), responses[1]["id"])
+		assert.Equal(t, "eth_test", responses[1]["result"])
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eth
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	definitions "github.com/hyperledger/burrow/definitions"
+	rpc "github.com/hyperledger/burrow/rpc"
+	server "github.com/hyperledger/burrow/server"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Requests and responses of the Ethereum JSON-RPC API. Unlike ours, its ids
+// may be numbers as well as strings and its params are always positional.
+type (
+	EthRequest struct {
+		JSONRPC string            `json:"jsonrpc"`
+		Method  string            `json:"method"`
+		Params  []json.RawMessage `json:"params"`
+		Id      json.RawMessage   `json:"id"`
+	}
+
+	EthResultResponse struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Id      json.RawMessage `json:"id"`
+		Result  interface{}     `json:"result"`
+	}
+
+	EthErrorResponse struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Id      json.RawMessage `json:"id"`
+		Error   *rpc.RPCError   `json:"error"`
+	}
+)
+
+// Server of the subset of the Ethereum JSON-RPC API that web3 libraries need,
+// so that they can be used against burrow. Implements server.Server
+type EthJsonRpcServer struct {
+	service *EthService
+	running bool
+}
+
+func NewEthJsonRpcServer(pipe definitions.Pipe) *EthJsonRpcServer {
+	return &EthJsonRpcServer{service: NewEthService(pipe)}
+}
+
+// Start adds the Ethereum rpc path to the router.
+func (this *EthJsonRpcServer) Start(config *server.ServerConfig,
+	router *gin.Engine) {
+	router.POST(config.HTTP.EthJsonRpcEndpoint, this.handleFunc)
+	this.running = true
+}
+
+// Is the server currently running?
+func (this *EthJsonRpcServer) Running() bool {
+	return this.running
+}
+
+// Shut the server down. Does nothing.
+func (this *EthJsonRpcServer) ShutDown() {
+	this.running = false
+}
+
+func (this *EthJsonRpcServer) handleFunc(c *gin.Context) {
+	this.service.Process(c.Request, c.Writer)
+}
+
+// Answers Ethereum JSON-RPC requests from the pipe. Implements
+// server.HttpService
+type EthService struct {
+	methods map[string]EthHandlerFunc
+}
+
+func NewEthService(pipe definitions.Pipe) *EthService {
+	return &EthService{methods: newEthMethods(pipe).getMethods()}
+}
+
+// Process a request, or a batch of them.
+func (this *EthService) Process(r *http.Request, w http.ResponseWriter) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		this.writeJSON(newErrorResponse(nil, rpc.PARSE_ERROR, err.Error()), w)
+		return
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var requests []*EthRequest
+		if err := json.Unmarshal(body, &requests); err != nil {
+			this.writeJSON(newErrorResponse(nil, rpc.PARSE_ERROR,
+				"Failed to parse request: "+err.Error()), w)
+			return
+		}
+		responses := make([]interface{}, len(requests))
+		for i, req := range requests {
+			responses[i] = this.handle(req)
+		}
+		this.writeJSON(responses, w)
+		return
+	}
+	req := new(EthRequest)
+	if err := json.Unmarshal(body, req); err != nil {
+		this.writeJSON(newErrorResponse(nil, rpc.PARSE_ERROR,
+			"Failed to parse request: "+err.Error()), w)
+		return
+	}
+	this.writeJSON(this.handle(req), w)
+}
+
+func (this *EthService) handle(req *EthRequest) interface{} {
+	if req.JSONRPC != "2.0" {
+		return newErrorResponse(req.Id, rpc.INVALID_REQUEST,
+			"Wrong protocol version: "+req.JSONRPC)
+	}
+	handler, ok := this.methods[req.Method]
+	if !ok {
+		return newErrorResponse(req.Id, rpc.METHOD_NOT_FOUND,
+			"Method not found: "+req.Method)
+	}
+	result, errCode, err := handler(req.Params)
+	if err != nil {
+		return newErrorResponse(req.Id, errCode, err.Error())
+	}
+	return &EthResultResponse{JSONRPC: "2.0", Id: req.Id, Result: result}
+}
+
+func (this *EthService) writeJSON(response interface{}, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "Failed to marshal response: "+err.Error(), 500)
+	}
+}
+
+func newErrorResponse(id json.RawMessage, code int, msg string) *EthErrorResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &EthErrorResponse{
+		JSONRPC: "2.0",
+		Id:      id,
+		Error:   &rpc.RPCError{Code: code, Message: msg},
+	}
+}
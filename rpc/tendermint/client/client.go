@@ -22,6 +22,7 @@ import (
 	core_types "github.com/hyperledger/burrow/core/types"
 	rpc_types "github.com/hyperledger/burrow/rpc/tendermint/core/types"
 	"github.com/hyperledger/burrow/txs"
+	abci "github.com/tendermint/abci/types"
 	rpcclient "github.com/tendermint/go-rpc/client"
 	"github.com/tendermint/go-wire"
 )
@@ -50,9 +51,12 @@ func GenPrivAccount(client rpcclient.Client) (*acm.PrivAccount, error) {
 	return res.(*rpc_types.ResultGenPrivAccount).PrivAccount, nil
 }
 
-func GetAccount(client rpcclient.Client, address []byte) (*acm.Account, error) {
+// Calls taking a height query the state as it was after committing the block
+// at that height, or the latest state for height 0
+func GetAccount(client rpcclient.Client, address []byte, height int) (*acm.Account, error) {
 	res, err := performCall(client, "get_account",
-		"address", address)
+		"address", address,
+		"height", height)
 	if err != nil {
 		return nil, err
 	}
@@ -70,6 +74,8 @@ func SignTx(client rpcclient.Client, tx txs.Tx,
 	return res.(*rpc_types.ResultSignTx).Tx, nil
 }
 
+// If the tx is rejected returns its receipt, with the ABCI code saying why it
+// was rejected, as well as an error
 func BroadcastTx(client rpcclient.Client,
 	tx txs.Tx) (txs.Receipt, error) {
 	res, err := performCall(client, "broadcast_tx",
@@ -77,27 +83,37 @@ func BroadcastTx(client rpcclient.Client,
 	if err != nil {
 		return txs.Receipt{}, err
 	}
-	receiptBytes := res.(*rpc_types.ResultBroadcastTx).Data
-	receipt := txs.Receipt{}
-	err = wire.ReadBinaryBytes(receiptBytes, &receipt)
-	return receipt, err
-
+	result := res.(*rpc_types.ResultBroadcastTx)
+	receipt := txs.Receipt{Code: result.Code, Log: result.Log}
+	if len(result.Data) > 0 {
+		err = wire.ReadBinaryBytes(result.Data, &receipt)
+		if err != nil {
+			return receipt, err
+		}
+	}
+	if result.Code != abci.CodeType_OK {
+		return receipt, fmt.Errorf("Transaction rejected with code %v: %s",
+			result.Code, result.Log)
+	}
+	return receipt, nil
 }
 
 func DumpStorage(client rpcclient.Client,
-	address []byte) (*rpc_types.ResultDumpStorage, error) {
+	address []byte, height int) (*rpc_types.ResultDumpStorage, error) {
 	res, err := performCall(client, "dump_storage",
-		"address", address)
+		"address", address,
+		"height", height)
 	if err != nil {
 		return nil, err
 	}
 	return res.(*rpc_types.ResultDumpStorage), err
 }
 
-func GetStorage(client rpcclient.Client, address, key []byte) ([]byte, error) {
+func GetStorage(client rpcclient.Client, address, key []byte, height int) ([]byte, error) {
 	res, err := performCall(client, "get_storage",
 		"address", address,
-		"key", key)
+		"key", key,
+		"height", height)
 	if err != nil {
 		return nil, err
 	}
@@ -117,20 +133,94 @@ func CallCode(client rpcclient.Client, fromAddress, code,
 }
 
 func Call(client rpcclient.Client, fromAddress, toAddress,
-	data []byte) (*rpc_types.ResultCall, error) {
+	data []byte, height int) (*rpc_types.ResultCall, error) {
 	res, err := performCall(client, "call",
 		"fromAddress", fromAddress,
 		"toAddress", toAddress,
-		"data", data)
+		"data", data,
+		"height", height)
 	if err != nil {
 		return nil, err
 	}
 	return res.(*rpc_types.ResultCall), err
 }
 
-func GetName(client rpcclient.Client, name string) (*core_types.NameRegEntry, error) {
+func TraceCall(client rpcclient.Client, fromAddress, toAddress,
+	data []byte) (*core_types.CallTrace, error) {
+	res, err := performCall(client, "trace_call",
+		"fromAddress", fromAddress,
+		"toAddress", toAddress,
+		"data", data)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*rpc_types.ResultTrace).Trace, err
+}
+
+func TraceTx(client rpcclient.Client, tx txs.Tx) (*core_types.CallTrace, error) {
+	res, err := performCall(client, "trace_tx",
+		"tx", wrappedTx{tx})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*rpc_types.ResultTrace).Trace, err
+}
+
+func ReplayTx(client rpcclient.Client, height int, hash []byte,
+	trace bool) (*rpc_types.ResultReplayTx, error) {
+	res, err := performCall(client, "replay_tx",
+		"height", height,
+		"hash", hash,
+		"trace", trace)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*rpc_types.ResultReplayTx), err
+}
+
+func EstimateGas(client rpcclient.Client, fromAddress, toAddress,
+	data []byte) (int64, error) {
+	res, err := performCall(client, "estimate_gas",
+		"fromAddress", fromAddress,
+		"toAddress", toAddress,
+		"data", data)
+	if err != nil {
+		return 0, err
+	}
+	return res.(*rpc_types.ResultEstimateGas).GasLimit, nil
+}
+
+func GetTxReceipt(client rpcclient.Client, hash []byte) (*txs.TxReceipt, error) {
+	res, err := performCall(client, "get_tx_receipt",
+		"hash", hash)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*rpc_types.ResultGetTxReceipt).Receipt, nil
+}
+
+func SearchTxs(client rpcclient.Client, filter *txs.TxFilter) (*rpc_types.ResultSearchTxs, error) {
+	res, err := performCall(client, "search_txs",
+		"filter", filter)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*rpc_types.ResultSearchTxs), nil
+}
+
+func GetLogs(client rpcclient.Client, filter *txs.LogFilter) (*rpc_types.ResultGetLogs, error) {
+	res, err := performCall(client, "get_logs",
+		"filter", filter)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*rpc_types.ResultGetLogs), nil
+}
+
+func GetName(client rpcclient.Client, name string, height int) (*core_types.NameRegEntry, error) {
 	res, err := performCall(client, "get_name",
-		"name", name)
+		"name", name,
+		"height", height)
 	if err != nil {
 		return nil, err
 	}
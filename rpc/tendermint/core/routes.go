@@ -44,14 +44,21 @@ func (tmRoutes *TendermintRoutes) GetRoutes() map[string]*rpc.RPCFunc {
 		"net_info":                rpc.NewRPCFunc(tmRoutes.NetInfoResult, ""),
 		"genesis":                 rpc.NewRPCFunc(tmRoutes.GenesisResult, ""),
 		"chain_id":                rpc.NewRPCFunc(tmRoutes.ChainIdResult, ""),
-		"get_account":             rpc.NewRPCFunc(tmRoutes.GetAccountResult, "address"),
-		"get_storage":             rpc.NewRPCFunc(tmRoutes.GetStorageResult, "address,key"),
-		"call":                    rpc.NewRPCFunc(tmRoutes.CallResult, "fromAddress,toAddress,data"),
+		"get_account":             rpc.NewRPCFunc(tmRoutes.GetAccountResult, "address,height"),
+		"get_storage":             rpc.NewRPCFunc(tmRoutes.GetStorageResult, "address,key,height"),
+		"call":                    rpc.NewRPCFunc(tmRoutes.CallResult, "fromAddress,toAddress,data,height"),
 		"call_code":               rpc.NewRPCFunc(tmRoutes.CallCodeResult, "fromAddress,code,data"),
-		"dump_storage":            rpc.NewRPCFunc(tmRoutes.DumpStorageResult, "address"),
-		"list_accounts":           rpc.NewRPCFunc(tmRoutes.ListAccountsResult, ""),
-		"get_name":                rpc.NewRPCFunc(tmRoutes.GetNameResult, "name"),
-		"list_names":              rpc.NewRPCFunc(tmRoutes.ListNamesResult, ""),
+		"trace_call":              rpc.NewRPCFunc(tmRoutes.TraceCallResult, "fromAddress,toAddress,data"),
+		"trace_tx":                rpc.NewRPCFunc(tmRoutes.TraceTxResult, "tx"),
+		"replay_tx":               rpc.NewRPCFunc(tmRoutes.ReplayTxResult, "height,hash,trace"),
+		"estimate_gas":            rpc.NewRPCFunc(tmRoutes.EstimateGasResult, "fromAddress,toAddress,data"),
+		"get_tx_receipt":          rpc.NewRPCFunc(tmRoutes.GetTxReceiptResult, "hash"),
+		"search_txs":              rpc.NewRPCFunc(tmRoutes.SearchTxsResult, "filter"),
+		"get_logs":                rpc.NewRPCFunc(tmRoutes.GetLogsResult, "filter"),
+		"dump_storage":            rpc.NewRPCFunc(tmRoutes.DumpStorageResult, "address,height"),
+		"list_accounts":           rpc.NewRPCFunc(tmRoutes.ListAccountsResult, "height"),
+		"get_name":                rpc.NewRPCFunc(tmRoutes.GetNameResult, "name,height"),
+		"list_names":              rpc.NewRPCFunc(tmRoutes.ListNamesResult, "height"),
 		"broadcast_tx":            rpc.NewRPCFunc(tmRoutes.BroadcastTxResult, "tx"),
 		"blockchain":              rpc.NewRPCFunc(tmRoutes.BlockchainInfo, "minHeight,maxHeight"),
 		"get_block":               rpc.NewRPCFunc(tmRoutes.GetBlock, "height"),
@@ -130,16 +137,16 @@ func (tmRoutes *TendermintRoutes) ChainIdResult() (ctypes.BurrowResult, error) {
 	}
 }
 
-func (tmRoutes *TendermintRoutes) GetAccountResult(address []byte) (ctypes.BurrowResult, error) {
-	if r, err := tmRoutes.tendermintPipe.GetAccount(address); err != nil {
+func (tmRoutes *TendermintRoutes) GetAccountResult(address []byte, height int) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.GetAccount(address, height); err != nil {
 		return nil, err
 	} else {
 		return r, nil
 	}
 }
 
-func (tmRoutes *TendermintRoutes) GetStorageResult(address, key []byte) (ctypes.BurrowResult, error) {
-	if r, err := tmRoutes.tendermintPipe.GetStorage(address, key); err != nil {
+func (tmRoutes *TendermintRoutes) GetStorageResult(address, key []byte, height int) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.GetStorage(address, key, height); err != nil {
 		return nil, err
 	} else {
 		return r, nil
@@ -147,8 +154,8 @@ func (tmRoutes *TendermintRoutes) GetStorageResult(address, key []byte) (ctypes.
 }
 
 func (tmRoutes *TendermintRoutes) CallResult(fromAddress, toAddress,
-	data []byte) (ctypes.BurrowResult, error) {
-	if r, err := tmRoutes.tendermintPipe.Call(fromAddress, toAddress, data); err != nil {
+	data []byte, height int) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.Call(fromAddress, toAddress, data, height); err != nil {
 		return nil, err
 	} else {
 		return r, nil
@@ -164,32 +171,91 @@ func (tmRoutes *TendermintRoutes) CallCodeResult(fromAddress, code,
 	}
 }
 
-func (tmRoutes *TendermintRoutes) DumpStorageResult(address []byte) (ctypes.BurrowResult, error) {
-	if r, err := tmRoutes.tendermintPipe.DumpStorage(address); err != nil {
+func (tmRoutes *TendermintRoutes) TraceCallResult(fromAddress, toAddress,
+	data []byte) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.TraceCall(fromAddress, toAddress, data); err != nil {
+		return nil, err
+	} else {
+		return r, nil
+	}
+}
+
+func (tmRoutes *TendermintRoutes) TraceTxResult(tx txs.Tx) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.TraceTx(tx); err != nil {
+		return nil, err
+	} else {
+		return r, nil
+	}
+}
+
+func (tmRoutes *TendermintRoutes) ReplayTxResult(height int, hash []byte,
+	trace bool) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.ReplayTx(height, hash, trace); err != nil {
+		return nil, err
+	} else {
+		return r, nil
+	}
+}
+
+func (tmRoutes *TendermintRoutes) EstimateGasResult(fromAddress, toAddress,
+	data []byte) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.EstimateGas(fromAddress, toAddress, data); err != nil {
+		return nil, err
+	} else {
+		return r, nil
+	}
+}
+
+func (tmRoutes *TendermintRoutes) GetTxReceiptResult(hash []byte) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.GetTxReceipt(hash); err != nil {
+		return nil, err
+	} else {
+		return r, nil
+	}
+}
+
+func (tmRoutes *TendermintRoutes) SearchTxsResult(filter *txs.TxFilter) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.SearchTxs(filter); err != nil {
+		return nil, err
+	} else {
+		return r, nil
+	}
+}
+
+func (tmRoutes *TendermintRoutes) GetLogsResult(filter *txs.LogFilter) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.GetLogs(filter); err != nil {
+		return nil, err
+	} else {
+		return r, nil
+	}
+}
+
+func (tmRoutes *TendermintRoutes) DumpStorageResult(address []byte, height int) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.DumpStorage(address, height); err != nil {
 		return nil, err
 	} else {
 		return r, nil
 	}
 }
 
-func (tmRoutes *TendermintRoutes) ListAccountsResult() (ctypes.BurrowResult, error) {
-	if r, err := tmRoutes.tendermintPipe.ListAccounts(); err != nil {
+func (tmRoutes *TendermintRoutes) ListAccountsResult(height int) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.ListAccounts(height); err != nil {
 		return nil, err
 	} else {
 		return r, nil
 	}
 }
 
-func (tmRoutes *TendermintRoutes) GetNameResult(name string) (ctypes.BurrowResult, error) {
-	if r, err := tmRoutes.tendermintPipe.GetName(name); err != nil {
+func (tmRoutes *TendermintRoutes) GetNameResult(name string, height int) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.GetName(name, height); err != nil {
 		return nil, err
 	} else {
 		return r, nil
 	}
 }
 
-func (tmRoutes *TendermintRoutes) ListNamesResult() (ctypes.BurrowResult, error) {
-	if r, err := tmRoutes.tendermintPipe.ListNames(); err != nil {
+func (tmRoutes *TendermintRoutes) ListNamesResult(height int) (ctypes.BurrowResult, error) {
+	if r, err := tmRoutes.tendermintPipe.ListNames(height); err != nil {
 		return nil, err
 	} else {
 		return r, nil
@@ -18,6 +18,7 @@ import (
 	acm "github.com/hyperledger/burrow/account"
 	core_types "github.com/hyperledger/burrow/core/types"
 	genesis "github.com/hyperledger/burrow/genesis"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
 	"github.com/hyperledger/burrow/txs"
 	tendermint_types "github.com/tendermint/tendermint/types"
 
@@ -37,9 +38,44 @@ type ResultGetStorage struct {
 type ResultCall struct {
 	Return  []byte `json:"return"`
 	GasUsed int64  `json:"gas_used"`
+	// Set when the call reverted, in which case Return holds its output
+	Exception    string `json:"exception"`
+	RevertReason string `json:"revert_reason"`
+	// Set when the callee's ABI has been registered
+	Decoded *abi.DecodedCall `json:"decoded"`
 	// TODO ...
 }
 
+type ResultTrace struct {
+	Trace *core_types.CallTrace `json:"trace"`
+}
+
+type ResultReplayTx struct {
+	Height int `json:"height"`
+	// Every event the tx fired, in order
+	Events []*ResultEvent `json:"events"`
+	// Only set for txs that ran the VM, with the ops run if a trace was asked for
+	Trace *core_types.CallTrace `json:"trace"`
+}
+
+type ResultEstimateGas struct {
+	GasLimit int64 `json:"gas_limit"`
+}
+
+type ResultGetTxReceipt struct {
+	Receipt *txs.TxReceipt `json:"receipt"`
+}
+
+type ResultSearchTxs struct {
+	Total    int              `json:"total"`
+	Receipts []*txs.TxReceipt `json:"receipts"`
+}
+
+type ResultGetLogs struct {
+	Logs []*txs.LogEntry `json:"logs"`
+	More bool            `json:"more"`
+}
+
 type ResultListAccounts struct {
 	BlockHeight int            `json:"block_height"`
 	Accounts    []*acm.Account `json:"accounts"`
@@ -179,6 +215,12 @@ const (
 	ResultTypeUnsubscribe        = byte(0x15)
 	ResultTypePeerConsensusState = byte(0x16)
 	ResultTypeChainId            = byte(0x17)
+	ResultTypeTrace              = byte(0x18)
+	ResultTypeReplayTx           = byte(0x19)
+	ResultTypeEstimateGas        = byte(0x1A)
+	ResultTypeGetTxReceipt       = byte(0x1B)
+	ResultTypeSearchTxs          = byte(0x1C)
+	ResultTypeGetLogs            = byte(0x1D)
 )
 
 type BurrowResult interface {
@@ -210,6 +252,12 @@ func ConcreteTypes() []wire.ConcreteType {
 		{&ResultSubscribe{}, ResultTypeSubscribe},
 		{&ResultUnsubscribe{}, ResultTypeUnsubscribe},
 		{&ResultChainId{}, ResultTypeChainId},
+		{&ResultTrace{}, ResultTypeTrace},
+		{&ResultReplayTx{}, ResultTypeReplayTx},
+		{&ResultEstimateGas{}, ResultTypeEstimateGas},
+		{&ResultGetTxReceipt{}, ResultTypeGetTxReceipt},
+		{&ResultSearchTxs{}, ResultTypeSearchTxs},
+		{&ResultGetLogs{}, ResultTypeGetLogs},
 	}
 }
 
@@ -189,7 +189,7 @@ func makeDefaultNameTx(t *testing.T, client rpcclient.Client, name, value string
 
 // get an account's nonce
 func getNonce(t *testing.T, client rpcclient.Client, addr []byte) int {
-	ac, err := edbcli.GetAccount(client, addr)
+	ac, err := edbcli.GetAccount(client, addr, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -201,7 +201,7 @@ func getNonce(t *testing.T, client rpcclient.Client, addr []byte) int {
 
 // get the account
 func getAccount(t *testing.T, client rpcclient.Client, addr []byte) *acm.Account {
-	ac, err := edbcli.GetAccount(client, addr)
+	ac, err := edbcli.GetAccount(client, addr, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -231,7 +231,7 @@ func broadcastTx(t *testing.T, client rpcclient.Client, tx txs.Tx) txs.Receipt {
 // dump all storage for an account. currently unused
 func dumpStorage(t *testing.T, addr []byte) *rpc_types.ResultDumpStorage {
 	client := clients["HTTP"]
-	resp, err := edbcli.DumpStorage(client, addr)
+	resp, err := edbcli.DumpStorage(client, addr, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -239,7 +239,7 @@ func dumpStorage(t *testing.T, addr []byte) *rpc_types.ResultDumpStorage {
 }
 
 func getStorage(t *testing.T, client rpcclient.Client, addr, key []byte) []byte {
-	resp, err := edbcli.GetStorage(client, addr, key)
+	resp, err := edbcli.GetStorage(client, addr, key, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -261,7 +261,7 @@ func callCode(t *testing.T, client rpcclient.Client, fromAddress, code, data,
 
 func callContract(t *testing.T, client rpcclient.Client, fromAddress, toAddress,
 	data, expected []byte) {
-	resp, err := edbcli.Call(client, fromAddress, toAddress, data)
+	resp, err := edbcli.Call(client, fromAddress, toAddress, data, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -274,7 +274,7 @@ func callContract(t *testing.T, client rpcclient.Client, fromAddress, toAddress,
 
 // get the namereg entry
 func getNameRegEntry(t *testing.T, client rpcclient.Client, name string) *core_types.NameRegEntry {
-	entry, err := edbcli.GetName(client, name)
+	entry, err := edbcli.GetName(client, name, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -21,6 +21,7 @@ import (
 	wire "github.com/tendermint/go-wire"
 
 	rpc "github.com/hyperledger/burrow/rpc"
+	"github.com/hyperledger/burrow/txs"
 )
 
 // Codec that uses tendermints 'binary' package for JSON.
@@ -55,6 +56,7 @@ func (this *TCodec) Decode(v interface{}, r io.Reader) error {
 	}
 	var err error
 	wire.ReadJSON(v, bts, &err)
+	upgradeTx(v)
 	return err
 }
 
@@ -62,6 +64,7 @@ func (this *TCodec) Decode(v interface{}, r io.Reader) error {
 func (this *TCodec) DecodeBytes(v interface{}, bts []byte) error {
 	var err error
 	wire.ReadJSON(v, bts, &err)
+	upgradeTx(v)
 	return err
 }
 
@@ -69,5 +72,13 @@ func (this *TCodec) DecodeBytes(v interface{}, bts []byte) error {
 func (this *TCodec) DecodeBytesPtr(v interface{}, bts []byte) error {
 	var err error
 	wire.ReadJSONPtr(v, bts, &err)
+	upgradeTx(v)
 	return err
 }
+
+// CallTxs sent as they were before gas was priced are decoded as CallTxs
+func upgradeTx(v interface{}) {
+	if tx, ok := v.(*txs.Tx); ok && *tx != nil {
+		*tx = txs.UpgradeTx(*tx)
+	}
+}
@@ -52,6 +52,12 @@ const (
 	GET_PEER                  = SERVICE_NAME + ".getPeer"
 	CALL                      = SERVICE_NAME + ".call" // Tx
 	CALL_CODE                 = SERVICE_NAME + ".callCode"
+	TRACE_CALL                = SERVICE_NAME + ".traceCall"
+	TRACE_TX                  = SERVICE_NAME + ".traceTx"
+	ESTIMATE_GAS              = SERVICE_NAME + ".estimateGas"
+	GET_TX_RECEIPT            = SERVICE_NAME + ".getTxReceipt"
+	SEARCH_TXS                = SERVICE_NAME + ".searchTxs"
+	GET_LOGS                  = SERVICE_NAME + ".getLogs"
 	BROADCAST_TX              = SERVICE_NAME + ".broadcastTx"
 	GET_UNCONFIRMED_TXS       = SERVICE_NAME + ".getUnconfirmedTxs"
 	SIGN_TX                   = SERVICE_NAME + ".signTx"
@@ -119,6 +125,12 @@ func (burrowMethods *BurrowMethods) getMethods() map[string]RequestHandlerFunc {
 	// Txs
 	dhMap[CALL] = burrowMethods.Call
 	dhMap[CALL_CODE] = burrowMethods.CallCode
+	dhMap[TRACE_CALL] = burrowMethods.TraceCall
+	dhMap[TRACE_TX] = burrowMethods.TraceTx
+	dhMap[ESTIMATE_GAS] = burrowMethods.EstimateGas
+	dhMap[GET_TX_RECEIPT] = burrowMethods.TxReceipt
+	dhMap[SEARCH_TXS] = burrowMethods.SearchTxs
+	dhMap[GET_LOGS] = burrowMethods.GetLogs
 	dhMap[BROADCAST_TX] = burrowMethods.BroadcastTx
 	dhMap[GET_UNCONFIRMED_TXS] = burrowMethods.UnconfirmedTxs
 	dhMap[SIGN_TX] = burrowMethods.SignTx
@@ -358,33 +370,103 @@ func (burrowMethods *BurrowMethods) CallCode(request *rpc.RPCRequest, requester
 	return call, 0, nil
 }
 
-func (burrowMethods *BurrowMethods) BroadcastTx(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
-	// Accept all transaction types as parameter for broadcast.
+func (burrowMethods *BurrowMethods) TraceCall(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
+	param := &CallParam{}
+	err := burrowMethods.codec.DecodeBytes(param, request.Params)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	trace, errC := burrowMethods.pipe.Transactor().TraceCall(param.From, param.Address, param.Data)
+	if errC != nil {
+		return nil, rpc.INTERNAL_ERROR, errC
+	}
+	return trace, 0, nil
+}
+
+func (burrowMethods *BurrowMethods) TraceTx(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
 	param := new(txs.Tx)
 	err := burrowMethods.codec.DecodeBytesPtr(param, request.Params)
 	if err != nil {
 		return nil, rpc.INVALID_PARAMS, err
 	}
-	receipt, errC := burrowMethods.pipe.Transactor().BroadcastTx(*param)
+	trace, errC := burrowMethods.pipe.Transactor().TraceTx(*param)
 	if errC != nil {
 		return nil, rpc.INTERNAL_ERROR, errC
 	}
-	return receipt, 0, nil
+	return trace, 0, nil
 }
 
-func (burrowMethods *BurrowMethods) Transact(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
-	param := &TransactParam{}
+func (burrowMethods *BurrowMethods) EstimateGas(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
+	param := &CallParam{}
+	err := burrowMethods.codec.DecodeBytes(param, request.Params)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	gasLimit, errC := burrowMethods.pipe.Transactor().EstimateGas(param.From, param.Address, param.Data)
+	if errC != nil {
+		return nil, rpc.INTERNAL_ERROR, errC
+	}
+	return &core_types.GasEstimate{GasLimit: gasLimit}, 0, nil
+}
+
+func (burrowMethods *BurrowMethods) TxReceipt(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
+	param := &TxHashParam{}
 	err := burrowMethods.codec.DecodeBytes(param, request.Params)
 	if err != nil {
 		return nil, rpc.INVALID_PARAMS, err
 	}
-	receipt, errC := burrowMethods.pipe.Transactor().Transact(param.PrivKey, param.Address, param.Data, param.GasLimit, param.Fee)
+	receipt, errC := burrowMethods.pipe.Transactor().TxReceipt(param.Hash)
 	if errC != nil {
 		return nil, rpc.INTERNAL_ERROR, errC
 	}
 	return receipt, 0, nil
 }
 
+func (burrowMethods *BurrowMethods) SearchTxs(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
+	param := &txs.TxFilter{}
+	err := burrowMethods.codec.DecodeBytes(param, request.Params)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	result, errC := burrowMethods.pipe.Transactor().SearchTxs(param)
+	if errC != nil {
+		return nil, rpc.INTERNAL_ERROR, errC
+	}
+	return result, 0, nil
+}
+
+func (burrowMethods *BurrowMethods) GetLogs(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
+	param := &txs.LogFilter{}
+	err := burrowMethods.codec.DecodeBytes(param, request.Params)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	result, errC := burrowMethods.pipe.Transactor().GetLogs(param)
+	if errC != nil {
+		return nil, rpc.INTERNAL_ERROR, errC
+	}
+	return result, 0, nil
+}
+
+func (burrowMethods *BurrowMethods) BroadcastTx(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
+	// Accept all transaction types as parameter for broadcast.
+	param := new(txs.Tx)
+	err := burrowMethods.codec.DecodeBytesPtr(param, request.Params)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	return receiptResult(burrowMethods.pipe.Transactor().BroadcastTx(*param))
+}
+
+func (burrowMethods *BurrowMethods) Transact(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
+	param := &TransactParam{}
+	err := burrowMethods.codec.DecodeBytes(param, request.Params)
+	if err != nil {
+		return nil, rpc.INVALID_PARAMS, err
+	}
+	return receiptResult(burrowMethods.pipe.Transactor().Transact(param.PrivKey, param.Address, param.Data, param.GasLimit, param.Fee))
+}
+
 func (burrowMethods *BurrowMethods) TransactAndHold(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
 	param := &TransactParam{}
 	err := burrowMethods.codec.DecodeBytes(param, request.Params)
@@ -404,11 +486,7 @@ func (this *BurrowMethods) Send(request *rpc.RPCRequest, requester interface{})
 	if err != nil {
 		return nil, rpc.INVALID_PARAMS, err
 	}
-	receipt, errC := this.pipe.Transactor().Send(param.PrivKey, param.ToAddress, param.Amount)
-	if errC != nil {
-		return nil, rpc.INTERNAL_ERROR, errC
-	}
-	return receipt, 0, nil
+	return receiptResult(this.pipe.Transactor().Send(param.PrivKey, param.ToAddress, param.Amount))
 }
 
 func (this *BurrowMethods) SendAndHold(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
@@ -430,11 +508,7 @@ func (burrowMethods *BurrowMethods) TransactNameReg(request *rpc.RPCRequest, req
 	if err != nil {
 		return nil, rpc.INVALID_PARAMS, err
 	}
-	receipt, errC := burrowMethods.pipe.Transactor().TransactNameReg(param.PrivKey, param.Name, param.Data, param.Amount, param.Fee)
-	if errC != nil {
-		return nil, rpc.INTERNAL_ERROR, errC
-	}
-	return receipt, 0, nil
+	return receiptResult(burrowMethods.pipe.Transactor().TransactNameReg(param.PrivKey, param.Name, param.Data, param.Amount, param.Fee))
 }
 
 func (burrowMethods *BurrowMethods) UnconfirmedTxs(request *rpc.RPCRequest, requester interface{}) (interface{}, int, error) {
@@ -489,3 +563,12 @@ func (burrowMethods *BurrowMethods) NameRegEntries(request *rpc.RPCRequest, requ
 	}
 	return list, 0, nil
 }
+
+// A tx rejected by the application is reported by the code of its receipt
+// rather than as an error
+func receiptResult(receipt *txs.Receipt, err error) (interface{}, int, error) {
+	if err != nil && receipt == nil {
+		return nil, rpc.INTERNAL_ERROR, err
+	}
+	return receipt, 0, nil
+}
@@ -54,6 +54,11 @@ type (
 		Height int `json:"height"`
 	}
 
+	// Get the receipt of a tx by its hash
+	TxHashParam struct {
+		Hash []byte `json:"hash"`
+	}
+
 	// Get a series of blocks
 	// TODO deprecate in favor of 'FilterListParam'
 	BlocksParam struct {
@@ -88,9 +88,10 @@ func (restServer *RestServer) Start(config *server.ServerConfig, router *gin.Eng
 	router.GET("/network/listeners", restServer.handleListeners)
 	router.GET("/network/peers", restServer.handlePeers)
 	router.GET("/network/peers/:address", peerAddressParam, restServer.handlePeer)
-	// Tx related (TODO get txs has still not been implemented)
+	// Tx related
 	router.POST("/txpool", restServer.handleBroadcastTx)
 	router.GET("/txpool", restServer.handleUnconfirmedTxs)
+	router.GET("/txs/:hash", txHashParam, restServer.handleTxReceipt)
 	// Code execution
 	router.POST("/calls", restServer.handleCall)
 	router.POST("/codecalls", restServer.handleCallCode)
@@ -361,14 +362,26 @@ func (restServer *RestServer) handleBroadcastTx(c *gin.Context) {
 	if errD != nil {
 		c.AbortWithError(500, errD)
 	}
+	// A rejected tx is reported by the code of its receipt
 	receipt, err := restServer.pipe.Transactor().BroadcastTx(param)
-	if err != nil {
+	if err != nil && receipt == nil {
 		c.AbortWithError(500, err)
 	}
 	c.Writer.WriteHeader(200)
 	restServer.codec.Encode(receipt, c.Writer)
 }
 
+func (restServer *RestServer) handleTxReceipt(c *gin.Context) {
+	hash := c.MustGet("txHash").([]byte)
+	receipt, err := restServer.pipe.Transactor().TxReceipt(hash)
+	if err != nil {
+		c.AbortWithError(404, err)
+		return
+	}
+	c.Writer.WriteHeader(200)
+	restServer.codec.Encode(receipt, c.Writer)
+}
+
 func (restServer *RestServer) handleUnconfirmedTxs(c *gin.Context) {
 	trans, err := restServer.pipe.GetConsensusEngine().ListUnconfirmedTxs(-1)
 	if err != nil {
@@ -424,7 +437,7 @@ func (restServer *RestServer) handleTransact(c *gin.Context) {
 		restServer.codec.Encode(res, c.Writer)
 	} else {
 		receipt, err := restServer.pipe.Transactor().Transact(param.PrivKey, param.Address, param.Data, param.GasLimit, param.Fee)
-		if err != nil {
+		if err != nil && receipt == nil {
 			c.AbortWithError(500, err)
 		}
 		c.Writer.WriteHeader(200)
@@ -439,7 +452,7 @@ func (restServer *RestServer) handleTransactNameReg(c *gin.Context) {
 		c.AbortWithError(500, errD)
 	}
 	receipt, err := restServer.pipe.Transactor().TransactNameReg(param.PrivKey, param.Name, param.Data, param.Amount, param.Fee)
-	if err != nil {
+	if err != nil && receipt == nil {
 		c.AbortWithError(500, err)
 	}
 	c.Writer.WriteHeader(200)
@@ -488,6 +501,16 @@ func keyParam(c *gin.Context) {
 	c.Next()
 }
 
+func txHashParam(c *gin.Context) {
+	hash := c.Param("hash")
+	bts, err := hex.DecodeString(hash)
+	if err != nil || len(bts) != 20 {
+		c.AbortWithError(400, fmt.Errorf("Malformed tx hash param: %s", hash))
+	}
+	c.Set("txHash", bts)
+	c.Next()
+}
+
 func heightParam(c *gin.Context) {
 	h, err := strconv.Atoi(c.Param("height"))
 	if err != nil {
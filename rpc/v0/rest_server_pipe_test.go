@@ -275,6 +275,30 @@ func (trans *transactor) CallCode(from, code, data []byte) (*core_types.Call, er
 	return trans.testData.CallCode.Output, nil
 }
 
+func (trans *transactor) TraceCall(fromAddress, toAddress, data []byte) (*core_types.CallTrace, error) {
+	return nil, nil
+}
+
+func (trans *transactor) TraceTx(tx txs.Tx) (*core_types.CallTrace, error) {
+	return nil, nil
+}
+
+func (trans *transactor) EstimateGas(fromAddress, toAddress, data []byte) (int64, error) {
+	return 0, nil
+}
+
+func (trans *transactor) TxReceipt(txHash []byte) (*txs.TxReceipt, error) {
+	return nil, nil
+}
+
+func (trans *transactor) SearchTxs(filter *txs.TxFilter) (*txs.TxSearchResult, error) {
+	return nil, nil
+}
+
+func (trans *transactor) GetLogs(filter *txs.LogFilter) (*txs.LogSearchResult, error) {
+	return nil, nil
+}
+
 func (trans *transactor) BroadcastTx(tx txs.Tx) (*txs.Receipt, error) {
 	receipt := txs.GenerateReceipt(trans.testData.GetChainId.Output.ChainId, tx)
 	return &receipt, nil
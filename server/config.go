@@ -57,6 +57,8 @@ type (
 
 	HTTP struct {
 		JsonRpcEndpoint string `toml:"json_rpc_endpoint"`
+		// Where to serve the Ethereum JSON-RPC API, or empty not to serve it
+		EthJsonRpcEndpoint string `toml:"eth_json_rpc_endpoint"`
 	}
 
 	WebSocket struct {
@@ -148,7 +150,8 @@ func ReadServerConfig(viper *viper.Viper) (*ServerConfig, error) {
 			MaxAge:           maxAgeUint64,
 		},
 		HTTP: HTTP{
-			JsonRpcEndpoint: viper.GetString("http.json_rpc_endpoint"),
+			JsonRpcEndpoint:    viper.GetString("http.json_rpc_endpoint"),
+			EthJsonRpcEndpoint: viper.GetString("http.eth_json_rpc_endpoint"),
 		},
 		WebSocket: WebSocket{
 			WebSocketEndpoint:    viper.GetString("websocket.endpoint"),
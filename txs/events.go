@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
 	. "github.com/hyperledger/burrow/word256"
 
 	"github.com/tendermint/go-wire"
@@ -84,6 +85,8 @@ var _ = wire.RegisterInterface(
 
 type EventDataNewBlock struct {
 	Block *tm_types.Block `json:"block"`
+	// The fees charged by the txs of the block
+	Fees int64 `json:"fees"`
 }
 
 type EventDataNewBlockHeader struct {
@@ -99,11 +102,14 @@ type EventDataTx struct {
 
 // EventDataCall fires when we call a contract, and when a contract calls another contract
 type EventDataCall struct {
-	CallData  *CallData `json:"call_data"`
-	Origin    []byte    `json:"origin"`
-	TxID      []byte    `json:"tx_id"`
-	Return    []byte    `json:"return"`
-	Exception string    `json:"exception"`
+	CallData     *CallData `json:"call_data"`
+	Origin       []byte    `json:"origin"`
+	TxID         []byte    `json:"tx_id"`
+	Return       []byte    `json:"return"`
+	Exception    string    `json:"exception"`
+	RevertReason string    `json:"revert_reason"`
+	// Set when the callee's ABI has been registered, see ContractABIName
+	Decoded *abi.DecodedCall `json:"decoded"`
 }
 
 type CallData struct {
@@ -120,6 +126,8 @@ type EventDataLog struct {
 	Topics  []Word256 `json:"topics"`
 	Data    []byte    `json:"data"`
 	Height  int64     `json:"height"`
+	// Set when the emitter's ABI has been registered, see ContractABIName
+	Decoded *abi.DecodedEvent `json:"decoded"`
 }
 
 // We fire the most recent round state that led to the event
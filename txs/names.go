@@ -15,6 +15,7 @@
 package txs
 
 import (
+	"fmt"
 	"regexp"
 
 	core_types "github.com/hyperledger/burrow/core/types"
@@ -58,6 +59,16 @@ func NameCostPerBlock(baseCost int64) int64 {
 	return NameBlockCostMultiplier * NameByteCostMultiplier * baseCost
 }
 
+// The ABI JSON of a contract can be registered as the data of the name made
+// by ContractABIName, after which its calls and logs are decoded in RPC results.
+// Only the contract itself and the account that created it may register it,
+// or a root account if no creator of the contract was recorded.
+const ContractABINamePrefix = "abi/"
+
+func ContractABIName(address []byte) string {
+	return fmt.Sprintf("%s%X", ContractABINamePrefix, address)
+}
+
 // XXX: vestige of an older time
 type ResultListNames struct {
 	BlockHeight int                        `json:"block_height"`
@@ -0,0 +1,107 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package txs
+
+import (
+	. "github.com/hyperledger/burrow/word256"
+)
+
+type (
+	// Selects committed txs by the heights of their blocks and, if Account is
+	// set, by whether they involve the account as an input, an output, the
+	// callee or the validator
+	TxFilter struct {
+		// First and last heights searched, which the node limits to a range
+		// of so many blocks. A ToHeight of 0 searches up to the last block
+		// committed and a FromHeight of 0 from as early as the node allows.
+		FromHeight int    `json:"from_height"`
+		ToHeight   int    `json:"to_height"`
+		Account    []byte `json:"account"`
+		// Of the txs matched, in the order they ran, how many to skip and at
+		// most how many to return. A Limit of 0 returns as many as the node
+		// allows.
+		Offset int `json:"offset"`
+		Limit  int `json:"limit"`
+	}
+
+	// Selects the logs of committed txs as eth_getLogs does. A log matches if
+	// it was emitted by one of Addresses, or by any address if there are none,
+	// and for each position of Topics its topic at that position is one of
+	// those given. A position with no topics matches any topic.
+	LogFilter struct {
+		FromHeight int         `json:"from_height"`
+		ToHeight   int         `json:"to_height"`
+		Addresses  [][]byte    `json:"addresses"`
+		Topics     [][]Word256 `json:"topics"`
+		Offset     int         `json:"offset"`
+		Limit      int         `json:"limit"`
+	}
+
+	TxSearchResult struct {
+		// The number of txs matched, of which Receipts are those on the page
+		Total    int          `json:"total"`
+		Receipts []*TxReceipt `json:"receipts"`
+	}
+
+	// A log emitted by a committed tx, with where it was emitted
+	LogEntry struct {
+		TxHash   []byte        `json:"tx_hash"`
+		Height   int           `json:"height"`
+		TxIndex  int           `json:"tx_index"`
+		LogIndex int           `json:"log_index"`
+		Log      *EventDataLog `json:"log"`
+	}
+
+	// Logs are only read until the page is full, so rather than the number
+	// matched a search tells whether any more logs match after its page
+	LogSearchResult struct {
+		Logs []*LogEntry `json:"logs"`
+		More bool        `json:"more"`
+	}
+)
+
+func (filter *LogFilter) Matches(log *EventDataLog) bool {
+	if len(filter.Addresses) > 0 {
+		matched := false
+		for _, address := range filter.Addresses {
+			if LeftPadWord256(address) == log.Address {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for i, topics := range filter.Topics {
+		if len(topics) == 0 {
+			continue
+		}
+		if i >= len(log.Topics) {
+			return false
+		}
+		matched := false
+		for _, topic := range topics {
+			if topic == log.Topics[i] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -23,10 +23,12 @@ import (
 	"golang.org/x/crypto/ripemd160"
 
 	acm "github.com/hyperledger/burrow/account"
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/sha3"
 	ptypes "github.com/hyperledger/burrow/permission/types"
 	. "github.com/tendermint/go-common"
 	"github.com/tendermint/go-wire"
 
+	abci "github.com/tendermint/abci/types"
 	"github.com/tendermint/go-crypto"
 	tendermint_types "github.com/tendermint/tendermint/types" // votes for dupeout ..
 )
@@ -81,8 +83,10 @@ Admin Txs:
 const (
 	// Account transactions
 	TxTypeSend = byte(0x01)
-	TxTypeCall = byte(0x02)
+	TxTypeCall = byte(0x02) // A CallTx paying no gas price, see LegacyCallTx
 	TxTypeName = byte(0x03)
+	// A CallTx paying a gas price, which changed its encoding and sign bytes
+	TxTypeCallWithGasPrice = byte(0x04)
 
 	// Validation transactions
 	TxTypeBond    = byte(0x11)
@@ -98,7 +102,8 @@ const (
 var _ = wire.RegisterInterface(
 	struct{ Tx }{},
 	wire.ConcreteType{&SendTx{}, TxTypeSend},
-	wire.ConcreteType{&CallTx{}, TxTypeCall},
+	wire.ConcreteType{&LegacyCallTx{}, TxTypeCall},
+	wire.ConcreteType{&CallTx{}, TxTypeCallWithGasPrice},
 	wire.ConcreteType{&NameTx{}, TxTypeName},
 	wire.ConcreteType{&BondTx{}, TxTypeBond},
 	wire.ConcreteType{&UnbondTx{}, TxTypeUnbond},
@@ -129,6 +134,28 @@ type (
 		TxHash          []byte `json:"tx_hash"`
 		CreatesContract uint8  `json:"creates_contract"`
 		ContractAddr    []byte `json:"contract_addr"`
+		// The ABCI code of the result of the tx, OK unless it failed, and why
+		// it failed
+		Code abci.CodeType `json:"code"`
+		Log  string        `json:"log"`
+	}
+
+	// What a tx did when run in a block, kept by the node under its hash
+	TxReceipt struct {
+		TxHash []byte `json:"tx_hash"`
+		Height int    `json:"height"`
+		// Position of the tx in its block
+		Index int `json:"index"`
+		// The ABCI code of the result of the tx, OK unless it failed, and why
+		// it failed. A CallTx whose call threw still ran, and took its fee.
+		Code abci.CodeType `json:"code"`
+		Log  string        `json:"log"`
+		// For a CallTx that ran its call
+		Return       []byte `json:"return"`
+		GasUsed      int64  `json:"gas_used"`
+		ContractAddr []byte `json:"contract_addr"`
+		// The logs emitted by the call, in order
+		Logs []*EventDataLog `json:"logs"`
 	}
 
 	NameTx struct {
@@ -139,6 +166,19 @@ type (
 	}
 
 	CallTx struct {
+		Input    *TxInput `json:"input"`
+		Address  []byte   `json:"address"`
+		GasLimit int64    `json:"gas_limit"`
+		// Native token paid per unit of gas used, at least the chain's minimum
+		GasPrice int64  `json:"gas_price"`
+		Fee      int64  `json:"fee"`
+		Data     []byte `json:"data"`
+	}
+
+	// A CallTx as encoded before CallTxs had a gas price. CallTxs paying no
+	// gas price are still encoded and signed this way so that they are the
+	// same to nodes and clients from before, and are decoded as CallTxs.
+	LegacyCallTx struct {
 		Input    *TxInput `json:"input"`
 		Address  []byte   `json:"address"`
 		GasLimit int64    `json:"gas_limit"`
@@ -226,9 +266,14 @@ func (tx *SendTx) String() string {
 //-----------------------------------------------------------------------------
 
 func (tx *CallTx) WriteSignBytes(chainID string, w io.Writer, n *int, err *error) {
+	if tx.GasPrice == 0 {
+		tx.legacy().WriteSignBytes(chainID, w, n, err)
+		return
+	}
 	wire.WriteTo([]byte(Fmt(`{"chain_id":%s`, jsonEscape(chainID))), w, n, err)
-	wire.WriteTo([]byte(Fmt(`,"tx":[%v,{"address":"%X","data":"%X"`, TxTypeCall, tx.Address, tx.Data)), w, n, err)
-	wire.WriteTo([]byte(Fmt(`,"fee":%v,"gas_limit":%v,"input":`, tx.Fee, tx.GasLimit)), w, n, err)
+	wire.WriteTo([]byte(Fmt(`,"tx":[%v,{"address":"%X","data":"%X"`, TxTypeCallWithGasPrice, tx.Address, tx.Data)), w, n, err)
+	wire.WriteTo([]byte(Fmt(`,"fee":%v,"gas_limit":%v,"gas_price":%v,"input":`, tx.Fee, tx.GasLimit,
+		tx.GasPrice)), w, n, err)
 	tx.Input.WriteSignBytes(w, n, err)
 	wire.WriteTo([]byte(`}]}`), w, n, err)
 }
@@ -237,6 +282,41 @@ func (tx *CallTx) String() string {
 	return Fmt("CallTx{%v -> %x: %x}", tx.Input, tx.Address, tx.Data)
 }
 
+func (tx *CallTx) legacy() *LegacyCallTx {
+	return &LegacyCallTx{
+		Input:    tx.Input,
+		Address:  tx.Address,
+		GasLimit: tx.GasLimit,
+		Fee:      tx.Fee,
+		Data:     tx.Data,
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func (tx *LegacyCallTx) WriteSignBytes(chainID string, w io.Writer, n *int, err *error) {
+	wire.WriteTo([]byte(Fmt(`{"chain_id":%s`, jsonEscape(chainID))), w, n, err)
+	wire.WriteTo([]byte(Fmt(`,"tx":[%v,{"address":"%X","data":"%X"`, TxTypeCall, tx.Address, tx.Data)), w, n, err)
+	wire.WriteTo([]byte(Fmt(`,"fee":%v,"gas_limit":%v,"input":`, tx.Fee, tx.GasLimit)), w, n, err)
+	tx.Input.WriteSignBytes(w, n, err)
+	wire.WriteTo([]byte(`}]}`), w, n, err)
+}
+
+func (tx *LegacyCallTx) String() string {
+	return Fmt("LegacyCallTx{%v -> %x: %x}", tx.Input, tx.Address, tx.Data)
+}
+
+// The CallTx paying no gas price that tx encodes
+func (tx *LegacyCallTx) CallTx() *CallTx {
+	return &CallTx{
+		Input:    tx.Input,
+		Address:  tx.Address,
+		GasLimit: tx.GasLimit,
+		Fee:      tx.Fee,
+		Data:     tx.Data,
+	}
+}
+
 func NewContractAddress(caller []byte, nonce int) []byte {
 	temp := make([]byte, 32+8)
 	copy(temp, caller)
@@ -246,6 +326,18 @@ func NewContractAddress(caller []byte, nonce int) []byte {
 	return hasher.Sum(nil)
 }
 
+// Address of a contract created by CREATE2, which depends only on its creator,
+// a salt and its init code (so can be known before the contract is deployed).
+// Follows Ethereum: the last 20 bytes of sha3(0xff ++ caller ++ salt ++ sha3(initCode))
+func NewContractAddress2(caller []byte, salt []byte, initCode []byte) []byte {
+	temp := make([]byte, 1+20+32+32)
+	temp[0] = 0xff
+	copy(temp[1:21], LeftPadBytes(caller, 20))
+	copy(temp[21:53], LeftPadBytes(salt, 32))
+	copy(temp[53:], sha3.Sha3(initCode))
+	return sha3.Sha3(temp)[12:]
+}
+
 //-----------------------------------------------------------------------------
 
 func (tx *NameTx) WriteSignBytes(chainID string, w io.Writer, n *int, err *error) {
@@ -401,9 +493,13 @@ func TxHash(chainID string, tx Tx) []byte {
 
 //-----------------------------------------------------------------------------
 
+// CallTxs paying no gas price are encoded as LegacyCallTxs
 func EncodeTx(tx Tx) ([]byte, error) {
 	var n int
 	var err error
+	if callTx, ok := tx.(*CallTx); ok && callTx.GasPrice == 0 {
+		tx = callTx.legacy()
+	}
 	buf := new(bytes.Buffer)
 	wire.WriteBinary(struct{ Tx }{tx}, buf, &n, &err)
 	if err != nil {
@@ -412,7 +508,7 @@ func EncodeTx(tx Tx) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// panic on err
+// LegacyCallTxs are decoded as the CallTxs they encode
 func DecodeTx(txBytes []byte) (Tx, error) {
 	var n int
 	var err error
@@ -422,7 +518,15 @@ func DecodeTx(txBytes []byte) (Tx, error) {
 	if err != nil {
 		return nil, err
 	}
-	return *tx, nil
+	return UpgradeTx(*tx), nil
+}
+
+// Returns the CallTx a LegacyCallTx encodes, or any other tx as it is
+func UpgradeTx(tx Tx) Tx {
+	if legacyTx, ok := tx.(*LegacyCallTx); ok {
+		return legacyTx.CallTx()
+	}
+	return tx
 }
 
 func GenerateReceipt(chainId string, tx Tx) Receipt {
@@ -431,7 +535,7 @@ func GenerateReceipt(chainId string, tx Tx) Receipt {
 		CreatesContract: 0,
 		ContractAddr:    nil,
 	}
-	if callTx, ok := tx.(*CallTx); ok {
+	if callTx, ok := UpgradeTx(tx).(*CallTx); ok {
 		if len(callTx.Address) == 0 {
 			receipt.CreatesContract = 1
 			receipt.ContractAddr = NewContractAddress(callTx.Input.Address,
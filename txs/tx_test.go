@@ -15,6 +15,7 @@
 package txs
 
 import (
+	"encoding/hex"
 	"testing"
 
 	acm "github.com/hyperledger/burrow/account"
@@ -23,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	. "github.com/tendermint/go-common"
 	"github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-wire"
 )
 
 var chainID = "myChainID"
@@ -71,18 +73,53 @@ func TestCallTxSignable(t *testing.T) {
 		},
 		Address:  []byte("contract1"),
 		GasLimit: 111,
+		GasPrice: 3,
 		Fee:      222,
 		Data:     []byte("data1"),
 	}
 	signBytes := acm.SignBytes(chainID, callTx)
 	signStr := string(signBytes)
-	expected := Fmt(`{"chain_id":"%s","tx":[2,{"address":"636F6E747261637431","data":"6461746131","fee":222,"gas_limit":111,"input":{"address":"696E70757431","amount":12345,"sequence":67890}}]}`,
+	expected := Fmt(`{"chain_id":"%s","tx":[4,{"address":"636F6E747261637431","data":"6461746131","fee":222,"gas_limit":111,"gas_price":3,"input":{"address":"696E70757431","amount":12345,"sequence":67890}}]}`,
+		chainID)
+	if signStr != expected {
+		t.Errorf("Got unexpected sign string for CallTx. Expected:\n%v\nGot:\n%v", expected, signStr)
+	}
+
+	// Without a gas price the sign bytes are those from before gas was priced
+	callTx.GasPrice = 0
+	signStr = string(acm.SignBytes(chainID, callTx))
+	expected = Fmt(`{"chain_id":"%s","tx":[2,{"address":"636F6E747261637431","data":"6461746131","fee":222,"gas_limit":111,"input":{"address":"696E70757431","amount":12345,"sequence":67890}}]}`,
 		chainID)
 	if signStr != expected {
 		t.Errorf("Got unexpected sign string for CallTx. Expected:\n%v\nGot:\n%v", expected, signStr)
 	}
 }
 
+func TestCallTxEncoding(t *testing.T) {
+	callTx := &CallTx{
+		Input:    &TxInput{Address: []byte("input1"), Amount: 12345, Sequence: 67890},
+		Address:  []byte("contract1"),
+		GasLimit: 111,
+		Fee:      222,
+		Data:     []byte("data1"),
+	}
+	// CallTxs paying no gas price are encoded as before gas was priced
+	txBytes, err := EncodeTx(callTx)
+	assert.NoError(t, err)
+	assert.Equal(t, wire.BinaryBytes(struct{ Tx }{callTx.legacy()}), txBytes)
+	tx, err := DecodeTx(txBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, callTx, tx)
+
+	callTx.GasPrice = 3
+	txBytes, err = EncodeTx(callTx)
+	assert.NoError(t, err)
+	assert.Equal(t, TxTypeCallWithGasPrice, txBytes[0])
+	tx, err = DecodeTx(txBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, callTx, tx)
+}
+
 func TestNameTxSignable(t *testing.T) {
 	nameTx := &NameTx{
 		Input: &TxInput{
@@ -246,3 +283,39 @@ func TestDupeoutTxSignable(t *testing.T) {
 		t.Errorf("Got unexpected sign string for DupeoutTx")
 	}
 }*/
+
+// Test vectors from EIP-1014
+func TestNewContractAddress2(t *testing.T) {
+	testCases := []struct {
+		caller, salt, initCode, address string
+	}{
+		{"0000000000000000000000000000000000000000",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"00", "4D1A2E2BB4F88F0250F26FFFF098B0B30B26BF38"},
+		{"DEADBEEF00000000000000000000000000000000",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"00", "B928F69BB1D91CD65274E3C79D8986362984FDA3"},
+		{"DEADBEEF00000000000000000000000000000000",
+			"000000000000000000000000FEED000000000000000000000000000000000000",
+			"00", "D04116CDD17BEBE565EB2422F2497E06CC1C9833"},
+		{"0000000000000000000000000000000000000000",
+			"0000000000000000000000000000000000000000000000000000000000000000",
+			"DEADBEEF", "70F2B2914A2A4B783FAEFB75F459A580616FCB5E"},
+		{"00000000000000000000000000000000DEADBEEF",
+			"00000000000000000000000000000000000000000000000000000000CAFEBABE",
+			"DEADBEEF", "60F3F640A8508FC6A86D45DF051962668E1E8AC7"},
+	}
+	for _, tc := range testCases {
+		address := NewContractAddress2(hexBytes(t, tc.caller), hexBytes(t, tc.salt),
+			hexBytes(t, tc.initCode))
+		assert.Equal(t, tc.address, Fmt("%X", address))
+	}
+}
+
+func hexBytes(t *testing.T, hexString string) []byte {
+	bs, err := hex.DecodeString(hexString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bs
+}
@@ -204,6 +204,17 @@ func (tx *BondTx) SignBond(chainID string, privAccount *acm.PrivAccount) error {
 	return nil
 }
 
+// The BondTx standing for a bond of amt through the Validators SNative by the
+// contract at owner, which must be signed with SignBond by the validator for
+// the bond to be accepted
+func NewSNativeBondTx(pubkey crypto.PubKeyEd25519, owner []byte, amt int64) *BondTx {
+	return &BondTx{
+		PubKey:   pubkey,
+		Inputs:   []*TxInput{{Address: owner, Amount: amt}},
+		UnbondTo: []*TxOutput{{Address: owner, Amount: amt}},
+	}
+}
+
 func (tx *BondTx) SignInput(chainID string, i int, privAccount *acm.PrivAccount) error {
 	if i >= len(tx.Inputs) {
 		return fmt.Errorf("Index %v is greater than number of inputs (%v)", i, len(tx.Inputs))
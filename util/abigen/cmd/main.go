@@ -0,0 +1,81 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/burrow/util/abigen/templates"
+)
+
+// Generate a Go binding from a contract's ABI JSON, as solc --abi outputs it,
+// and optionally its code, as solc --bin outputs it
+func main() {
+	abiFile := flag.String("abi", "", "file holding the contract's ABI JSON")
+	binFile := flag.String("bin", "", "file holding the contract's code in hex, needed to deploy it")
+	name := flag.String("type", "", "name of the binding's type (defaults to the ABI file's name)")
+	pkg := flag.String("pkg", "", "package of the binding (defaults to the lower case type name)")
+	out := flag.String("out", "", "file to write the binding to (defaults to stdout)")
+	flag.Parse()
+
+	if *abiFile == "" {
+		fmt.Fprintln(os.Stderr, "An ABI file must be given with -abi")
+		flag.Usage()
+		os.Exit(1)
+	}
+	abiJSON, err := ioutil.ReadFile(*abiFile)
+	if err != nil {
+		fatalf("Could not read ABI: %s", err)
+	}
+	var bin []byte
+	if *binFile != "" {
+		if bin, err = ioutil.ReadFile(*binFile); err != nil {
+			fatalf("Could not read code: %s", err)
+		}
+	}
+	if *name == "" {
+		base := filepath.Base(*abiFile)
+		*name = strings.Title(strings.TrimSuffix(base, filepath.Ext(base)))
+	}
+	if *pkg == "" {
+		*pkg = strings.ToLower(*name)
+	}
+
+	contract, err := templates.NewGoContract(string(abiJSON), string(bin), *name, *pkg)
+	if err != nil {
+		fatalf("Could not bind %s: %s", *name, err)
+	}
+	source, err := contract.Go()
+	if err != nil {
+		fatalf("Error generating Go for contract %s: %s", *name, err)
+	}
+	if *out == "" {
+		fmt.Print(source)
+		return
+	}
+	if err = ioutil.WriteFile(*out, []byte(source), 0644); err != nil {
+		fatalf("Could not write binding: %s", err)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,414 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
+)
+
+const bindingTemplateText = `// Code generated by util/abigen from the ABI of [[.Name]]. DO NOT EDIT.
+
+package [[.Package]]
+
+import (
+[[- if .UsesBig]]
+	"math/big"
+[[end]]
+	"github.com/hyperledger/burrow/client"
+	"github.com/hyperledger/burrow/client/bind"
+[[- if .UsesRPC]]
+	"github.com/hyperledger/burrow/client/rpc"
+[[- end]]
+[[- if .UsesABI]]
+	"github.com/hyperledger/burrow/manager/burrow-mint/evm/abi"
+[[- end]]
+[[- if .Events]]
+	"github.com/hyperledger/burrow/txs"
+[[- end]]
+)
+
+// The ABI [[.Name]] was generated from
+const [[.Name]]ABI = [[.QuotedABI]]
+[[if .Bin]]
+// The code that deploys [[.Name]], in hex
+const [[.Name]]Bin = "[[.Bin]]"
+[[end]]
+// A binding to a deployed [[.Name]] contract
+type [[.Name]] struct {
+	*bind.BoundContract
+}
+
+// Binds to the [[.Name]] contract deployed at address
+func New[[.Name]](address []byte, nodeClient client.NodeClient) (*[[.Name]], error) {
+	contract, err := bind.NewBoundContract(address, [[.Name]]ABI, nodeClient)
+	if err != nil {
+		return nil, err
+	}
+	return &[[.Name]]{contract}, nil
+}
+[[if .Bin]]
+// Deploys a [[.Name]] contract and waits for it to be committed
+func Deploy[[.Name]](nodeClient client.NodeClient, opts *bind.TransactOpts[[range .ConstructorArgs]], [[.Param]] [[.GoType]][[end]]) (*[[.Name]], *rpc.TxResult, error) {
+	contract, result, err := bind.Deploy(nodeClient, opts, [[.Name]]ABI, [[.Name]]Bin[[range .ConstructorArgs]], [[.Param]][[end]])
+	if err != nil {
+		return nil, nil, err
+	}
+	return &[[.Name]]{contract}, result, nil
+}
+[[end]]
+[[- range .Functions]]
+// Calls [[.Signature]] as caller without sending a tx
+func (contract *[[$.Name]]) [[.GoName]](caller []byte[[range .Inputs]], [[.Param]] [[.GoType]][[end]]) ([[range .Outputs]][[.Param]] [[.GoType]], [[end]]err error) {
+	[[if .Outputs]]values, err :=[[else]]_, err =[[end]] contract.BoundContract.Call(caller, "[[.Name]]"[[range .Inputs]], [[.Param]][[end]])
+	[[- if .Outputs]]
+	if err != nil {
+		return
+	}
+	[[- range $i, $output := .Outputs]]
+	[[$output.Param]] = values[[print "[" $i "]"]].([[$output.GoType]])
+	[[- end]]
+	[[- end]]
+	return
+}
+[[if not .Constant]]
+// Sends a tx calling [[.Signature]] and waits for it to be committed
+func (contract *[[$.Name]]) Transact[[.GoName]](opts *bind.TransactOpts[[range .Inputs]], [[.Param]] [[.GoType]][[end]]) ([[range .Outputs]][[.Param]] [[.GoType]], [[end]]result *rpc.TxResult, err error) {
+	[[if .Outputs]]values, result, err :=[[else]]_, result, err =[[end]] contract.BoundContract.Transact(opts, "[[.Name]]"[[range .Inputs]], [[.Param]][[end]])
+	[[- if .Outputs]]
+	if err != nil {
+		return
+	}
+	[[- range $i, $output := .Outputs]]
+	[[$output.Param]] = values[[print "[" $i "]"]].([[$output.GoType]])
+	[[- end]]
+	[[- end]]
+	return
+}
+[[end]]
+[[- end]]
+[[- range .Events]]
+// A log of the [[.Signature]] event of [[$.Name]]
+type [[$.Name]][[.GoName]] struct {
+	[[- range .Inputs]]
+	[[.Field]] [[.GoType]]
+	[[- end]]
+	Raw txs.EventDataLog
+}
+
+// Watches the [[.Name]] logs the contract emits[[if .IndexedInputs]] whose indexed arguments are
+// those given, where nil matches any value,[[end]] until stop is called
+func (contract *[[$.Name]]) Watch[[.GoName]]([[range $i, $arg := .IndexedInputs]][[if $i]], [[end]][[$arg.Param]][[end]][[if .IndexedInputs]] interface{}[[end]]) (events chan *[[$.Name]][[.GoName]], stop func(), err error) {
+	logs, stop, err := contract.BoundContract.WatchLogs("[[.Name]]"[[range .IndexedInputs]], [[.Param]][[end]])
+	if err != nil {
+		return
+	}
+	events = make(chan *[[$.Name]][[.GoName]])
+	go func() {
+		defer close(events)
+		for log := range logs {
+			events <- &[[$.Name]][[.GoName]]{
+				[[- range $i, $input := .Inputs]]
+				[[$input.Field]]: log.Values[[print "[" $i "]"]].([[$input.GoType]]),
+				[[- end]]
+				Raw: log.Raw,
+			}
+		}
+	}()
+	return
+}
+[[end]]`
+
+var bindingTemplate *template.Template
+
+func init() {
+	var err error
+	bindingTemplate, err = template.New("GoBindingTemplate").
+		Delims("[[", "]]").
+		Parse(bindingTemplateText)
+	if err != nil {
+		panic(fmt.Errorf("Couldn't parse Go binding template: %s", err))
+	}
+}
+
+type goContract struct {
+	*abi.ABI
+	Name    string
+	Package string
+	// The ABI JSON and deployment code in hex the binding is generated from
+	JSON string
+	Bin  string
+}
+
+type goFunction struct {
+	*abi.Method
+	Inputs  []*goArg
+	Outputs []*goArg
+}
+
+type goEvent struct {
+	*abi.Event
+	Inputs []*goArg
+}
+
+type goArg struct {
+	abi.Argument
+	// The name of the argument as a Go parameter and as a struct field
+	Param string
+	Field string
+}
+
+//
+// Contract
+//
+
+// Create a templated goContract from a contract's ABI for a binding of type
+// name in package pkg. The binding can deploy the contract only if bin, its
+// code in hex, is given.
+func NewGoContract(abiJSON, bin, name, pkg string) (*goContract, error) {
+	contractABI, err := abi.JSON([]byte(abiJSON))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, method := range contractABI.Functions {
+		if names[method.Name] {
+			return nil, fmt.Errorf("Cannot bind overloaded function '%s'", method.Name)
+		}
+		names[method.Name] = true
+	}
+	return &goContract{
+		ABI:     contractABI,
+		Name:    name,
+		Package: pkg,
+		JSON:    abiJSON,
+		Bin:     strings.TrimPrefix(strings.TrimSpace(bin), "0x"),
+	}, nil
+}
+
+// Generate the gofmt'd Go source of the binding
+func (contract *goContract) Go() (string, error) {
+	buf := new(bytes.Buffer)
+	err := bindingTemplate.Execute(buf, contract)
+	if err != nil {
+		return "", err
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("Generated invalid Go for %s: %s", contract.Name, err)
+	}
+	return string(source), nil
+}
+
+// The ABI as a Go string literal, raw unless it holds a backquote
+func (contract *goContract) QuotedABI() string {
+	if strings.Contains(contract.JSON, "`") {
+		return fmt.Sprintf("%q", contract.JSON)
+	}
+	return "`" + contract.JSON + "`"
+}
+
+func (contract *goContract) ConstructorArgs() []*goArg {
+	if contract.Constructor == nil {
+		return nil
+	}
+	return goArgs(contract.Constructor.Inputs)
+}
+
+func (contract *goContract) Functions() []*goFunction {
+	functions := make([]*goFunction, len(contract.ABI.Functions))
+	for i, method := range contract.ABI.Functions {
+		functions[i] = &goFunction{
+			Method:  method,
+			Inputs:  goArgs(method.Inputs),
+			Outputs: goArgs(method.Outputs),
+		}
+		// Keep the names of return values apart from those of parameters
+		for j, output := range functions[i].Outputs {
+			if output.Name == "" {
+				output.Param = fmt.Sprintf("ret%d", j)
+			} else {
+				output.Param = "ret" + exported(output.Param)
+			}
+		}
+	}
+	return functions
+}
+
+func (contract *goContract) Events() []*goEvent {
+	events := make([]*goEvent, len(contract.ABI.Events))
+	for i, event := range contract.ABI.Events {
+		events[i] = &goEvent{
+			Event:  event,
+			Inputs: goArgs(event.Inputs),
+		}
+	}
+	return events
+}
+
+// Whether the binding needs to import math/big
+func (contract *goContract) UsesBig() bool {
+	return contract.usesGoType("*big.Int")
+}
+
+// Whether the binding sends txs, for which it needs to import client/rpc
+func (contract *goContract) UsesRPC() bool {
+	if contract.Bin != "" {
+		return true
+	}
+	for _, method := range contract.ABI.Functions {
+		if !method.Constant {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the binding needs to import the abi package
+func (contract *goContract) UsesABI() bool {
+	return contract.usesGoType("abi.Address")
+}
+
+func (contract *goContract) usesGoType(goType string) bool {
+	var args []*goArg
+	if contract.Bin != "" {
+		args = append(args, contract.ConstructorArgs()...)
+	}
+	for _, function := range contract.Functions() {
+		args = append(append(args, function.Inputs...), function.Outputs...)
+	}
+	for _, event := range contract.Events() {
+		args = append(args, event.Inputs...)
+	}
+	for _, arg := range args {
+		if arg.GoType() == goType {
+			return true
+		}
+	}
+	return false
+}
+
+//
+// Function and event
+//
+
+func (function *goFunction) GoName() string {
+	return exported(function.Name)
+}
+
+func (event *goEvent) GoName() string {
+	return exported(event.Name)
+}
+
+func (event *goEvent) IndexedInputs() []*goArg {
+	var indexed []*goArg
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	return indexed
+}
+
+//
+// Argument
+//
+
+// The Go type abi.Unpack gives values of the argument as, or the bytes of
+// their hash for indexed event arguments that do not fit in a word
+func (arg *goArg) GoType() string {
+	t := arg.Type
+	switch t.Kind {
+	case abi.UintKind, abi.IntKind:
+		if t.Size > 64 {
+			return "*big.Int"
+		}
+		if t.Kind == abi.UintKind {
+			return "uint64"
+		}
+		return "int64"
+	case abi.AddressKind:
+		return "abi.Address"
+	case abi.BoolKind:
+		return "bool"
+	case abi.StringKind:
+		if !arg.Indexed {
+			return "string"
+		}
+	case abi.ArrayKind, abi.SliceKind, abi.TupleKind:
+		if !arg.Indexed {
+			return "[]interface{}"
+		}
+	}
+	return "[]byte"
+}
+
+// Gives each argument a Go name, keeping apart unnamed arguments, arguments
+// whose names differ only in case and names the bindings use themselves
+func goArgs(args []abi.Argument) []*goArg {
+	goArgs := make([]*goArg, len(args))
+	taken := map[string]bool{"caller": true, "contract": true, "err": true, "events": true,
+		"log": true, "logs": true, "opts": true, "result": true, "stop": true, "values": true, "Raw": true}
+	for i, arg := range args {
+		param := unexported(arg.Name)
+		if param == "" || taken[param] || taken[exported(param)] || token.Lookup(param).IsKeyword() {
+			param = fmt.Sprintf("%s%d", param, i)
+			if !unicode.IsLetter(rune(param[0])) {
+				param = "arg" + param
+			}
+		}
+		taken[param] = true
+		taken[exported(param)] = true
+		goArgs[i] = &goArg{
+			Argument: arg,
+			Param:    param,
+			Field:    exported(param),
+		}
+	}
+	return goArgs
+}
+
+//
+// Utility
+//
+
+// Solidity names are often lowerCamelCase or prefixed by an underscore, as
+// in _owner, which is dropped
+func identifier(name string) string {
+	return strings.TrimLeft(name, "_")
+}
+
+func exported(name string) string {
+	name = identifier(name)
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func unexported(name string) string {
+	name = identifier(name)
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
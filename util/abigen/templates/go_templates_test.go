@@ -0,0 +1,85 @@
+// Copyright 2017 Monax Industries Limited
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package templates
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const tokenABI = `[
+	{"type":"constructor","inputs":[{"name":"supply","type":"uint"}]},
+	{"type":"function","name":"transfer","constant":false,
+	 "inputs":[{"name":"_to","type":"address"},{"name":"amount","type":"uint256"}],
+	 "outputs":[{"name":"ok","type":"bool"}]},
+	{"type":"function","name":"balanceOf","stateMutability":"view",
+	 "inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint64"}]},
+	{"type":"event","name":"Transfer","inputs":[
+	 {"name":"from","type":"address","indexed":true},
+	 {"name":"memo","type":"string","indexed":true},
+	 {"name":"type","type":"int8[]","indexed":false}]}
+]`
+
+// This test checks that we generate a binding that parses, with the
+// declarations we expect
+func TestGoContractTemplate(t *testing.T) {
+	contract, err := NewGoContract(tokenABI, "0x6060", "Token", "token")
+	assert.NoError(t, err)
+	source, err := contract.Go()
+	assert.NoError(t, err)
+	file, err := parser.ParseFile(token.NewFileSet(), "token.go", source, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "token", file.Name.Name)
+	var imports []string
+	for _, spec := range file.Imports {
+		imports = append(imports, spec.Path.Value)
+	}
+	assert.Contains(t, imports, `"math/big"`)
+	assert.Contains(t, imports, `"github.com/hyperledger/burrow/client/rpc"`)
+	for _, decl := range []string{
+		"const TokenBin = \"6060\"",
+		"func DeployToken(nodeClient client.NodeClient, opts *bind.TransactOpts, supply *big.Int) (*Token, *rpc.TxResult, error)",
+		"func (contract *Token) Transfer(caller []byte, to abi.Address, amount *big.Int) (retOk bool, err error)",
+		"func (contract *Token) TransactTransfer(opts *bind.TransactOpts, to abi.Address, amount *big.Int) (retOk bool, result *rpc.TxResult, err error)",
+		"func (contract *Token) BalanceOf(caller []byte, owner abi.Address) (ret0 uint64, err error)",
+		"Type2 []interface{}",
+		"log.Values[1].([]byte)",
+		"func (contract *Token) WatchTransfer(from, memo interface{}) (events chan *TokenTransfer, stop func(), err error)",
+	} {
+		assert.Contains(t, source, decl)
+	}
+	// Constant functions are only called
+	assert.NotContains(t, source, "TransactBalanceOf")
+}
+
+func TestGoContractWithoutCode(t *testing.T) {
+	contract, err := NewGoContract(`[{"type":"function","name":"get","constant":true,
+		"inputs":[],"outputs":[{"name":"","type":"bytes"}]}]`, "", "Getter", "getter")
+	assert.NoError(t, err)
+	source, err := contract.Go()
+	assert.NoError(t, err)
+	assert.NotContains(t, source, "Deploy")
+	assert.NotContains(t, source, "client/rpc")
+	assert.NotContains(t, source, "math/big")
+
+	_, err = NewGoContract(`[{"type":"function","name":"f","inputs":[]},
+		{"type":"function","name":"f","inputs":[{"name":"x","type":"uint8"}]}]`, "", "Overloaded", "overloaded")
+	assert.Error(t, err)
+}
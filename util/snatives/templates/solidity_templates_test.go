@@ -42,3 +42,14 @@ func TestSNativeContractTemplate(t *testing.T) {
 	assert.NoError(t, err)
 	fmt.Println(solidity)
 }
+
+func TestSNativeNameRegContractTemplate(t *testing.T) {
+	contract := vm.SNativeContracts()["NameReg"]
+	solidityContract := NewSolidityContract(contract)
+	solidity, err := solidityContract.Solidity()
+	assert.NoError(t, err)
+	assert.Contains(t, solidity, "contract NameReg {")
+	assert.Contains(t, solidity,
+		"function setName(string _name, string _data, uint64 _amount) constant returns (uint64 expires);")
+	assert.Contains(t, solidity, "function owner(string _name) constant returns (address owner);")
+}